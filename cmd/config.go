@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/fancontrol"
+	"github.com/markusressel/fan2go/internal/hwmon"
+	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+)
+
+const (
+	// exit code of "config validate", if the configuration contains errors,
+	// and of "config generate" and "config import-fancontrol", if the resulting configuration is invalid
+	exitCodeInvalidConfig = 1
+	// exit code of "config validate", if the configuration file cannot be read
+	exitCodeUnreadableConfig = 2
+	// exit code of "config validate", if it has been called with invalid arguments
+	exitCodeUsage = 3
+)
+
+var (
+	configValidateOutputFormat string
+	configOutputFile           string
+	configGenerateChips        []string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration related commands",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file",
+	Long: `Checks the configuration file and prints all problems found in it,
+each with the path of the affected entry, f.ex. "curves[2].function.curves[1]".
+
+Exit codes:
+  0  the configuration is valid, but may have warnings
+  1  the configuration contains errors
+  2  the configuration file cannot be read
+  3  invalid arguments, f.ex. an unknown output format`,
+	Run: func(cmd *cobra.Command, args []string) {
+		setupUi()
+		if configValidateOutputFormat != "text" && configValidateOutputFormat != "json" {
+			ui.Error("Unknown output format '%s', use one of: text | json", configValidateOutputFormat)
+			os.Exit(exitCodeUsage)
+		}
+		if configValidateOutputFormat == "json" {
+			// keep stdout parsable
+			ui.SetOutput(os.Stderr)
+		}
+
+		config, err := configuration.ParseConfigFile()
+		if err != nil {
+			ui.Error("%v", err)
+			os.Exit(exitCodeUnreadableConfig)
+		}
+
+		problems := configuration.Validate(&config)
+		valid := !configuration.HasErrors(problems)
+
+		if configValidateOutputFormat == "json" {
+			if problems == nil {
+				problems = []configuration.Problem{}
+			}
+			result := struct {
+				File     string                  `json:"file"`
+				Valid    bool                    `json:"valid"`
+				Problems []configuration.Problem `json:"problems"`
+			}{
+				File:     viper.ConfigFileUsed(),
+				Valid:    valid,
+				Problems: problems,
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(result); err != nil {
+				ui.Fatal("Unable to write result: %v", err)
+			}
+		} else {
+			printProblems(problems)
+		}
+
+		if !valid {
+			os.Exit(exitCodeInvalidConfig)
+		}
+	},
+}
+
+var configImportFancontrolCmd = &cobra.Command{
+	Use:   "import-fancontrol [<file>]",
+	Short: "Convert a fancontrol configuration to a fan2go configuration",
+	Long: `Reads a configuration of the fancontrol script from lm-sensors (default: /etc/fancontrol)
+and prints an equivalent fan2go configuration.
+
+Each temperature input is mapped to a sensor, each MINTEMP/MAXTEMP pair to a linear curve
+and each PWM output to a hwmon fan. MINSTART is used as the startPwm of the fan.
+Settings which cannot be mapped are reported, and noted in the generated configuration.
+If the resulting configuration is invalid, its errors are reported and it is not written.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupUi()
+		// keep stdout parsable
+		ui.SetOutput(os.Stderr)
+
+		path := "/etc/fancontrol"
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			ui.Fatal("Unable to open fancontrol configuration: %v", err)
+		}
+		defer file.Close()
+
+		config, err := fancontrol.Parse(file)
+		if err != nil {
+			ui.Fatal("Unable to parse fancontrol configuration %s: %v", path, err)
+		}
+		result := fancontrol.Convert(config)
+
+		for _, warning := range result.Warnings {
+			ui.Warning("%s", warning)
+		}
+		header := []string{"fan2go configuration imported from " + path}
+		if len(result.Warnings) > 0 {
+			header = append(header, "", "The following settings could not be imported:")
+			for _, warning := range result.Warnings {
+				header = append(header, "- "+warning)
+			}
+		}
+		result.Comments[""] = header
+
+		writeGeneratedConfig(result.Config, result.Comments)
+	},
+}
+
+var configGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a configuration from the detected devices",
+	Long: `Detects all devices and prints a starter configuration, with a sensor for each
+temperature input and a fan for each controllable PWM output.
+
+Each fan uses a linear curve, driven by the CPU temperature if available, which should be
+adjusted to your needs. Devices are selected by their chip name and channels, which don't
+depend on the enumeration order of the kernel. PWM outputs are skipped if no sensor is available
+for their curve. If the resulting configuration is invalid, its errors are reported and it is not written.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		setupUi()
+		// keep stdout parsable
+		ui.SetOutput(os.Stderr)
+
+		configuration.ReadConfigFileIfExists()
+		controllers := hwmon.GetChips()
+
+		config, comments := hwmon.GenerateConfig(controllers, configGenerateChips)
+		if len(config.Sensors) <= 0 && len(config.Fans) <= 0 {
+			ui.Fatal("No matching devices found")
+		}
+		comments[""] = append([]string{"fan2go configuration generated by 'fan2go config generate'"}, comments[""]...)
+
+		writeGeneratedConfig(config, comments)
+	},
+}
+
+// validates the given generated configuration and writes it to stdout, or the output file.
+// If the configuration contains errors, they are printed and nothing is written.
+func writeGeneratedConfig(config configuration.Configuration, comments configuration.Comments) {
+	// only the sensors, curves and fans are written, all other settings use their defaults
+	config.HwMonBackend = configuration.HwMonBackendSysfs
+	errorCount := 0
+	for _, problem := range configuration.Validate(&config) {
+		// unused entries are expected in a generated configuration
+		if problem.Severity == configuration.SeverityError {
+			errorCount++
+			ui.Error("%s", problem)
+		}
+	}
+	if errorCount > 0 {
+		ui.Error("The resulting configuration is invalid, found %d error(s)", errorCount)
+		os.Exit(exitCodeInvalidConfig)
+	}
+
+	writer := os.Stdout
+	if len(configOutputFile) > 0 {
+		file, err := os.Create(configOutputFile)
+		if err != nil {
+			ui.Fatal("Unable to create output file: %v", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	if err := configuration.WriteYaml(writer, config, comments); err != nil {
+		ui.Fatal("Unable to write configuration: %v", err)
+	}
+	if len(configOutputFile) > 0 {
+		ui.Info("Configuration written to %s", configOutputFile)
+	}
+}
+
+func printProblems(problems []configuration.Problem) {
+	errorCount := 0
+	for _, problem := range problems {
+		if problem.Severity == configuration.SeverityError {
+			errorCount++
+			ui.Error("%s", problem)
+		} else {
+			ui.Warning("%s", problem)
+		}
+	}
+
+	summary := fmt.Sprintf("%s: %d error(s), %d warning(s)", viper.ConfigFileUsed(), errorCount, len(problems)-errorCount)
+	if errorCount > 0 {
+		ui.Error("%s", summary)
+	} else {
+		ui.Info("%s", summary)
+	}
+}
+
+func init() {
+	configValidateCmd.Flags().StringVarP(&configValidateOutputFormat, "output", "o", "text", "Output format, one of: text | json")
+	configCmd.AddCommand(configValidateCmd)
+
+	configImportFancontrolCmd.Flags().StringVarP(&configOutputFile, "file", "f", "", "Write the configuration to the given file instead of stdout")
+	configCmd.AddCommand(configImportFancontrolCmd)
+
+	configGenerateCmd.Flags().StringVarP(&configOutputFile, "file", "f", "", "Write the configuration to the given file instead of stdout")
+	configGenerateCmd.Flags().StringSliceVar(&configGenerateChips, "chip", nil, "Only use the devices with the given chip names or identifiers")
+	configCmd.AddCommand(configGenerateCmd)
+	rootCmd.AddCommand(configCmd)
+}
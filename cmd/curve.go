@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"github.com/guptarohit/asciigraph"
 	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/controller"
 	"github.com/markusressel/fan2go/internal/fans"
 	"github.com/markusressel/fan2go/internal/hwmon"
 	"github.com/markusressel/fan2go/internal/persistence"
@@ -11,7 +12,6 @@ import (
 	"github.com/mgutz/ansi"
 	"github.com/spf13/cobra"
 	"github.com/tomlazar/table"
-	"regexp"
 	"sort"
 	"strconv"
 )
@@ -29,20 +29,12 @@ var curveCmd = &cobra.Command{
 		var fanList []fans.Fan
 		for _, config := range configuration.CurrentConfig.Fans {
 			if config.HwMon != nil {
-				for _, controller := range controllers {
-					matched, err := regexp.MatchString("(?i)"+config.HwMon.Platform, controller.Platform)
-					if err != nil {
-						ui.Fatal("Failed to match platform regex of %s (%s) against controller platform %s", config.ID, config.HwMon.Platform, controller.Platform)
-					}
-					if matched {
-						index := config.HwMon.Index - 1
-						if len(controller.Fans) > index {
-							fan := controller.Fans[index]
-							config.HwMon.PwmOutput = fan.PwmOutput
-							config.HwMon.RpmInput = fan.RpmInput
-							break
-						}
-					}
+				fan, err := hwmon.ResolveFan(hwmon.NewFanSelector(*config.HwMon), controllers)
+				if err != nil {
+					ui.Warning("Fan %s: %v", config.ID, err)
+				} else {
+					config.HwMon.PwmOutput = fan.PwmOutput
+					config.HwMon.RpmInput = fan.RpmInput
 				}
 			}
 
@@ -58,6 +50,10 @@ var curveCmd = &cobra.Command{
 			if fanCurveErr == nil {
 				_ = fan.AttachFanCurveData(&pwmData)
 			}
+			thresholds, thresholdsErr := persistence.LoadFanPwmThresholds(fan)
+			if fanCurveErr == nil && thresholdsErr == nil {
+				controller.ApplyPwmThresholds(fan, thresholds)
+			}
 
 			if idx > 0 {
 				ui.Printfln("")
@@ -69,7 +65,8 @@ var curveCmd = &cobra.Command{
 			tab := table.Table{
 				Headers: []string{"", ""},
 				Rows: [][]string{
-					{"Start PWM", strconv.Itoa(fan.GetMinPwm())},
+					{"Start PWM", strconv.Itoa(fan.GetStartPwm())},
+					{"Min PWM", strconv.Itoa(fan.GetMinPwm())},
 					{"Max PWM", strconv.Itoa(fan.GetMaxPwm())},
 				},
 			}
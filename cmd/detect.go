@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/markusressel/fan2go/internal/configuration"
 	"github.com/markusressel/fan2go/internal/hwmon"
@@ -9,101 +10,264 @@ import (
 	"github.com/mgutz/ansi"
 	"github.com/spf13/cobra"
 	"github.com/tomlazar/table"
+	"gopkg.in/yaml.v2"
+	"os"
 	"path/filepath"
 	"strconv"
 )
 
+var detectOutputFormat string
+
 var detectCmd = &cobra.Command{
 	Use:   "detect",
 	Short: "Detect devices",
 	Long:  `Detects all fans and sensors and prints them as a list`,
 	Run: func(cmd *cobra.Command, args []string) {
-		configuration.LoadConfig()
+		switch detectOutputFormat {
+		case "table":
+		case "json", "yaml":
+			// keep stdout parsable
+			ui.SetOutput(os.Stderr)
+		default:
+			ui.Fatal("Unknown output format '%s', use one of: table | json | yaml", detectOutputFormat)
+		}
+
+		configuration.ReadConfigFileIfExists()
 
 		controllers := hwmon.GetChips()
 
-		// === Print detected devices ===
-		tableConfig := &table.Config{
-			ShowIndex:       false,
-			Color:           !noColor,
-			AlternateColors: true,
-			TitleColorCode:  ansi.ColorCode("white+buf"),
-			AltColorCodes: []string{
-				ansi.ColorCode("white"),
-				ansi.ColorCode("white:236"),
-			},
+		var err error
+		switch detectOutputFormat {
+		case "table":
+			printDetectedTables(controllers)
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(newDetectedControllers(controllers))
+		case "yaml":
+			encoder := yaml.NewEncoder(os.Stdout)
+			err = encoder.Encode(newDetectedControllers(controllers))
+			if err == nil {
+				err = encoder.Close()
+			}
+		}
+		if err != nil {
+			ui.Fatal("Unable to write detected devices: %v", err)
 		}
+	},
+}
 
-		for _, controller := range controllers {
-			if len(controller.Name) <= 0 {
-				continue
-			}
+// detectedController is the machine-readable representation of a detected device
+type detectedController struct {
+	Name       string           `json:"name" yaml:"name"`
+	Chip       string           `json:"chip" yaml:"chip"`
+	Platform   string           `json:"platform" yaml:"platform"`
+	DType      string           `json:"dtype" yaml:"dtype"`
+	Modalias   string           `json:"modalias" yaml:"modalias"`
+	Path       string           `json:"path" yaml:"path"`
+	DevicePath string           `json:"devicePath" yaml:"devicePath"`
+	Fans       []detectedFan    `json:"fans" yaml:"fans"`
+	Sensors    []detectedSensor `json:"sensors" yaml:"sensors"`
+}
 
-			fanList := controller.Fans
-			sensorList := controller.Sensors
+type detectedFan struct {
+	Index     int    `json:"index" yaml:"index"`
+	Channel   int    `json:"channel" yaml:"channel"`
+	Label     string `json:"label" yaml:"label"`
+	PwmOutput string `json:"pwmOutput" yaml:"pwmOutput"`
+	// empty if the fan has no RPM sensor
+	RpmInput string `json:"rpmInput" yaml:"rpmInput"`
+	Pwm      int    `json:"pwm" yaml:"pwm"`
+	Rpm      int    `json:"rpm" yaml:"rpm"`
+	// value of pwm_enable, nil if it cannot be read
+	PwmEnable *int `json:"pwmEnable" yaml:"pwmEnable"`
+	MinPwm    int  `json:"minPwm" yaml:"minPwm"`
+	MaxPwm    int  `json:"maxPwm" yaml:"maxPwm"`
+	// limits of the RPM sensor, nil if not provided by the device
+	MinRpm *int `json:"minRpm" yaml:"minRpm"`
+	MaxRpm *int `json:"maxRpm" yaml:"maxRpm"`
+}
 
-			if len(fanList) <= 0 && len(sensorList) <= 0 {
-				continue
-			}
+type detectedSensor struct {
+	Index   int    `json:"index" yaml:"index"`
+	Channel int    `json:"channel" yaml:"channel"`
+	Label   string `json:"label" yaml:"label"`
+	Input   string `json:"input" yaml:"input"`
+	// current value in degree
+	Value float64 `json:"value" yaml:"value"`
+	// limits in degree, nil if not provided by the device
+	Min  *int `json:"min" yaml:"min"`
+	Max  *int `json:"max" yaml:"max"`
+	Crit *int `json:"crit" yaml:"crit"`
+}
 
-			ui.Printfln("> %s", controller.Name)
+func newDetectedControllers(controllers []*hwmon.HwMonController) []detectedController {
+	result := []detectedController{}
+	for _, controller := range controllers {
+		if len(controller.Name) <= 0 || (len(controller.Fans) <= 0 && len(controller.Sensors) <= 0) {
+			continue
+		}
 
-			var fanRows [][]string
-			for _, fan := range fanList {
-				pwm := fan.GetPwm()
-				rpm := fan.GetRpm()
-				isAuto, _ := fan.IsPwmAuto()
-				fanRows = append(fanRows, []string{
-					"", strconv.Itoa(fan.Index), fan.Label, strconv.Itoa(rpm), strconv.Itoa(pwm), fmt.Sprintf("%v", isAuto),
-				})
-			}
-			var fanHeaders = []string{"Fans   ", "Index", "Label", "RPM", "PWM", "Auto"}
+		c := detectedController{
+			Name:       controller.Name,
+			Chip:       controller.ChipName,
+			Platform:   controller.Platform,
+			DType:      controller.DType,
+			Modalias:   controller.Modalias,
+			Path:       controller.Path,
+			DevicePath: controller.DevicePath,
+			Fans:       []detectedFan{},
+			Sensors:    []detectedSensor{},
+		}
 
-			fanTable := table.Table{
-				Headers: fanHeaders,
-				Rows:    fanRows,
+		for _, fan := range controller.Fans {
+			var pwmEnable *int
+			if fan.PwmEnabled >= 0 {
+				value := fan.PwmEnabled
+				pwmEnable = &value
 			}
+			c.Fans = append(c.Fans, detectedFan{
+				Index:     fan.Index,
+				Channel:   hwmon.GetChannel(fan.PwmOutput),
+				Label:     fan.Label,
+				PwmOutput: fan.PwmOutput,
+				RpmInput:  fan.RpmInput,
+				Pwm:       fan.GetPwm(),
+				Rpm:       fan.GetRpm(),
+				PwmEnable: pwmEnable,
+				MinPwm:    fan.GetMinPwm(),
+				MaxPwm:    fan.GetMaxPwm(),
+				MinRpm:    optionalValue(fan.MinRpm),
+				MaxRpm:    optionalValue(fan.MaxRpm),
+			})
+		}
 
-			var sensorRows [][]string
-			for _, sensor := range sensorList {
-				value, _ := sensor.GetValue()
+		for _, sensor := range controller.Sensors {
+			value, _ := sensor.GetValue()
+			c.Sensors = append(c.Sensors, detectedSensor{
+				Index:   sensor.Index,
+				Channel: hwmon.GetChannel(sensor.Input),
+				Label:   sensor.Label,
+				Input:   sensor.Input,
+				Value:   value / 1000,
+				Min:     optionalValue(sensor.Min),
+				Max:     optionalValue(sensor.Max),
+				Crit:    optionalValue(sensor.Crit),
+			})
+		}
 
-				_, file := filepath.Split(sensor.Input)
-				labelAndFile := fmt.Sprintf("%s (%s)", sensor.Label, file)
+		result = append(result, c)
+	}
+	return result
+}
 
-				sensorRows = append(sensorRows, []string{
-					"", strconv.Itoa(sensor.Index), labelAndFile, strconv.Itoa(int(value)),
-				})
-			}
-			var sensorHeaders = []string{"Sensors", "Index", "Label", "Value"}
+// returns nil for limits which are not provided by the device
+func optionalValue(value int) *int {
+	if value < 0 {
+		return nil
+	}
+	return &value
+}
 
-			sensorTable := table.Table{
-				Headers: sensorHeaders,
-				Rows:    sensorRows,
-			}
+// prints the given devices as tables
+func printDetectedTables(controllers []*hwmon.HwMonController) {
+	tableConfig := &table.Config{
+		ShowIndex:       false,
+		Color:           !noColor,
+		AlternateColors: true,
+		TitleColorCode:  ansi.ColorCode("white+buf"),
+		AltColorCodes: []string{
+			ansi.ColorCode("white"),
+			ansi.ColorCode("white:236"),
+		},
+	}
 
-			tables := []table.Table{fanTable, sensorTable}
-
-			for idx, table := range tables {
-				if table.Rows == nil {
-					continue
-				}
-				var buf bytes.Buffer
-				tableErr := table.WriteTable(&buf, tableConfig)
-				if tableErr != nil {
-					ui.Fatal("Error printing table: %v", tableErr)
-				}
-				tableString := buf.String()
-				if idx < (len(tables) - 1) {
-					ui.Printf(tableString)
-				} else {
-					ui.Printfln(tableString)
-				}
+	for _, controller := range controllers {
+		if len(controller.Name) <= 0 {
+			continue
+		}
+
+		fanList := controller.Fans
+		sensorList := controller.Sensors
+
+		if len(fanList) <= 0 && len(sensorList) <= 0 {
+			continue
+		}
+
+		ui.Printfln("> %s", controller.Name)
+		printDeviceIdentity(controller)
+
+		var fanRows [][]string
+		for _, fan := range fanList {
+			pwm := fan.GetPwm()
+			rpm := fan.GetRpm()
+			isAuto, _ := fan.IsPwmAuto()
+			fanRows = append(fanRows, []string{
+				"", strconv.Itoa(fan.Index), strconv.Itoa(hwmon.GetChannel(fan.PwmOutput)), fan.Label, strconv.Itoa(rpm), strconv.Itoa(pwm), fmt.Sprintf("%v", isAuto),
+			})
+		}
+		var fanHeaders = []string{"Fans   ", "Index", "Channel", "Label", "RPM", "PWM", "Auto"}
+
+		fanTable := table.Table{
+			Headers: fanHeaders,
+			Rows:    fanRows,
+		}
+
+		var sensorRows [][]string
+		for _, sensor := range sensorList {
+			value, _ := sensor.GetValue()
+
+			_, file := filepath.Split(sensor.Input)
+			labelAndFile := fmt.Sprintf("%s (%s)", sensor.Label, file)
+
+			sensorRows = append(sensorRows, []string{
+				"", strconv.Itoa(sensor.Index), strconv.Itoa(hwmon.GetChannel(sensor.Input)), labelAndFile, strconv.Itoa(int(value)),
+			})
+		}
+		var sensorHeaders = []string{"Sensors", "Index", "Channel", "Label", "Value"}
+
+		sensorTable := table.Table{
+			Headers: sensorHeaders,
+			Rows:    sensorRows,
+		}
+
+		tables := []table.Table{fanTable, sensorTable}
+
+		for idx, table := range tables {
+			if table.Rows == nil {
+				continue
+			}
+			var buf bytes.Buffer
+			tableErr := table.WriteTable(&buf, tableConfig)
+			if tableErr != nil {
+				ui.Fatal("Error printing table: %v", tableErr)
+			}
+			tableString := buf.String()
+			if idx < (len(tables) - 1) {
+				ui.Printf(tableString)
+			} else {
+				ui.Printfln(tableString)
 			}
 		}
-	},
+	}
+}
+
+// prints the values which can be used to select the given device in the configuration
+func printDeviceIdentity(controller *hwmon.HwMonController) {
+	identity := [][]string{
+		{"chip", controller.ChipName},
+		{"platform", controller.Platform},
+		{"modalias", controller.Modalias},
+		{"devicePath", controller.DevicePath},
+	}
+	for _, entry := range identity {
+		if len(entry[1]) > 0 {
+			ui.Printfln("  %-10s %s", entry[0]+":", entry[1])
+		}
+	}
 }
 
 func init() {
+	detectCmd.Flags().StringVarP(&detectOutputFormat, "output", "o", "table", "Output format, one of: table | json | yaml")
 	rootCmd.AddCommand(detectCmd)
 }
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/markusressel/fan2go/internal/hwmon"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// helper function to create a fake sysfs tree with a single device, whose second fan has RPM limits
+func createDetectTestTree(t *testing.T) (root string) {
+	root = t.TempDir()
+	chipPath := filepath.Join(hwmon.GetClassPath(root), "hwmon2")
+	assert.NoError(t, os.MkdirAll(chipPath, 0755))
+
+	files := map[string]string{
+		"name":        "nct6798",
+		"pwm1":        "128",
+		"pwm1_enable": "2",
+		"fan1_input":  "1200",
+		"pwm2":        "100",
+		"fan2_input":  "800",
+		"fan2_min":    "300",
+		"fan2_max":    "2000",
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(chipPath, name), []byte(content+"\n"), 0644)
+		assert.NoError(t, err)
+	}
+	return root
+}
+
+func TestNewDetectedControllersRpmLimits(t *testing.T) {
+	// GIVEN
+	root := createDetectTestTree(t)
+	controllers := hwmon.GetSysfsChips(root)
+
+	// WHEN
+	result := newDetectedControllers(controllers)
+
+	// THEN
+	assert.Len(t, result, 1)
+	assert.Len(t, result[0].Fans, 2)
+
+	fan := result[0].Fans[0]
+	assert.Equal(t, 2, *fan.PwmEnable)
+	assert.Equal(t, 0, fan.MinPwm)
+	assert.Equal(t, 255, fan.MaxPwm)
+	assert.Nil(t, fan.MinRpm)
+	assert.Nil(t, fan.MaxRpm)
+
+	fan = result[0].Fans[1]
+	assert.Nil(t, fan.PwmEnable)
+	assert.Equal(t, 0, fan.MinPwm)
+	assert.Equal(t, 255, fan.MaxPwm)
+	assert.Equal(t, 300, *fan.MinRpm)
+	assert.Equal(t, 2000, *fan.MaxRpm)
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/guptarohit/asciigraph"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	historySince        time.Duration
+	historyUntil        time.Duration
+	historyOutputFormat string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [<device id>|<series>]...",
+	Short: "Print the recorded history of sensor, curve and fan values",
+	Long: `Prints the values recorded to the database while the history is enabled in the configuration.
+Each series holds the average values of a sensor ("sensor/<id>"), a curve ("curve/<id>")
+or the PWM and RPM values of a fan ("fan/<id>/pwm", "fan/<id>/rpm").
+
+The series can be limited to the given device IDs or series names.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// keep stdout parsable
+		ui.SetOutput(os.Stderr)
+
+		configuration.ReadConfigFile()
+
+		now := time.Now()
+		from := now.Add(-historySince)
+		to := now.Add(-historyUntil)
+
+		history := persistence.NewReadOnlyHistory(configuration.CurrentConfig.DbPath)
+		series, err := history.Load(from, to)
+		if err != nil {
+			ui.Fatal("Unable to load history: %v", err)
+		}
+
+		keys := filterHistorySeries(series, args)
+		if len(keys) <= 0 {
+			ui.Warning("No history recorded in the given time range")
+			return
+		}
+
+		switch historyOutputFormat {
+		case "graph":
+			printHistoryGraphs(series, keys)
+		case "csv":
+			err = writeHistoryCsv(series, keys)
+		case "json":
+			result := map[string][]persistence.HistoryPoint{}
+			for _, key := range keys {
+				result[key] = series[key]
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(result)
+		default:
+			ui.Fatal("Unknown output format '%s', use one of: graph | csv | json", historyOutputFormat)
+		}
+		if err != nil {
+			ui.Fatal("Unable to write history: %v", err)
+		}
+	},
+}
+
+// returns the sorted keys of all series matching any of the given device IDs or series names,
+// or all keys if no filter is given
+func filterHistorySeries(series map[string][]persistence.HistoryPoint, filters []string) []string {
+	var keys []string
+	for key := range series {
+		if len(filters) <= 0 {
+			keys = append(keys, key)
+			continue
+		}
+		parts := strings.Split(key, "/")
+		for _, filter := range filters {
+			if key == filter || (len(parts) > 1 && parts[1] == filter) {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printHistoryGraphs(series map[string][]persistence.HistoryPoint, keys []string) {
+	for idx, key := range keys {
+		points := series[key]
+		values := make([]float64, 0, len(points))
+		for _, point := range points {
+			values = append(values, point.Value)
+		}
+
+		if idx > 0 {
+			fmt.Println()
+			fmt.Println()
+		}
+
+		caption := fmt.Sprintf("%s (%s - %s)",
+			key,
+			points[0].Time.Format("2006-01-02 15:04"),
+			points[len(points)-1].Time.Format("2006-01-02 15:04"),
+		)
+		graph := asciigraph.Plot(values, asciigraph.Height(15), asciigraph.Width(100), asciigraph.Caption(caption))
+		fmt.Println(graph)
+	}
+}
+
+// writes the given series as CSV, with a header line of the form "time,<series>,<series>,..."
+// and one line per point in time, values missing at this time are left empty
+func writeHistoryCsv(series map[string][]persistence.HistoryPoint, keys []string) error {
+	rows := map[int64][]string{}
+	for column, key := range keys {
+		for _, point := range series[key] {
+			t := point.Time.UnixNano()
+			row, ok := rows[t]
+			if !ok {
+				row = make([]string, len(keys))
+				rows[t] = row
+			}
+			row[column] = strconv.FormatFloat(point.Value, 'f', -1, 64)
+		}
+	}
+
+	times := make([]int64, 0, len(rows))
+	for t := range rows {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i] < times[j]
+	})
+
+	w := csv.NewWriter(os.Stdout)
+	err := w.Write(append([]string{"time"}, keys...))
+	if err != nil {
+		return err
+	}
+	for _, t := range times {
+		record := append([]string{time.Unix(0, t).Format(time.RFC3339)}, rows[t]...)
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func init() {
+	historyCmd.Flags().DurationVar(&historySince, "since", 24*time.Hour, "Start of the time range, relative to now")
+	historyCmd.Flags().DurationVar(&historyUntil, "until", 0, "End of the time range, relative to now")
+	historyCmd.Flags().StringVarP(&historyOutputFormat, "output", "o", "graph", "Output format, one of: graph | csv | json")
+	rootCmd.AddCommand(historyCmd)
+}
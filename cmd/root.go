@@ -15,6 +15,7 @@ var (
 	noColor bool
 	noStyle bool
 	verbose bool
+	dryRun  bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -29,6 +30,9 @@ on your computer based on temperature sensors.`,
 		printHeader()
 
 		configuration.ReadConfigFile()
+		if dryRun {
+			configuration.CurrentConfig.DryRun = true
+		}
 		internal.RunDaemon()
 	},
 }
@@ -67,6 +71,7 @@ func Execute() {
 	rootCmd.PersistentFlags().BoolVarP(&noColor, "no-color", "", false, "Disable all terminal output coloration")
 	rootCmd.PersistentFlags().BoolVarP(&noStyle, "no-style", "", false, "Disable all terminal output styling")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "More verbose output")
+	rootCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "Observe only, never change any fan speed")
 
 	if err := rootCmd.Execute(); err != nil {
 		ui.Fatal("Error Executing daemon: %v", err)
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/replay"
+	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/mgutz/ansi"
+	"github.com/spf13/cobra"
+	"github.com/tomlazar/table"
+	"os"
+	"strconv"
+)
+
+var simulateOutputFormat string
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate <trace file>",
+	Short: "Replay a recorded trace of sensor values and print the resulting PWM values of all fans",
+	Long: `Replays a recorded trace of timestamped sensor values through the configured sensors,
+curves and fan controllers, faster than real time, without touching any fan.
+
+The trace is either a CSV file with a header line of the form "time,<sensor id>,...",
+or a JSON file (*.json) containing a list of {"time": ..., "values": {"<sensor id>": ...}} objects.
+Times are given in RFC 3339 format or as unix seconds, sensor values in milli-degree.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// keep stdout parsable
+		ui.SetOutput(os.Stderr)
+
+		configuration.ReadConfigFile()
+		// only virtual fans are controlled during a replay
+		configuration.CurrentConfig.DryRun = false
+
+		trace, err := replay.ReadTrace(args[0])
+		if err != nil {
+			ui.Fatal("Unable to read trace %s: %v", args[0], err)
+		}
+
+		pers := persistence.NewReadOnlyPersistence(configuration.CurrentConfig.DbPath)
+		result, err := replay.Run(configuration.CurrentConfig, trace, pers)
+		if err != nil {
+			ui.Fatal("Unable to replay trace %s: %v", args[0], err)
+		}
+
+		switch simulateOutputFormat {
+		case "csv":
+			err = replay.WriteCsv(os.Stdout, result)
+			if err != nil {
+				ui.Fatal("Unable to write result: %v", err)
+			}
+		case "table":
+			printSimulationTable(result)
+		default:
+			ui.Fatal("Unknown output format '%s', use one of: table | csv", simulateOutputFormat)
+		}
+	},
+}
+
+func printSimulationTable(result *replay.Result) {
+	var rows [][]string
+	for _, point := range result.Points {
+		row := []string{point.Time.Format("2006-01-02 15:04:05")}
+		for _, fanId := range result.FanIds {
+			row = append(row, strconv.Itoa(point.Pwm[fanId]))
+		}
+		rows = append(rows, row)
+	}
+
+	tab := table.Table{
+		Headers: append([]string{"Time"}, result.FanIds...),
+		Rows:    rows,
+	}
+	var buf bytes.Buffer
+	err := tab.WriteTable(&buf, &table.Config{
+		ShowIndex:       false,
+		Color:           !noColor,
+		AlternateColors: true,
+		TitleColorCode:  ansi.ColorCode("white+buf"),
+		AltColorCodes: []string{
+			ansi.ColorCode("white"),
+			ansi.ColorCode("white:236"),
+		},
+	})
+	if err != nil {
+		ui.Fatal("Unable to print result: %v", err)
+	}
+	os.Stdout.Write(buf.Bytes())
+}
+
+func init() {
+	simulateCmd.Flags().StringVarP(&simulateOutputFormat, "output", "o", "table", "Output format, one of: table | csv")
+	rootCmd.AddCommand(simulateCmd)
+}
@@ -18,4 +18,5 @@ require (
 	github.com/stretchr/testify v1.7.0
 	github.com/tomlazar/table v0.1.0
 	go.etcd.io/bbolt v1.3.6
+	gopkg.in/yaml.v2 v2.4.0
 )
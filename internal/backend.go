@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/markusressel/fan2go/internal/configuration"
 	"github.com/markusressel/fan2go/internal/controller"
@@ -9,27 +10,38 @@ import (
 	"github.com/markusressel/fan2go/internal/fans"
 	"github.com/markusressel/fan2go/internal/hwmon"
 	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/resume"
 	"github.com/markusressel/fan2go/internal/sensors"
 	"github.com/markusressel/fan2go/internal/statistics"
+	"github.com/markusressel/fan2go/internal/systemd"
 	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/markusressel/fan2go/internal/watchdog"
 	"github.com/oklog/run"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
-	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 func RunDaemon() {
-	if getProcessOwner() != "root" {
-		ui.Fatal("Fan control requires root permissions to be able to modify fan speeds, please run fan2go as root")
-	}
+	dryRun := configuration.CurrentConfig.DryRun
 
-	pers := persistence.NewPersistence(configuration.CurrentConfig.DbPath)
+	var pers persistence.Persistence
+	if dryRun {
+		ui.Warning("Running in dry-run mode, fan speeds will not be changed")
+		pers = persistence.NewReadOnlyPersistence(configuration.CurrentConfig.DbPath)
+	} else {
+		// simulated fans can be controlled without root permissions
+		if usesHardware() && getProcessOwner() != "root" {
+			ui.Fatal("Fan control requires root permissions to be able to modify fan speeds, please run fan2go as root")
+		}
+		pers = persistence.NewPersistence(configuration.CurrentConfig.DbPath)
+	}
 
 	InitializeObjects()
 
@@ -40,178 +52,402 @@ func RunDaemon() {
 		enabled := configuration.CurrentConfig.Statistics.Enabled
 		if enabled {
 			// === Prometheus Exporter
+			port := configuration.CurrentConfig.Statistics.Port
+			if port <= 0 || port >= 65535 {
+				port = 9000
+			}
+			endpoint := "/metrics"
+			mux := http.NewServeMux()
+			mux.Handle(endpoint, promhttp.Handler())
+			server := &http.Server{
+				Addr:    fmt.Sprintf(":%d", port),
+				Handler: mux,
+			}
+
 			g.Add(func() error {
-				port := configuration.CurrentConfig.Statistics.Port
-				if port <= 0 || port >= 65535 {
-					port = 9000
-				}
-				endpoint := "/metrics"
-				addr := fmt.Sprintf(":%d", port)
-				http.Handle(endpoint, promhttp.Handler())
-				if err := http.ListenAndServe(addr, nil); err != nil {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 					ui.Error("Cannot start prometheus metrics endpoint (%s)", err.Error())
+					// keep the daemon running without the metrics endpoint
+					<-ctx.Done()
 				}
-				select {}
+				return nil
 			}, func(err error) {
-				if err != nil {
-					ui.Warning("Error ")
-				}
+				_ = server.Shutdown(context.Background())
 			})
 		}
 	}
+	var w *watchdog.Watchdog
+	if configuration.CurrentConfig.Watchdog.Enabled {
+		// === watchdog
+		w = watchdog.NewWatchdog(configuration.CurrentConfig.Watchdog.Timeout)
+
+		g.Add(func() error {
+			return w.Run(ctx)
+		}, func(err error) {
+			cancel()
+		})
+
+		watchdogCollector := statistics.NewWatchdogCollector(w)
+		statistics.Register(watchdogCollector)
+	}
+	hotplug := newHotplugMonitor(HotplugPollingRate)
+	if usesHwMon() {
+		// === hwmon hotplug
+		g.Add(func() error {
+			return hotplug.Run(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	devices := newDeviceManager(ctx, cancel, pers, hotplug, w)
 	{
-		// === sensor monitoring
-		for _, sensor := range sensors.SensorMap {
-			pollingRate := configuration.CurrentConfig.TempSensorPollingRate
-			mon := NewSensorMonitor(sensor, pollingRate)
+		// === sensor monitoring and fan controllers
+		if len(fans.FanMap) == 0 {
+			ui.Fatal("No valid fan configurations, exiting.")
+		}
 
-			g.Add(func() error {
-				err := mon.Run(ctx)
-				if err != nil {
-					panic(err)
-				}
-				return err
-			}, func(err error) {
-				if err != nil {
-					ui.Warning("Error monitoring sensor: %v", err)
-				}
-			})
+		// fans have to be known before sensors, so they can be put into failsafe
+		// while the device of a sensor is missing
+		for _, fan := range fans.FanMap {
+			devices.startFan(fan)
 		}
+		for _, sensor := range sensors.SensorMap {
+			devices.startSensor(sensor)
+		}
+		devices.registerCollectors()
+
+		g.Add(func() error {
+			<-ctx.Done()
+			// returns only after all fans have been handed back
+			devices.Wait()
+			return nil
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if configuration.CurrentConfig.History.Enabled && !dryRun {
+		// === history
+		config := configuration.CurrentConfig.History
+		history := persistence.NewHistory(configuration.CurrentConfig.DbPath, config.Interval, config.Retention)
+		recorder := newHistoryRecorder(history, HistorySampleRate, config.Interval, hotplug.IsFanBound)
+
+		g.Add(func() error {
+			return recorder.Run(ctx)
+		}, func(err error) {
+			cancel()
+		})
 	}
 	{
-		// === fan controllers
-		for _, fan := range fans.FanMap {
-			updateRate := configuration.CurrentConfig.ControllerAdjustmentTickRate
-			fanController := controller.NewFanController(pers, fan, updateRate)
+		// === systemd notifications
+		g.Add(func() error {
+			return runSystemdNotifier(ctx, devices.getControllers, devices.getMonitors, hotplug.IsFanBound)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	{
+		// === configuration reload
+		hook := make(chan os.Signal, 1)
+		signal.Notify(hook, syscall.SIGHUP)
 
-			g.Add(func() error {
-				err := fanController.Run(ctx)
-				if err != nil {
-					panic(err)
+		g.Add(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-hook:
+					reloadConfig(devices)
 				}
-				return err
-			}, func(err error) {
-				if err != nil {
-					ui.Warning("Something went wrong: %v", err)
+			}
+		}, func(err error) {
+			signal.Stop(hook)
+			cancel()
+		})
+	}
+	{
+		// === suspend/resume detection
+		hook := make(chan os.Signal, 1)
+		signal.Notify(hook, syscall.SIGUSR1)
+		detector := resume.NewDetector(resume.DefaultPollingRate, resume.DefaultThreshold)
+
+		g.Add(func() error {
+			return detector.Run(ctx, hook, func() {
+				ui.Info("System resumed, re-applying fan control...")
+				for _, c := range devices.getControllers() {
+					c.HandleResume()
 				}
 			})
-		}
-
-		if len(fans.FanMap) == 0 {
-			ui.Fatal("No valid fan configurations, exiting.")
-		}
+		}, func(err error) {
+			signal.Stop(hook)
+			cancel()
+		})
 	}
 	{
-		sig := make(chan os.Signal)
-		signal.Notify(sig, os.Interrupt, syscall.SIGTERM, os.Kill)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 
 		g.Add(func() error {
-			<-sig
-			ui.Info("Exiting...")
+			select {
+			case <-sig:
+				ui.Info("Exiting...")
+			case <-ctx.Done():
+			}
 			return nil
 		}, func(err error) {
+			signal.Stop(sig)
 			cancel()
-			close(sig)
 		})
 	}
 
+	// all actors (including the fan controllers restoring their fans) have returned at this point
 	if err := g.Run(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func InitializeObjects() {
-	controllers := hwmon.GetChips()
+// notifies systemd once all fan controllers are running, and sends watchdog pings
+// as long as all of them are making progress
+func runSystemdNotifier(ctx context.Context, getControllers func() []controller.FanController, getMonitors func() []SensorMonitor, isFanBound func(fanId string) bool) error {
+	watchdogInterval, err := systemd.WatchdogInterval()
+	if err != nil {
+		ui.Warning("Ignoring systemd watchdog: %v", err)
+	}
 
-	var sensorList []sensors.Sensor
-	for _, config := range configuration.CurrentConfig.Sensors {
-		if config.HwMon != nil {
-			found := false
-			for _, c := range controllers {
-				matched, err := regexp.MatchString("(?i)"+config.HwMon.Platform, c.Platform)
+	pollingRate := 500 * time.Millisecond
+	if watchdogInterval > 0 && watchdogInterval/2 < pollingRate {
+		pollingRate = watchdogInterval / 2
+	}
+
+	notifier := &systemdNotifier{
+		watchdogInterval: watchdogInterval,
+		getControllers:   getControllers,
+		getMonitors:      getMonitors,
+		isFanBound:       isFanBound,
+	}
+	tick := time.NewTicker(pollingRate)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_, _ = systemd.Notify(systemd.StateStopping)
+			return nil
+		case now := <-tick.C:
+			becameReady, ping := notifier.update(now)
+			if becameReady {
+				ui.Info("All fan controllers are running")
+				_, err = systemd.Notify(systemd.StateReady)
 				if err != nil {
-					ui.Fatal("Failed to match platform regex of %s (%s) against controller platform %s", config.ID, config.HwMon.Platform, c.Platform)
-				}
-				if matched {
-					found = true
-					config.HwMon.TempInput = c.Sensors[config.HwMon.Index-1].Input
+					ui.Warning("Unable to notify systemd: %v", err)
 				}
+				_, _ = systemd.NotifyStatus("Controlling %d fans", len(getControllers()))
 			}
-			if !found {
-				ui.Fatal("Couldn't find hwmon device with platform '%s' for sensor: %s. Run 'fan2go detect' again and correct any mistake.", config.HwMon.Platform, config.ID)
+			if ping {
+				_, _ = systemd.Notify(systemd.StateWatchdog)
 			}
 		}
+	}
+}
 
-		sensor, err := sensors.NewSensor(config)
-		if err != nil {
-			ui.Fatal("Unable to process sensor configuration: %s", config.ID)
+// systemdNotifier decides when fan2go is ready, and when a watchdog ping is sent,
+// based on the heartbeats of the fan controllers and sensor monitors
+type systemdNotifier struct {
+	// 0 if systemd doesn't expect watchdog pings
+	watchdogInterval time.Duration
+	getControllers   func() []controller.FanController
+	getMonitors      func() []SensorMonitor
+	isFanBound       func(fanId string) bool
+
+	ready bool
+	// the latest heartbeat at the time of the last watchdog ping
+	lastHeartbeat time.Time
+}
+
+// update checks the heartbeats at the given time, and returns whether all fan controllers
+// have just started running, and whether a watchdog ping should be sent.
+// A ping is only sent if a controller or monitor made progress since the last ping and, once
+// all controllers are running, none of them is stuck. The initialization sequence may take a
+// while, so the control loops are only required to make progress once they are running.
+func (n *systemdNotifier) update(now time.Time) (becameReady bool, ping bool) {
+	started := true
+	alive := true
+	var latest time.Time
+	for _, c := range n.getControllers() {
+		if !n.isFanBound(c.GetFanId()) {
+			// waiting for its device to appear
+			continue
+		}
+		lastHeartbeat := c.GetLastHeartbeat()
+		if lastHeartbeat.IsZero() {
+			started = false
+		} else if n.watchdogInterval > 0 && now.Sub(lastHeartbeat) > n.watchdogInterval {
+			alive = false
+		}
+		if lastHeartbeat.After(latest) {
+			latest = lastHeartbeat
+		}
+	}
+	for _, mon := range n.getMonitors() {
+		if lastHeartbeat := mon.GetLastHeartbeat(); lastHeartbeat.After(latest) {
+			latest = lastHeartbeat
 		}
-		sensorList = append(sensorList, sensor)
+	}
 
-		currentValue, err := sensor.GetValue()
-		if err != nil {
-			ui.Warning("Error reading sensor %s: %v", config.ID, err)
+	if !n.ready && started {
+		n.ready = true
+		becameReady = true
+	}
+
+	progressed := latest.After(n.lastHeartbeat)
+	if n.watchdogInterval > 0 && progressed && (!n.ready || alive) {
+		n.lastHeartbeat = latest
+		ping = true
+	}
+	return becameReady, ping
+}
+
+// returns the ids of all fans whose speed depends on the given sensor
+func getFansUsingSensor(sensorId string) (result []string) {
+	config := configuration.GetConfig()
+	curveConfigs := map[string]configuration.CurveConfig{}
+	for _, curveConfig := range config.Curves {
+		curveConfigs[curveConfig.ID] = curveConfig
+	}
+
+	var curveUsesSensor func(curveId string) bool
+	curveUsesSensor = func(curveId string) bool {
+		curveConfig, ok := curveConfigs[curveId]
+		if !ok {
+			return false
+		}
+		if curveConfig.Linear != nil {
+			return curveConfig.Linear.Sensor == sensorId
+		}
+		if curveConfig.Pid != nil {
+			return curveConfig.Pid.Sensor == sensorId
+		}
+		if curveConfig.Function != nil {
+			// function curves are validated to be free of cycles
+			for _, id := range curveConfig.Function.Curves {
+				if curveUsesSensor(id) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, fanConfig := range config.Fans {
+		usesZeroRpmSensor := fanConfig.ZeroRpm != nil && fanConfig.ZeroRpm.Sensor == sensorId
+		if usesZeroRpmSensor || curveUsesSensor(fanConfig.Curve) {
+			result = append(result, fanConfig.ID)
 		}
-		sensor.SetMovingAvg(currentValue)
+	}
+	return result
+}
 
-		sensors.SensorMap[config.ID] = sensor
+// returns true if any fan is backed by real hardware, which requires root permissions to be controlled
+func usesHardware() bool {
+	for _, config := range configuration.CurrentConfig.Fans {
+		if config.Simulated == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// returns true if any fan or sensor needs to be resolved to a hwmon device
+func usesHwMon() bool {
+	for _, config := range configuration.CurrentConfig.Fans {
+		if config.HwMon != nil {
+			return true
+		}
+	}
+	for _, config := range configuration.CurrentConfig.Sensors {
+		if config.HwMon != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func InitializeObjects() {
+	var controllers []*hwmon.HwMonController
+	if usesHwMon() {
+		controllers = hwmon.GetChips()
 	}
 
-	sensorCollector := statistics.NewSensorCollector(sensorList)
-	statistics.Register(sensorCollector)
+	for _, config := range configuration.CurrentConfig.Sensors {
+		sensor, err := createSensor(config, controllers)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		sensors.SensorMap[config.ID] = sensor
+	}
 
-	var curveList []curves.SpeedCurve
 	for _, config := range configuration.CurrentConfig.Curves {
 		curve, err := curves.NewSpeedCurve(config)
 		if err != nil {
 			ui.Fatal("Unable to process curve configuration: %s", config.ID)
 		}
-		curveList = append(curveList, curve)
 		curves.SpeedCurveMap[config.ID] = curve
 	}
 
-	curveCollector := statistics.NewCurveCollector(curveList)
-	statistics.Register(curveCollector)
-
-	var fanList []fans.Fan
 	for _, config := range configuration.CurrentConfig.Fans {
-		if config.HwMon != nil {
-			found := false
-			for _, c := range controllers {
-				matched, err := regexp.MatchString("(?i)"+config.HwMon.Platform, c.Platform)
-				if err != nil {
-					ui.Fatal("Failed to match platform regex of %s (%s) against controller platform %s", config.ID, config.HwMon.Platform, c.Platform)
-				}
-				if matched {
-					found = true
-					index := config.HwMon.Index - 1
-					if len(c.Fans) > index {
-						fan := c.Fans[index]
-						config.HwMon.PwmOutput = fan.PwmOutput
-						config.HwMon.RpmInput = fan.RpmInput
-					}
-
-					break
-				}
-			}
-			if !found {
-				ui.Fatal("Couldn't find hwmon device with platform '%s' for fan: %s", config.HwMon.Platform, config.ID)
-			}
-		}
-
-		fan, err := fans.NewFan(config)
+		fan, err := createFan(config, controllers)
 		if err != nil {
-			ui.Fatal("Unable to process fan configuration: %s", config.ID)
+			ui.Fatal("%v", err)
 		}
 		fans.FanMap[config.ID] = fan
+	}
+}
+
+// creates the sensor of the given configuration, resolving the paths of a hwmon sensor using the given devices.
+// A hwmon sensor whose device is missing is created anyway, it is bound once the device appears.
+func createSensor(config configuration.SensorConfig, controllers []*hwmon.HwMonController) (sensors.Sensor, error) {
+	if config.HwMon != nil {
+		tempInput, err := resolveSensorHwMonPaths(config, controllers)
+		if errors.Is(err, hwmon.ErrDeviceNotFound) {
+			ui.Warning("Sensor %s: %v, waiting for it to appear. Run 'fan2go detect' again and correct any mistake.", config.ID, err)
+		} else if err != nil {
+			return nil, fmt.Errorf("Sensor %s: %v", config.ID, err)
+		}
+		config.HwMon.TempInput = tempInput
+	}
 
-		fanList = append(fanList, fan)
+	sensor, err := sensors.NewSensor(config)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to process sensor configuration: %s", config.ID)
 	}
 
-	fanCollector := statistics.NewFanCollector(fanList)
-	statistics.Register(fanCollector)
+	currentValue, err := sensor.GetValue()
+	if err != nil {
+		ui.Warning("Error reading sensor %s: %v", config.ID, err)
+	}
+	sensor.SetMovingAvg(currentValue)
+
+	return sensor, nil
+}
+
+// creates the fan of the given configuration, resolving the paths of a hwmon fan using the given devices.
+// A hwmon fan whose device is missing is created anyway, it is bound once the device appears.
+func createFan(config configuration.FanConfig, controllers []*hwmon.HwMonController) (fans.Fan, error) {
+	if config.HwMon != nil {
+		pwmOutput, rpmInput, err := resolveFanHwMonPaths(config, controllers)
+		if errors.Is(err, hwmon.ErrDeviceNotFound) {
+			ui.Warning("Fan %s: %v, waiting for it to appear. Run 'fan2go detect' again and correct any mistake.", config.ID, err)
+		} else if err != nil {
+			return nil, fmt.Errorf("Fan %s: %v", config.ID, err)
+		}
+		config.HwMon.PwmOutput = pwmOutput
+		config.HwMon.RpmInput = rpmInput
+	}
 
+	fan, err := fans.NewFan(config)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to process fan configuration: %s", config.ID)
+	}
+	return fan, nil
 }
 
 func getProcessOwner() string {
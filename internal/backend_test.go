@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"github.com/markusressel/fan2go/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockController struct {
+	controller.FanController
+	id            string
+	lastHeartbeat time.Time
+}
+
+func (c *mockController) GetFanId() string {
+	return c.id
+}
+
+func (c *mockController) GetLastHeartbeat() time.Time {
+	return c.lastHeartbeat
+}
+
+type mockMonitor struct {
+	SensorMonitor
+	lastHeartbeat time.Time
+}
+
+func (m *mockMonitor) GetLastHeartbeat() time.Time {
+	return m.lastHeartbeat
+}
+
+// helper function to create a notifier for the given controllers and monitors
+func createNotifier(controllers []controller.FanController, monitors []SensorMonitor) *systemdNotifier {
+	return &systemdNotifier{
+		watchdogInterval: 10 * time.Second,
+		getControllers: func() []controller.FanController {
+			return controllers
+		},
+		getMonitors: func() []SensorMonitor {
+			return monitors
+		},
+		isFanBound: func(fanId string) bool {
+			return true
+		},
+	}
+}
+
+func TestSystemdNotifierPingsOnlyOnProgress(t *testing.T) {
+	// GIVEN
+	now := time.Unix(1000, 0)
+	c := &mockController{id: "fan"}
+	mon := &mockMonitor{lastHeartbeat: now}
+	notifier := createNotifier([]controller.FanController{c}, []SensorMonitor{mon})
+
+	// WHEN
+	// the fan is still being initialized, but the sensor is read
+	becameReady1, ping1 := notifier.update(now)
+	// nothing happened since the last ping
+	becameReady2, ping2 := notifier.update(now.Add(time.Second))
+	mon.lastHeartbeat = now.Add(2 * time.Second)
+	becameReady3, ping3 := notifier.update(now.Add(2 * time.Second))
+
+	// THEN
+	assert.False(t, becameReady1)
+	assert.True(t, ping1)
+	assert.False(t, becameReady2)
+	assert.False(t, ping2)
+	assert.False(t, becameReady3)
+	assert.True(t, ping3)
+}
+
+func TestSystemdNotifierStuckController(t *testing.T) {
+	// GIVEN
+	now := time.Unix(1000, 0)
+	c := &mockController{id: "fan", lastHeartbeat: now}
+	mon := &mockMonitor{lastHeartbeat: now}
+	notifier := createNotifier([]controller.FanController{c}, []SensorMonitor{mon})
+
+	// WHEN
+	becameReady1, ping1 := notifier.update(now)
+	// the sensor is still read, but the controller is stuck
+	now = now.Add(20 * time.Second)
+	mon.lastHeartbeat = now
+	becameReady2, ping2 := notifier.update(now)
+
+	// THEN
+	assert.True(t, becameReady1)
+	assert.True(t, ping1)
+	assert.False(t, becameReady2)
+	assert.False(t, ping2)
+}
@@ -1,18 +1,26 @@
 package configuration
 
 import (
-	"github.com/looplab/tarjan"
+	"fmt"
 	"github.com/markusressel/fan2go/internal/ui"
-	"github.com/markusressel/fan2go/internal/util"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 	"os"
+	"sync"
 	"time"
 )
 
 type Configuration struct {
 	DbPath string `json:"dbPath"`
 
+	// DryRun runs the daemon without ever writing to any fan
+	DryRun bool `json:"dryRun"`
+
+	// HwMonBackend is the backend used to detect hwmon devices
+	HwMonBackend string `json:"hwMonBackend"`
+	// SysfsRoot is the mount point of sysfs, scanned by the sysfs backend
+	SysfsRoot string `json:"sysfsRoot"`
+
 	RunFanInitializationInParallel bool    `json:"runFanInitializationInParallel"`
 	MaxRpmDiffForSettledFan        float64 `json:"maxRpmDiffForSettledFan"`
 
@@ -29,10 +37,30 @@ type Configuration struct {
 	Curves  []CurveConfig  `json:"curves"`
 
 	Statistics StatisticsConfig `json:"statistics"`
+	Watchdog   WatchdogConfig   `json:"watchdog"`
+	Resume     ResumeConfig     `json:"resume"`
+	History    HistoryConfig    `json:"history"`
 }
 
 var CurrentConfig Configuration
 
+// guards CurrentConfig, which is replaced when the configuration is reloaded
+var currentConfigMu sync.RWMutex
+
+// GetConfig returns the current configuration, it is safe to use while the configuration is reloaded
+func GetConfig() Configuration {
+	currentConfigMu.RLock()
+	defer currentConfigMu.RUnlock()
+	return CurrentConfig
+}
+
+// SetConfig replaces the current configuration
+func SetConfig(config Configuration) {
+	currentConfigMu.Lock()
+	defer currentConfigMu.Unlock()
+	CurrentConfig = config
+}
+
 // InitConfig reads in config file and ENV variables if set.
 func InitConfig(cfgFile string) {
 	viper.SetConfigName("fan2go")
@@ -60,6 +88,8 @@ func InitConfig(cfgFile string) {
 
 func setDefaultValues() {
 	viper.SetDefault("dbpath", "/etc/fan2go/fan2go.db")
+	viper.SetDefault("HwMonBackend", HwMonBackendSysfs)
+	viper.SetDefault("SysfsRoot", "/sys")
 	viper.SetDefault("RunFanInitializationInParallel", true)
 	viper.SetDefault("MaxRpmDiffForSettledFan", 10.0)
 	viper.SetDefault("TempSensorPollingRate", 200*time.Millisecond)
@@ -69,6 +99,14 @@ func setDefaultValues() {
 
 	viper.SetDefault("ControllerAdjustmentTickRate", 200*time.Millisecond)
 
+	viper.SetDefault("watchdog.enabled", false)
+	viper.SetDefault("watchdog.timeout", 10*time.Second)
+	viper.SetDefault("watchdog.action", WatchdogActionMaxPwm)
+
+	viper.SetDefault("history.enabled", false)
+	viper.SetDefault("history.interval", 1*time.Minute)
+	viper.SetDefault("history.retention", 7*24*time.Hour)
+
 	viper.SetDefault("sensors", []SensorConfig{})
 	viper.SetDefault("fans", []FanConfig{})
 }
@@ -78,11 +116,35 @@ func ReadConfigFile() {
 		// config file is required, so we fail here
 		ui.Fatal("Error reading config file, %s", err)
 	}
+	loadConfigFile()
+}
+
+// ReadConfigFileIfExists reads and validates the configuration file like ReadConfigFile,
+// but uses the default values if no configuration file can be found.
+func ReadConfigFileIfExists() {
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			ui.Fatal("Error reading config file, %s", err)
+		}
+		ui.Info("No configuration file found, using default values")
+		LoadConfig()
+		return
+	}
+	loadConfigFile()
+}
+
+// loads and validates the configuration file read by viper
+func loadConfigFile() {
 	// this is only populated _after_ ReadInConfig()
 	ui.Info("Using configuration file at: %s", viper.ConfigFileUsed())
 
 	LoadConfig()
-	validateConfig()
+	if err := validateConfig(&CurrentConfig); err != nil {
+		for _, problem := range err.Problems {
+			ui.Error("%s", problem)
+		}
+		ui.Fatal("Invalid configuration, found %d error(s)", len(err.Problems))
+	}
 }
 
 func LoadConfig() {
@@ -93,121 +155,27 @@ func LoadConfig() {
 	}
 }
 
-func validateConfig() {
-	config := &CurrentConfig
-
-	validateSensors(config)
-	validateCurves(config)
-	validateFans(config)
-}
-
-func validateSensors(config *Configuration) {
-	for _, sensorConfig := range config.Sensors {
-		if sensorConfig.HwMon != nil && sensorConfig.File != nil {
-			ui.Fatal("Sensor %s: only one sensor type can be used per sensor definition block", sensorConfig.ID)
-		}
-
-		if sensorConfig.HwMon == nil && sensorConfig.File == nil {
-			ui.Fatal("Sensor %s: sub-configuration for sensor is missing, use one of: hwmon | file", sensorConfig.ID)
-		}
-
-		if !isSensorConfigInUse(sensorConfig, config.Curves) {
-			ui.Warning("Unused sensor configuration: %s", sensorConfig.ID)
-		}
-	}
-}
-
-func isSensorConfigInUse(config SensorConfig, curves []CurveConfig) bool {
-	for _, curveConfig := range curves {
-		if curveConfig.Function != nil {
-			// function curves cannot reference sensors
-			continue
-		}
-		if curveConfig.Linear.Sensor == config.ID {
-			return true
-		}
-	}
-
-	return false
-}
-
-func validateCurves(config *Configuration) {
-	graph := make(map[interface{}][]interface{})
-
-	for _, curveConfig := range config.Curves {
-		if curveConfig.Linear != nil && curveConfig.Function != nil {
-			ui.Fatal("Curve %s: only one curve type can be used per curve definition block", curveConfig.ID)
-		}
-
-		if curveConfig.Linear == nil && curveConfig.Function == nil {
-			ui.Fatal("Curve %s: sub-configuration for curve is missing, use one of: linear | function", curveConfig.ID)
-		}
-
-		if !isCurveConfigInUse(curveConfig, config.Curves, config.Fans) {
-			ui.Warning("Unused curve configuration: %s", curveConfig.ID)
-		}
-
-		if curveConfig.Function != nil {
-			var connections []interface{}
-			for _, curve := range curveConfig.Function.Curves {
-				connections = append(connections, curve)
-			}
-			graph[curveConfig.ID] = connections
-		}
-
-		if curveConfig.Linear != nil {
-			if len(curveConfig.Linear.Sensor) <= 0 {
-				ui.Fatal("Curve %s: Missing sensorId", curveConfig.ID)
-			}
-		}
-
+// ParseConfigFile reads the configuration file, without validating it or changing the CurrentConfig.
+func ParseConfigFile() (Configuration, error) {
+	var config Configuration
+	if err := viper.ReadInConfig(); err != nil {
+		return config, fmt.Errorf("error reading config file, %v", err)
 	}
-
-	validateNoLoops(graph)
-}
-
-func validateNoLoops(graph map[interface{}][]interface{}) {
-	output := tarjan.Connections(graph)
-	for _, items := range output {
-		if len(items) > 1 {
-			ui.Fatal("You have created a curve  dependency cycle: %v", items)
-		}
+	if err := viper.Unmarshal(&config); err != nil {
+		return config, fmt.Errorf("unable to decode into struct, %v", err)
 	}
+	return config, nil
 }
 
-func isCurveConfigInUse(config CurveConfig, curves []CurveConfig, fans []FanConfig) bool {
-	for _, curveConfig := range curves {
-		if curveConfig.Linear != nil {
-			// linear curves cannot reference curves
-			continue
-		}
-
-		if util.ContainsString(curveConfig.Function.Curves, config.ID) {
-			return true
-		}
-	}
-
-	for _, fanConfig := range fans {
-		if fanConfig.Curve == config.ID {
-			return true
-		}
+// ReloadConfigFile reads and validates the configuration file again, without changing the CurrentConfig.
+// Returns an error if the configuration file cannot be read or is invalid.
+func ReloadConfigFile() (Configuration, error) {
+	config, err := ParseConfigFile()
+	if err != nil {
+		return config, err
 	}
-
-	return false
-}
-
-func validateFans(config *Configuration) {
-	for _, fanConfig := range config.Fans {
-		if fanConfig.HwMon != nil && fanConfig.File != nil {
-			ui.Fatal("Fans %s: only one fan type can be used per fan definition block", fanConfig.ID)
-		}
-
-		if fanConfig.HwMon == nil && fanConfig.File == nil {
-			ui.Fatal("Fans %s: sub-configuration for fan is missing, use one of: hwmon | file", fanConfig.ID)
-		}
-
-		if len(fanConfig.Curve) <= 0 {
-			ui.Fatal("Fan %s: missing curve definition in configuration entry", fanConfig.ID)
-		}
+	if err := validateConfig(&config); err != nil {
+		return config, err
 	}
+	return config, nil
 }
@@ -0,0 +1,46 @@
+package configuration
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// helper function to initialize viper, searching for the configuration file in the given directory only
+func initTestConfig(t *testing.T, dir string) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigName("fan2go")
+	viper.AddConfigPath(dir)
+	setDefaultValues()
+}
+
+func TestReadConfigFileIfExistsWithoutFile(t *testing.T) {
+	// GIVEN
+	initTestConfig(t, t.TempDir())
+
+	// WHEN
+	ReadConfigFileIfExists()
+
+	// THEN
+	assert.Equal(t, "/etc/fan2go/fan2go.db", GetConfig().DbPath)
+	assert.Equal(t, HwMonBackendSysfs, GetConfig().HwMonBackend)
+}
+
+func TestReadConfigFileIfExists(t *testing.T) {
+	// GIVEN
+	dir := t.TempDir()
+	content := "dbPath: " + filepath.Join(dir, "fan2go.db") + "\nhwMonBackend: libsensors\n"
+	err := ioutil.WriteFile(filepath.Join(dir, "fan2go.yaml"), []byte(content), 0644)
+	assert.NoError(t, err)
+	initTestConfig(t, dir)
+
+	// WHEN
+	ReadConfigFileIfExists()
+
+	// THEN
+	assert.Equal(t, filepath.Join(dir, "fan2go.db"), GetConfig().DbPath)
+	assert.Equal(t, HwMonBackendLibSensors, GetConfig().HwMonBackend)
+}
@@ -4,6 +4,7 @@ type CurveConfig struct {
 	ID       string               `json:"id"`
 	Linear   *LinearCurveConfig   `json:"linear,omitempty"`
 	Function *FunctionCurveConfig `json:"function,omitempty"`
+	Pid      *PidCurveConfig      `json:"pid,omitempty"`
 }
 
 type LinearCurveConfig struct {
@@ -13,6 +14,14 @@ type LinearCurveConfig struct {
 	Steps  map[int]float64 `json:"steps"`
 }
 
+type PidCurveConfig struct {
+	Sensor   string  `json:"sensor"`
+	SetPoint float64 `json:"setPoint"`
+	P        float64 `json:"p"`
+	I        float64 `json:"i"`
+	D        float64 `json:"d"`
+}
+
 const (
 	FunctionAverage = "average"
 	FunctionDelta   = "delta"
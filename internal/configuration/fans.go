@@ -1,21 +1,132 @@
 package configuration
 
+import "time"
+
+const (
+	// ControlModePwm applies the curve value to the PWM output of a fan as is
+	ControlModePwm = "pwm"
+	// ControlModeRpm treats the curve value as a share of the measured RPM range of a fan
+	ControlModeRpm = "rpm"
+	// ControlModeClosedLoop treats the curve value as a share of the measured RPM range of a fan
+	// and continuously adjusts the PWM value until the measured RPM matches this target
+	ControlModeClosedLoop = "closedLoop"
+)
+
+const (
+	// ThirdPartyPolicyOverride overwrites PWM changes made by third parties on the next controller tick
+	ThirdPartyPolicyOverride = "override"
+	// ThirdPartyPolicyYield stops controlling a fan for a backoff period after a third party changed its PWM
+	ThirdPartyPolicyYield = "yield"
+	// ThirdPartyPolicyRelinquish restores the original pwm_enable mode of a fan and stops controlling it
+	ThirdPartyPolicyRelinquish = "relinquish"
+)
+
 type FanConfig struct {
-	ID        string          `json:"id"`
-	NeverStop bool            `json:"neverStop"`
-	StartPwm  *int            `json:"startPwm,omitempty"`
-	Curve     string          `json:"curve"`
-	HwMon     *HwMonFanConfig `json:"hwMon,omitempty"`
-	File      *FileFanConfig  `json:"file,omitempty"`
+	ID             string                `json:"id"`
+	NeverStop      bool                  `json:"neverStop"`
+	StartPwm       *int                  `json:"startPwm,omitempty"`
+	Curve          string                `json:"curve"`
+	ControlMode    string                `json:"controlMode,omitempty"`
+	ClosedLoop     *ClosedLoopConfig     `json:"closedLoop,omitempty"`
+	RampRate       *RampRateConfig       `json:"rampRate,omitempty"`
+	Smoothing      float64               `json:"smoothing"`
+	ThirdParty     *ThirdPartyConfig     `json:"thirdParty,omitempty"`
+	SpinUp         *SpinUpConfig         `json:"spinUp,omitempty"`
+	ZeroRpm        *ZeroRpmConfig        `json:"zeroRpm,omitempty"`
+	Initialization *InitializationConfig `json:"initialization,omitempty"`
+	HwMon          *HwMonFanConfig       `json:"hwMon,omitempty"`
+	File           *FileFanConfig        `json:"file,omitempty"`
+	Simulated      *SimulatedFanConfig   `json:"simulated,omitempty"`
 }
 
+// ClosedLoopConfig holds the PID gains used to correct the PWM value
+// of a fan in ControlModeClosedLoop, based on the RPM error
+type ClosedLoopConfig struct {
+	P float64 `json:"p"`
+	I float64 `json:"i"`
+	D float64 `json:"d"`
+}
+
+// RampRateConfig limits how fast the PWM of a fan may change,
+// in PWM steps per second. A value of 0 disables the limit.
+type RampRateConfig struct {
+	Increase float64 `json:"increase"`
+	Decrease float64 `json:"decrease"`
+}
+
+// HwMonFanConfig selects a device using at least one of
+// Platform, Chip, Modalias, PciAddress or DevicePath,
+// and a fan of this device using at least one of Index, Channel or Label.
 type HwMonFanConfig struct {
-	Platform  string `json:"platform"`
-	Index     int    `json:"index"`
-	PwmOutput string
-	RpmInput  string
+	Platform   string `json:"platform,omitempty"`
+	Chip       string `json:"chip,omitempty"`
+	Modalias   string `json:"modalias,omitempty"`
+	PciAddress string `json:"pciAddress,omitempty"`
+	DevicePath string `json:"devicePath,omitempty"`
+	Index      int    `json:"index,omitempty"`
+	Channel    int    `json:"channel,omitempty"`
+	Label      string `json:"label,omitempty"`
+	PwmOutput  string
+	RpmInput   string
+}
+
+func (c HwMonFanConfig) hasDeviceSelector() bool {
+	return len(c.Platform) > 0 || len(c.Chip) > 0 || len(c.Modalias) > 0 || len(c.PciAddress) > 0 || len(c.DevicePath) > 0
+}
+
+func (c HwMonFanConfig) hasChannelSelector() bool {
+	return c.Index > 0 || c.Channel > 0 || len(c.Label) > 0
 }
 
 type FileFanConfig struct {
 	Path string `json:"path"`
 }
+
+// SimulatedFanConfig describes the physical behaviour of a simulated fan
+type SimulatedFanConfig struct {
+	// MaxRpm is the speed of the fan at max PWM
+	MaxRpm int `json:"maxRpm"`
+	// StartPwm is the lowest PWM value at which the fan starts to rotate from a stand still
+	StartPwm int `json:"startPwm"`
+	// StopPwm is the PWM value below which a rotating fan stops
+	StopPwm int `json:"stopPwm"`
+	// Inertia is the time constant of the fan speed following a PWM change
+	Inertia time.Duration `json:"inertia"`
+	// Noise is the max deviation of a single RPM measurement from the actual speed
+	Noise float64 `json:"noise"`
+}
+
+// SpinUpConfig defines how to start a fan from a stand still
+type SpinUpConfig struct {
+	// Pwm is the PWM value used to start the fan, defaults to its start PWM
+	Pwm *int `json:"pwm,omitempty"`
+	// Duration is the time to drive the fan at this PWM value, before dropping to the curve target
+	Duration time.Duration `json:"duration"`
+}
+
+// ZeroRpmConfig defines when a fan is allowed to stop completely and when to start it again.
+// The thresholds are compared to the value of the given sensor (in degree) or,
+// if no sensor is given, to the curve value (0..255) of the fan.
+type ZeroRpmConfig struct {
+	Sensor     string        `json:"sensor,omitempty"`
+	StopBelow  float64       `json:"stopBelow"`
+	StartAbove float64       `json:"startAbove"`
+	MinOnTime  time.Duration `json:"minOnTime"`
+	MinOffTime time.Duration `json:"minOffTime"`
+}
+
+// ThirdPartyConfig defines how to react when a third party changes the PWM value of a fan
+type ThirdPartyConfig struct {
+	Policy  string        `json:"policy"`
+	Backoff time.Duration `json:"backoff"`
+}
+
+// InitializationConfig configures the sequence used to measure the fan curve of a fan
+type InitializationConfig struct {
+	// StepSize is the PWM step size of the initial, coarse sweep
+	StepSize int `json:"stepSize"`
+	// SettleTimeout is the max time to wait for the fan speed to settle at a single PWM value
+	SettleTimeout time.Duration `json:"settleTimeout"`
+	// MaxRpmDiffForSettledFan overrides the global setting of the same name for this fan
+	MaxRpmDiffForSettledFan float64 `json:"maxRpmDiffForSettledFan"`
+}
@@ -0,0 +1,12 @@
+package configuration
+
+import "time"
+
+// HistoryConfig configures the history of sensor, curve and fan values recorded to the database
+type HistoryConfig struct {
+	Enabled bool `json:"enabled"`
+	// Interval is the time span values are averaged over, before they are recorded
+	Interval time.Duration `json:"interval"`
+	// Retention is the time span after which recorded values are overwritten
+	Retention time.Duration `json:"retention"`
+}
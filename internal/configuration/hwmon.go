@@ -0,0 +1,8 @@
+package configuration
+
+const (
+	// HwMonBackendSysfs detects devices by scanning the sysfs hwmon class directly
+	HwMonBackendSysfs = "sysfs"
+	// HwMonBackendLibSensors detects devices using libsensors (lm-sensors)
+	HwMonBackendLibSensors = "libsensors"
+)
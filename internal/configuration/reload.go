@@ -0,0 +1,147 @@
+package configuration
+
+import (
+	"reflect"
+)
+
+// Diff lists the IDs of all sensors, curves and fans which differ between two configurations
+type Diff struct {
+	AddedSensors   []string
+	RemovedSensors []string
+	ChangedSensors []string
+
+	AddedCurves   []string
+	RemovedCurves []string
+	ChangedCurves []string
+
+	AddedFans   []string
+	RemovedFans []string
+	// ChangedFans are fans whose configuration changed, while they are still bound to the same device
+	ChangedFans []string
+	// ReboundFans are fans which are bound to a different device (or fan type) than before
+	ReboundFans []string
+}
+
+// IsEmpty returns true if no sensor, curve or fan differs
+func (d Diff) IsEmpty() bool {
+	return len(d.AddedSensors)+len(d.RemovedSensors)+len(d.ChangedSensors)+
+		len(d.AddedCurves)+len(d.RemovedCurves)+len(d.ChangedCurves)+
+		len(d.AddedFans)+len(d.RemovedFans)+len(d.ChangedFans)+len(d.ReboundFans) <= 0
+}
+
+// ComputeDiff compares the sensors, curves and fans of the given configurations.
+// Paths resolved at runtime (f.ex. the pwm output of a hwmon fan) are ignored.
+func ComputeDiff(old Configuration, new Configuration) (diff Diff) {
+	oldSensors := map[string]SensorConfig{}
+	for _, config := range old.Sensors {
+		oldSensors[config.ID] = config
+	}
+	for _, config := range new.Sensors {
+		oldConfig, ok := oldSensors[config.ID]
+		if !ok {
+			diff.AddedSensors = append(diff.AddedSensors, config.ID)
+		} else if !reflect.DeepEqual(withoutResolvedSensorPaths(oldConfig), withoutResolvedSensorPaths(config)) {
+			diff.ChangedSensors = append(diff.ChangedSensors, config.ID)
+		}
+		delete(oldSensors, config.ID)
+	}
+	for _, config := range old.Sensors {
+		if _, ok := oldSensors[config.ID]; ok {
+			diff.RemovedSensors = append(diff.RemovedSensors, config.ID)
+		}
+	}
+
+	oldCurves := map[string]CurveConfig{}
+	for _, config := range old.Curves {
+		oldCurves[config.ID] = config
+	}
+	for _, config := range new.Curves {
+		oldConfig, ok := oldCurves[config.ID]
+		if !ok {
+			diff.AddedCurves = append(diff.AddedCurves, config.ID)
+		} else if !reflect.DeepEqual(oldConfig, config) {
+			diff.ChangedCurves = append(diff.ChangedCurves, config.ID)
+		}
+		delete(oldCurves, config.ID)
+	}
+	for _, config := range old.Curves {
+		if _, ok := oldCurves[config.ID]; ok {
+			diff.RemovedCurves = append(diff.RemovedCurves, config.ID)
+		}
+	}
+
+	oldFans := map[string]FanConfig{}
+	for _, config := range old.Fans {
+		oldFans[config.ID] = config
+	}
+	for _, config := range new.Fans {
+		oldConfig, ok := oldFans[config.ID]
+		delete(oldFans, config.ID)
+		if !ok {
+			diff.AddedFans = append(diff.AddedFans, config.ID)
+			continue
+		}
+
+		oldConfig, newConfig := withoutResolvedFanPaths(oldConfig), withoutResolvedFanPaths(config)
+		switch {
+		case !reflect.DeepEqual(oldConfig.HwMon, newConfig.HwMon) ||
+			!reflect.DeepEqual(oldConfig.File, newConfig.File) ||
+			!reflect.DeepEqual(oldConfig.Simulated, newConfig.Simulated):
+			diff.ReboundFans = append(diff.ReboundFans, config.ID)
+		case !reflect.DeepEqual(oldConfig, newConfig):
+			diff.ChangedFans = append(diff.ChangedFans, config.ID)
+		}
+	}
+	for _, config := range old.Fans {
+		if _, ok := oldFans[config.ID]; ok {
+			diff.RemovedFans = append(diff.RemovedFans, config.ID)
+		}
+	}
+
+	return diff
+}
+
+func withoutResolvedSensorPaths(config SensorConfig) SensorConfig {
+	if config.HwMon != nil {
+		hwMon := *config.HwMon
+		hwMon.TempInput = ""
+		config.HwMon = &hwMon
+	}
+	return config
+}
+
+func withoutResolvedFanPaths(config FanConfig) FanConfig {
+	if config.HwMon != nil {
+		hwMon := *config.HwMon
+		hwMon.PwmOutput = ""
+		hwMon.RpmInput = ""
+		config.HwMon = &hwMon
+	}
+	return config
+}
+
+// RetainRestartSettings resets all settings of the given new configuration, which are only
+// applied when fan2go is started, to their value in the old configuration.
+// Returns the names of the settings which have been changed.
+func RetainRestartSettings(new *Configuration, old Configuration) (changed []string) {
+	retain := func(name string, newValue interface{}, oldValue interface{}) {
+		value := reflect.ValueOf(newValue).Elem()
+		if !reflect.DeepEqual(value.Interface(), oldValue) {
+			changed = append(changed, name)
+			value.Set(reflect.ValueOf(oldValue))
+		}
+	}
+
+	retain("dbPath", &new.DbPath, old.DbPath)
+	retain("dryRun", &new.DryRun, old.DryRun)
+	retain("hwMonBackend", &new.HwMonBackend, old.HwMonBackend)
+	retain("sysfsRoot", &new.SysfsRoot, old.SysfsRoot)
+	retain("tempSensorPollingRate", &new.TempSensorPollingRate, old.TempSensorPollingRate)
+	retain("rpmPollingRate", &new.RpmPollingRate, old.RpmPollingRate)
+	retain("controllerAdjustmentTickRate", &new.ControllerAdjustmentTickRate, old.ControllerAdjustmentTickRate)
+	retain("statistics", &new.Statistics, old.Statistics)
+	retain("watchdog", &new.Watchdog, old.Watchdog)
+	retain("history", &new.History, old.History)
+
+	return changed
+}
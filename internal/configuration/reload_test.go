@@ -0,0 +1,140 @@
+package configuration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func createReloadTestConfig() Configuration {
+	return Configuration{
+		DbPath:                       "/etc/fan2go/fan2go.db",
+		ControllerAdjustmentTickRate: 200 * time.Millisecond,
+		Sensors: []SensorConfig{
+			{
+				ID: "cpu_package",
+				HwMon: &HwMonSensorConfig{
+					Platform:  "coretemp",
+					Index:     1,
+					TempInput: "/sys/class/hwmon/hwmon1/temp1_input",
+				},
+			},
+			{
+				ID:   "ssd",
+				File: &FileSensorConfig{Path: "/tmp/ssd"},
+			},
+		},
+		Curves: []CurveConfig{
+			{
+				ID: "cpu_curve",
+				Linear: &LinearCurveConfig{
+					Sensor: "cpu_package",
+					Min:    40,
+					Max:    80,
+				},
+			},
+		},
+		Fans: []FanConfig{
+			{
+				ID:    "cpu",
+				Curve: "cpu_curve",
+				HwMon: &HwMonFanConfig{
+					Platform:  "nct6798",
+					Index:     1,
+					PwmOutput: "/sys/class/hwmon/hwmon2/pwm1",
+					RpmInput:  "/sys/class/hwmon/hwmon2/fan1_input",
+				},
+			},
+			{
+				ID:    "case",
+				Curve: "cpu_curve",
+				File:  &FileFanConfig{Path: "/tmp/case"},
+			},
+		},
+	}
+}
+
+func TestComputeDiffIgnoresResolvedPaths(t *testing.T) {
+	// GIVEN
+	old := createReloadTestConfig()
+	new := createReloadTestConfig()
+	new.Sensors[0].HwMon.TempInput = ""
+	new.Fans[0].HwMon.PwmOutput = ""
+	new.Fans[0].HwMon.RpmInput = ""
+
+	// WHEN
+	diff := ComputeDiff(old, new)
+
+	// THEN
+	assert.True(t, diff.IsEmpty())
+}
+
+func TestComputeDiff(t *testing.T) {
+	// GIVEN
+	old := createReloadTestConfig()
+	new := createReloadTestConfig()
+	new.Sensors = new.Sensors[:1]
+	new.Sensors[0].HwMon.Index = 2
+	new.Curves[0].Linear.Max = 70
+	new.Curves = append(new.Curves, CurveConfig{
+		ID:  "gpu_curve",
+		Pid: &PidCurveConfig{Sensor: "cpu_package", SetPoint: 60},
+	})
+	new.Fans[0].NeverStop = true
+	new.Fans[1].File.Path = "/tmp/other"
+	new.Fans = append(new.Fans, FanConfig{
+		ID:    "gpu",
+		Curve: "gpu_curve",
+		File:  &FileFanConfig{Path: "/tmp/gpu"},
+	})
+
+	// WHEN
+	diff := ComputeDiff(old, new)
+
+	// THEN
+	assert.Equal(t, Diff{
+		RemovedSensors: []string{"ssd"},
+		ChangedSensors: []string{"cpu_package"},
+		AddedCurves:    []string{"gpu_curve"},
+		ChangedCurves:  []string{"cpu_curve"},
+		AddedFans:      []string{"gpu"},
+		ChangedFans:    []string{"cpu"},
+		ReboundFans:    []string{"case"},
+	}, diff)
+}
+
+func TestComputeDiffRemovedFan(t *testing.T) {
+	// GIVEN
+	old := createReloadTestConfig()
+	new := createReloadTestConfig()
+	new.Fans = new.Fans[1:]
+
+	// WHEN
+	diff := ComputeDiff(old, new)
+
+	// THEN
+	assert.Equal(t, Diff{
+		RemovedFans: []string{"cpu"},
+	}, diff)
+}
+
+func TestRetainRestartSettings(t *testing.T) {
+	// GIVEN
+	old := createReloadTestConfig()
+	new := createReloadTestConfig()
+	new.DbPath = "/tmp/fan2go.db"
+	new.ControllerAdjustmentTickRate = time.Second
+	new.TempRollingWindowSize = 10
+	new.Watchdog.Enabled = true
+
+	// WHEN
+	changed := RetainRestartSettings(&new, old)
+
+	// THEN
+	assert.Equal(t, []string{"dbPath", "controllerAdjustmentTickRate", "watchdog"}, changed)
+	assert.Equal(t, old.DbPath, new.DbPath)
+	assert.Equal(t, old.ControllerAdjustmentTickRate, new.ControllerAdjustmentTickRate)
+	assert.Equal(t, old.Watchdog, new.Watchdog)
+	// applied without restarting
+	assert.Equal(t, 10, new.TempRollingWindowSize)
+}
@@ -0,0 +1,6 @@
+package configuration
+
+type ResumeConfig struct {
+	// SpinTest checks whether each fan is still spinning before control continues after a resume
+	SpinTest bool `json:"spinTest"`
+}
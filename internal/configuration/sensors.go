@@ -1,17 +1,55 @@
 package configuration
 
+import "time"
+
 type SensorConfig struct {
-	ID    string             `json:"id"`
-	HwMon *HwMonSensorConfig `json:"hwMon,omitempty"`
-	File  *FileSensorConfig  `json:"file,omitempty"`
+	ID        string                 `json:"id"`
+	HwMon     *HwMonSensorConfig     `json:"hwMon,omitempty"`
+	File      *FileSensorConfig      `json:"file,omitempty"`
+	Simulated *SimulatedSensorConfig `json:"simulated,omitempty"`
 }
 
+// HwMonSensorConfig selects a device using at least one of
+// Platform, Chip, Modalias, PciAddress or DevicePath,
+// and a sensor of this device using at least one of Index, Channel or Label.
 type HwMonSensorConfig struct {
-	Platform  string `json:"platform"`
-	Index     int    `json:"index"`
-	TempInput string
+	Platform   string `json:"platform,omitempty"`
+	Chip       string `json:"chip,omitempty"`
+	Modalias   string `json:"modalias,omitempty"`
+	PciAddress string `json:"pciAddress,omitempty"`
+	DevicePath string `json:"devicePath,omitempty"`
+	Index      int    `json:"index,omitempty"`
+	Channel    int    `json:"channel,omitempty"`
+	Label      string `json:"label,omitempty"`
+	TempInput  string
+}
+
+func (c HwMonSensorConfig) hasDeviceSelector() bool {
+	return len(c.Platform) > 0 || len(c.Chip) > 0 || len(c.Modalias) > 0 || len(c.PciAddress) > 0 || len(c.DevicePath) > 0
+}
+
+func (c HwMonSensorConfig) hasChannelSelector() bool {
+	return c.Index > 0 || c.Channel > 0 || len(c.Label) > 0
 }
 
 type FileSensorConfig struct {
 	Path string `json:"path"`
 }
+
+// SimulatedSensorConfig describes a simulated heat source, f.ex. a CPU,
+// whose temperature depends on the airflow of the given simulated fans
+type SimulatedSensorConfig struct {
+	// Ambient is the temperature (in degree) of the surrounding air
+	Ambient float64 `json:"ambient"`
+	// Power is the temperature rise (in degree) above ambient, without any airflow
+	Power float64 `json:"power"`
+	// Cooling is the factor by which the temperature rise is divided
+	// for each fan running at its max speed
+	Cooling float64 `json:"cooling"`
+	// Fans are the IDs of the simulated fans cooling this heat source
+	Fans []string `json:"fans"`
+	// Inertia is the time constant of the temperature following a change of airflow
+	Inertia time.Duration `json:"inertia"`
+	// Noise is the max deviation of a single measurement from the actual temperature
+	Noise float64 `json:"noise"`
+}
@@ -0,0 +1,528 @@
+package configuration
+
+import (
+	"fmt"
+	"github.com/looplab/tarjan"
+	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/markusressel/fan2go/internal/util"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	// SeverityError marks a problem which prevents fan2go from using the configuration
+	SeverityError Severity = "error"
+	// SeverityWarning marks a problem which is most likely a mistake, but does no harm
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a single issue found while validating a configuration
+type Problem struct {
+	// Path of the affected configuration entry, f.ex. "curves[2].function.curves[1]"
+	Path     string   `json:"path"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	if len(p.Path) <= 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// ValidationError holds all errors found in a configuration
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var messages []string
+	for _, problem := range e.Problems {
+		messages = append(messages, problem.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks the given configuration and returns all problems found in it,
+// in the order of the configuration entries they belong to
+func Validate(config *Configuration) []Problem {
+	v := &validator{config: config}
+	v.validateHwMonBackend()
+	v.validateSensors()
+	v.validateCurves()
+	v.validateFans()
+	v.validateWatchdog()
+	v.validateHistory()
+	return v.problems
+}
+
+// HasErrors returns true if any of the given problems is an error
+func HasErrors(problems []Problem) bool {
+	for _, problem := range problems {
+		if problem.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// validates the given configuration, logging all warnings.
+// Returns a ValidationError holding all errors, if any.
+func validateConfig(config *Configuration) *ValidationError {
+	var errs []Problem
+	for _, problem := range Validate(config) {
+		if problem.Severity == SeverityWarning {
+			ui.Warning("%s", problem)
+		} else {
+			errs = append(errs, problem)
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Problems: errs}
+	}
+	return nil
+}
+
+type validator struct {
+	config   *Configuration
+	problems []Problem
+}
+
+func (v *validator) error(path string, format string, a ...interface{}) {
+	v.problems = append(v.problems, Problem{Path: path, Severity: SeverityError, Message: fmt.Sprintf(format, a...)})
+}
+
+func (v *validator) warning(path string, format string, a ...interface{}) {
+	v.problems = append(v.problems, Problem{Path: path, Severity: SeverityWarning, Message: fmt.Sprintf(format, a...)})
+}
+
+func (v *validator) validateHwMonBackend() {
+	switch v.config.HwMonBackend {
+	case HwMonBackendSysfs, HwMonBackendLibSensors:
+	default:
+		v.error("hwMonBackend", "unknown backend '%s', use one of: sysfs | libsensors", v.config.HwMonBackend)
+	}
+}
+
+func (v *validator) validateWatchdog() {
+	config := v.config.Watchdog
+	if !config.Enabled {
+		return
+	}
+
+	if config.Timeout <= 0 {
+		v.error("watchdog.timeout", "timeout must be positive")
+	}
+
+	switch config.Action {
+	case WatchdogActionMaxPwm, WatchdogActionAuto:
+	default:
+		v.error("watchdog.action", "unknown action '%s', use one of: max | auto", config.Action)
+	}
+}
+
+func (v *validator) validateHistory() {
+	config := v.config.History
+	if !config.Enabled {
+		return
+	}
+	if config.Interval <= 0 {
+		v.error("history.interval", "interval must be positive")
+	} else if config.Retention < config.Interval {
+		v.error("history.retention", "retention must be at least as long as the interval")
+	}
+}
+
+// reports all IDs which are empty, or already used by a previous entry
+func (v *validator) validateIds(kind string, section string, ids []string) {
+	firstIndex := map[string]int{}
+	for i, id := range ids {
+		path := fmt.Sprintf("%s[%d].id", section, i)
+		if len(id) <= 0 {
+			v.error(path, "%s id is missing", kind)
+			continue
+		}
+		if first, ok := firstIndex[id]; ok {
+			v.error(path, "duplicate %s id '%s', already used by %s[%d]", kind, id, section, first)
+			continue
+		}
+		firstIndex[id] = i
+	}
+}
+
+func (v *validator) validateSensors() {
+	var ids []string
+	for _, sensorConfig := range v.config.Sensors {
+		ids = append(ids, sensorConfig.ID)
+	}
+	v.validateIds("sensor", "sensors", ids)
+
+	for i, sensorConfig := range v.config.Sensors {
+		path := fmt.Sprintf("sensors[%d]", i)
+
+		types := countTrue(sensorConfig.HwMon != nil, sensorConfig.File != nil, sensorConfig.Simulated != nil)
+		if types > 1 {
+			v.error(path, "only one sensor type can be used per sensor definition block")
+		}
+		if types <= 0 {
+			v.error(path, "sub-configuration for sensor is missing, use one of: hwMon | file | simulated")
+		}
+
+		if sensorConfig.HwMon != nil {
+			if !sensorConfig.HwMon.hasDeviceSelector() {
+				v.error(path+".hwMon", "device is not specified, use at least one of: platform | chip | modalias | pciAddress | devicePath")
+			}
+			if !sensorConfig.HwMon.hasChannelSelector() {
+				v.error(path+".hwMon", "channel is not specified, use at least one of: index | channel | label")
+			}
+		}
+
+		if sensorConfig.File != nil && len(sensorConfig.File.Path) <= 0 {
+			v.error(path+".file.path", "path is missing")
+		}
+
+		if sensorConfig.Simulated != nil {
+			v.validateSimulatedSensor(path+".simulated", *sensorConfig.Simulated)
+		}
+
+		if !isSensorConfigInUse(sensorConfig, v.config.Curves, v.config.Fans) {
+			v.warning(path, "sensor '%s' is not used by any curve or fan", sensorConfig.ID)
+		}
+	}
+}
+
+func (v *validator) validateSimulatedSensor(path string, config SimulatedSensorConfig) {
+	if config.Cooling < 0 || config.Inertia < 0 || config.Noise < 0 {
+		v.error(path, "cooling, inertia and noise must not be negative")
+	}
+
+	for i, fanId := range config.Fans {
+		found := false
+		for _, fanConfig := range v.config.Fans {
+			if fanConfig.ID == fanId {
+				found = fanConfig.Simulated != nil
+				break
+			}
+		}
+		if !found {
+			v.error(fmt.Sprintf("%s.fans[%d]", path, i), "no simulated fan with id '%s' found", fanId)
+		}
+	}
+}
+
+func isSensorConfigInUse(config SensorConfig, curves []CurveConfig, fans []FanConfig) bool {
+	for _, curveConfig := range curves {
+		if curveConfig.Linear != nil && curveConfig.Linear.Sensor == config.ID {
+			return true
+		}
+		if curveConfig.Pid != nil && curveConfig.Pid.Sensor == config.ID {
+			return true
+		}
+	}
+
+	for _, fanConfig := range fans {
+		if fanConfig.ZeroRpm != nil && fanConfig.ZeroRpm.Sensor == config.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSensorConfigDefined(id string, sensors []SensorConfig) bool {
+	for _, sensorConfig := range sensors {
+		if sensorConfig.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateCurves() {
+	var ids []string
+	for _, curveConfig := range v.config.Curves {
+		ids = append(ids, curveConfig.ID)
+	}
+	v.validateIds("curve", "curves", ids)
+
+	graph := make(map[interface{}][]interface{})
+
+	for i, curveConfig := range v.config.Curves {
+		path := fmt.Sprintf("curves[%d]", i)
+
+		curveTypes := countTrue(curveConfig.Linear != nil, curveConfig.Function != nil, curveConfig.Pid != nil)
+		if curveTypes > 1 {
+			v.error(path, "only one curve type can be used per curve definition block")
+		}
+		if curveTypes <= 0 {
+			v.error(path, "sub-configuration for curve is missing, use one of: linear | function | pid")
+		}
+
+		if !isCurveConfigInUse(curveConfig, v.config.Curves, v.config.Fans) {
+			v.warning(path, "curve '%s' is not used by any curve or fan", curveConfig.ID)
+		}
+
+		if curveConfig.Function != nil {
+			switch curveConfig.Function.Type {
+			case FunctionAverage, FunctionDelta, FunctionMinimum, FunctionMaximum:
+			default:
+				v.error(path+".function.type", "unknown function type '%s', use one of: average | delta | minimum | maximum", curveConfig.Function.Type)
+			}
+
+			if len(curveConfig.Function.Curves) <= 0 {
+				v.error(path+".function.curves", "at least one curve is required")
+			}
+
+			var connections []interface{}
+			for j, curve := range curveConfig.Function.Curves {
+				if !isCurveConfigDefined(curve, v.config.Curves) {
+					v.error(fmt.Sprintf("%s.function.curves[%d]", path, j), "curve '%s' is not defined", curve)
+					continue
+				}
+				if curve == curveConfig.ID {
+					v.error(fmt.Sprintf("%s.function.curves[%d]", path, j), "curve must not reference itself")
+					continue
+				}
+				connections = append(connections, curve)
+			}
+			graph[curveConfig.ID] = connections
+		}
+
+		if curveConfig.Linear != nil {
+			v.validateCurveSensor(path+".linear.sensor", curveConfig.Linear.Sensor)
+
+			// sort the steps, to report them in a stable order
+			var temps []int
+			for temp := range curveConfig.Linear.Steps {
+				temps = append(temps, temp)
+			}
+			sort.Ints(temps)
+			for _, temp := range temps {
+				speed := curveConfig.Linear.Steps[temp]
+				if speed < 0 || speed > 255 {
+					v.error(fmt.Sprintf("%s.linear.steps[%d]", path, temp), "speed %v must be within [0..255]", speed)
+				}
+			}
+		}
+
+		if curveConfig.Pid != nil {
+			v.validateCurveSensor(path+".pid.sensor", curveConfig.Pid.Sensor)
+		}
+	}
+
+	v.validateNoLoops(graph)
+}
+
+func (v *validator) validateCurveSensor(path string, sensorId string) {
+	if len(sensorId) <= 0 {
+		v.error(path, "sensor is missing")
+	} else if !isSensorConfigDefined(sensorId, v.config.Sensors) {
+		v.error(path, "sensor '%s' is not defined", sensorId)
+	}
+}
+
+func (v *validator) validateNoLoops(graph map[interface{}][]interface{}) {
+	output := tarjan.Connections(graph)
+	for _, items := range output {
+		if len(items) <= 1 {
+			continue
+		}
+
+		// report the cycle at the first curve which is part of it
+		var ids []string
+		for _, item := range items {
+			ids = append(ids, item.(string))
+		}
+		path := "curves"
+		for i, curveConfig := range v.config.Curves {
+			if util.ContainsString(ids, curveConfig.ID) {
+				path = fmt.Sprintf("curves[%d].function.curves", i)
+				break
+			}
+		}
+		v.error(path, "curve dependency cycle between: %s", strings.Join(ids, ", "))
+	}
+}
+
+func isCurveConfigInUse(config CurveConfig, curves []CurveConfig, fans []FanConfig) bool {
+	for _, curveConfig := range curves {
+		if curveConfig.Function == nil {
+			// only function curves can reference curves
+			continue
+		}
+
+		if util.ContainsString(curveConfig.Function.Curves, config.ID) {
+			return true
+		}
+	}
+
+	for _, fanConfig := range fans {
+		if fanConfig.Curve == config.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isCurveConfigDefined(id string, curves []CurveConfig) bool {
+	for _, curveConfig := range curves {
+		if curveConfig.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateFans() {
+	var ids []string
+	for _, fanConfig := range v.config.Fans {
+		ids = append(ids, fanConfig.ID)
+	}
+	v.validateIds("fan", "fans", ids)
+
+	// paths of the first fan bound to each output
+	outputs := map[string]string{}
+
+	for i, fanConfig := range v.config.Fans {
+		path := fmt.Sprintf("fans[%d]", i)
+
+		types := countTrue(fanConfig.HwMon != nil, fanConfig.File != nil, fanConfig.Simulated != nil)
+		if types > 1 {
+			v.error(path, "only one fan type can be used per fan definition block")
+		}
+		if types <= 0 {
+			v.error(path, "sub-configuration for fan is missing, use one of: hwMon | file | simulated")
+		}
+
+		if fanConfig.Simulated != nil {
+			v.validateSimulatedFan(path+".simulated", *fanConfig.Simulated)
+		}
+
+		if fanConfig.HwMon != nil {
+			if !fanConfig.HwMon.hasDeviceSelector() {
+				v.error(path+".hwMon", "device is not specified, use at least one of: platform | chip | modalias | pciAddress | devicePath")
+			}
+			if !fanConfig.HwMon.hasChannelSelector() {
+				v.error(path+".hwMon", "channel is not specified, use at least one of: index | channel | label")
+			}
+		}
+
+		if fanConfig.File != nil && len(fanConfig.File.Path) <= 0 {
+			v.error(path+".file.path", "path is missing")
+		}
+
+		if output, ok := getFanOutput(fanConfig); ok {
+			if first, ok := outputs[output]; ok {
+				v.error(path, "bound to the same PWM output as %s", first)
+			} else {
+				outputs[output] = path
+			}
+		}
+
+		if len(fanConfig.Curve) <= 0 {
+			v.error(path+".curve", "curve is missing")
+		} else if !isCurveConfigDefined(fanConfig.Curve, v.config.Curves) {
+			v.error(path+".curve", "curve '%s' is not defined", fanConfig.Curve)
+		}
+
+		if fanConfig.StartPwm != nil && (*fanConfig.StartPwm < 0 || *fanConfig.StartPwm > 255) {
+			v.error(path+".startPwm", "startPwm must be within [0..255]")
+		}
+
+		switch fanConfig.ControlMode {
+		case "", ControlModePwm, ControlModeRpm:
+		case ControlModeClosedLoop:
+			if fanConfig.HwMon == nil && fanConfig.Simulated == nil {
+				v.error(path+".controlMode", "controlMode '%s' is only supported for hwMon and simulated fans", fanConfig.ControlMode)
+			}
+		default:
+			v.error(path+".controlMode", "unknown controlMode '%s', use one of: pwm | rpm | closedLoop", fanConfig.ControlMode)
+		}
+
+		if fanConfig.ThirdParty != nil {
+			switch fanConfig.ThirdParty.Policy {
+			case "", ThirdPartyPolicyOverride, ThirdPartyPolicyYield, ThirdPartyPolicyRelinquish:
+			default:
+				v.error(path+".thirdParty.policy", "unknown policy '%s', use one of: override | yield | relinquish", fanConfig.ThirdParty.Policy)
+			}
+		}
+
+		if fanConfig.SpinUp != nil && fanConfig.SpinUp.Pwm != nil {
+			pwm := *fanConfig.SpinUp.Pwm
+			if pwm < 0 || pwm > 255 {
+				v.error(path+".spinUp.pwm", "pwm must be within [0..255]")
+			}
+		}
+
+		if fanConfig.ZeroRpm != nil {
+			if fanConfig.NeverStop {
+				v.error(path+".zeroRpm", "zeroRpm cannot be used together with neverStop")
+			}
+			if fanConfig.ZeroRpm.StartAbove < fanConfig.ZeroRpm.StopBelow {
+				v.error(path+".zeroRpm.startAbove", "startAbove must not be lower than stopBelow")
+			}
+			if len(fanConfig.ZeroRpm.Sensor) > 0 && !isSensorConfigDefined(fanConfig.ZeroRpm.Sensor, v.config.Sensors) {
+				v.error(path+".zeroRpm.sensor", "sensor '%s' is not defined", fanConfig.ZeroRpm.Sensor)
+			}
+		}
+
+		if fanConfig.Smoothing < 0 || fanConfig.Smoothing >= 1 {
+			v.error(path+".smoothing", "smoothing must be within [0..1)")
+		}
+
+		if fanConfig.RampRate != nil && (fanConfig.RampRate.Increase < 0 || fanConfig.RampRate.Decrease < 0) {
+			v.error(path+".rampRate", "rampRate values must not be negative")
+		}
+	}
+}
+
+// returns a key identifying the PWM output the given fan is bound to,
+// if it can be determined from the configuration.
+// The index and channel of a hwmon fan both are the number of its PWM output, so they are compared
+// with each other. Different device selectors (f.ex. platform and chip) of the same device, or a label
+// and the number of the same output cannot be compared without resolving them, so they are not detected.
+func getFanOutput(config FanConfig) (string, bool) {
+	if config.File != nil && len(config.File.Path) > 0 {
+		return "file:" + config.File.Path, true
+	}
+	if config.HwMon != nil && config.HwMon.hasDeviceSelector() && config.HwMon.hasChannelSelector() {
+		c := config.HwMon
+		output := c.Channel
+		if output <= 0 {
+			output = c.Index
+		}
+		label := ""
+		if output <= 0 {
+			label = c.Label
+		}
+		return fmt.Sprintf("hwmon:%s|%s|%s|%s|%s|%d|%s",
+			c.Platform, c.Chip, c.Modalias, c.PciAddress, c.DevicePath, output, label), true
+	}
+	return "", false
+}
+
+func (v *validator) validateSimulatedFan(path string, config SimulatedFanConfig) {
+	if config.MaxRpm <= 0 {
+		v.error(path+".maxRpm", "maxRpm must be positive")
+	}
+	if config.StopPwm < 0 || config.StopPwm > config.StartPwm || config.StartPwm > 255 {
+		v.error(path, "PWM thresholds must satisfy 0 <= stopPwm <= startPwm <= 255")
+	}
+	if config.Inertia < 0 || config.Noise < 0 {
+		v.error(path, "inertia and noise must not be negative")
+	}
+}
+
+// returns the number of given conditions which are true
+func countTrue(conditions ...bool) (count int) {
+	for _, condition := range conditions {
+		if condition {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,162 @@
+package configuration
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func createValidationTestConfig() Configuration {
+	config := createReloadTestConfig()
+	config.HwMonBackend = HwMonBackendSysfs
+	config.Curves = append(config.Curves, CurveConfig{
+		ID: "ssd_curve",
+		Linear: &LinearCurveConfig{
+			Sensor: "ssd",
+			Steps: map[int]float64{
+				30: 0,
+				50: 255,
+			},
+		},
+	}, CurveConfig{
+		ID: "avg_curve",
+		Function: &FunctionCurveConfig{
+			Type:   FunctionAverage,
+			Curves: []string{"cpu_curve", "ssd_curve"},
+		},
+	})
+	config.Fans[1].Curve = "avg_curve"
+	return config
+}
+
+func getErrors(problems []Problem) []Problem {
+	var result []Problem
+	for _, problem := range problems {
+		if problem.Severity == SeverityError {
+			result = append(result, problem)
+		}
+	}
+	return result
+}
+
+func TestValidateValidConfig(t *testing.T) {
+	// GIVEN
+	config := createValidationTestConfig()
+
+	// WHEN
+	problems := Validate(&config)
+
+	// THEN
+	assert.Empty(t, problems)
+	assert.False(t, HasErrors(problems))
+}
+
+func TestValidateAggregatesAllProblems(t *testing.T) {
+	// GIVEN
+	config := createValidationTestConfig()
+	config.Curves[0].Linear.Sensor = "missing_sensor"
+	config.Curves[2].Function.Type = "median"
+	config.Curves[2].Function.Curves[1] = "missing_curve"
+	config.Curves[1].Linear.Steps[60] = 300
+
+	// WHEN
+	problems := Validate(&config)
+
+	// THEN
+	assert.True(t, HasErrors(problems))
+	assert.Equal(t, []Problem{
+		{Path: "curves[0].linear.sensor", Severity: SeverityError, Message: "sensor 'missing_sensor' is not defined"},
+		{Path: "curves[1].linear.steps[60]", Severity: SeverityError, Message: "speed 300 must be within [0..255]"},
+		{Path: "curves[2].function.type", Severity: SeverityError, Message: "unknown function type 'median', use one of: average | delta | minimum | maximum"},
+		{Path: "curves[2].function.curves[1]", Severity: SeverityError, Message: "curve 'missing_curve' is not defined"},
+	}, getErrors(problems))
+}
+
+func TestValidateDuplicateIds(t *testing.T) {
+	// GIVEN
+	config := createValidationTestConfig()
+	config.Sensors[1].ID = "cpu_package"
+	config.Fans[1].ID = ""
+
+	// WHEN
+	problems := getErrors(Validate(&config))
+
+	// THEN
+	assert.Contains(t, problems, Problem{Path: "sensors[1].id", Severity: SeverityError, Message: "duplicate sensor id 'cpu_package', already used by sensors[0]"})
+	assert.Contains(t, problems, Problem{Path: "fans[1].id", Severity: SeverityError, Message: "fan id is missing"})
+}
+
+func TestValidateFansBoundToSameOutput(t *testing.T) {
+	// GIVEN
+	config := createValidationTestConfig()
+	fan := config.Fans[0]
+	hwMon := *fan.HwMon
+	fan.ID = "cpu2"
+	fan.HwMon = &hwMon
+	config.Fans = append(config.Fans, fan)
+
+	// WHEN
+	problems := getErrors(Validate(&config))
+
+	// THEN
+	assert.Equal(t, []Problem{
+		{Path: "fans[2]", Severity: SeverityError, Message: "bound to the same PWM output as fans[0]"},
+	}, problems)
+}
+
+func TestValidateFansBoundToSameOutputByIndexAndChannel(t *testing.T) {
+	// GIVEN
+	config := createValidationTestConfig()
+	fan := config.Fans[0]
+	hwMon := *fan.HwMon
+	hwMon.Channel = hwMon.Index
+	hwMon.Index = 0
+	fan.ID = "cpu2"
+	fan.HwMon = &hwMon
+	config.Fans = append(config.Fans, fan)
+
+	// WHEN
+	problems := getErrors(Validate(&config))
+
+	// THEN
+	assert.Equal(t, []Problem{
+		{Path: "fans[2]", Severity: SeverityError, Message: "bound to the same PWM output as fans[0]"},
+	}, problems)
+}
+
+func TestValidateCurveCycle(t *testing.T) {
+	// GIVEN
+	config := createValidationTestConfig()
+	config.Curves = append(config.Curves, CurveConfig{
+		ID: "other_curve",
+		Function: &FunctionCurveConfig{
+			Type:   FunctionMaximum,
+			Curves: []string{"avg_curve"},
+		},
+	})
+	config.Curves[2].Function.Curves = append(config.Curves[2].Function.Curves, "other_curve")
+
+	// WHEN
+	problems := getErrors(Validate(&config))
+
+	// THEN
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "curves[2].function.curves", problems[0].Path)
+}
+
+func TestValidateUnusedSensorIsWarning(t *testing.T) {
+	// GIVEN
+	config := createValidationTestConfig()
+	config.Sensors = append(config.Sensors, SensorConfig{
+		ID:   "unused",
+		File: &FileSensorConfig{Path: "/tmp/unused"},
+	})
+
+	// WHEN
+	problems := Validate(&config)
+
+	// THEN
+	assert.False(t, HasErrors(problems))
+	assert.Equal(t, []Problem{
+		{Path: "sensors[2]", Severity: SeverityWarning, Message: "sensor 'unused' is not used by any curve or fan"},
+	}, problems)
+}
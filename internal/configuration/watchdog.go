@@ -0,0 +1,16 @@
+package configuration
+
+import "time"
+
+const (
+	// WatchdogActionMaxPwm drives the affected fans at their max speed
+	WatchdogActionMaxPwm = "max"
+	// WatchdogActionAuto hands the affected fans back to the hardware
+	WatchdogActionAuto = "auto"
+)
+
+type WatchdogConfig struct {
+	Enabled bool          `json:"enabled"`
+	Timeout time.Duration `json:"timeout"`
+	Action  string        `json:"action"`
+}
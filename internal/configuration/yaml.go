@@ -0,0 +1,193 @@
+package configuration
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Comments holds the comment lines written above configuration entries,
+// keyed by the path of the entry, f.ex. "fans[0]". Comments with an empty path
+// are written at the top of the file.
+type Comments map[string][]string
+
+// values which can be written without quotes
+var plainYamlRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_./:-]*$`)
+
+// WriteYaml writes the sensors, curves and fans of the given configuration as YAML,
+// which can be used as a configuration file.
+// Only the device selection, curve definition, curve, neverStop and startPwm options are written,
+// all other options are left at their defaults.
+func WriteYaml(writer io.Writer, config Configuration, comments Comments) error {
+	w := &yamlWriter{writer: bufio.NewWriter(writer), comments: comments}
+
+	w.comment("", 0)
+	if len(comments[""]) > 0 {
+		w.line(0, "")
+	}
+
+	w.line(0, "sensors:")
+	for i, sensorConfig := range config.Sensors {
+		if i > 0 {
+			w.line(0, "")
+		}
+		w.comment(fmt.Sprintf("sensors[%d]", i), 1)
+		w.line(1, "- id: %s", yamlString(sensorConfig.ID))
+		switch {
+		case sensorConfig.HwMon != nil:
+			c := sensorConfig.HwMon
+			w.line(2, "hwmon:")
+			w.selector(c.Platform, c.Chip, c.Modalias, c.PciAddress, c.DevicePath, c.Index, c.Channel, c.Label)
+		case sensorConfig.File != nil:
+			w.line(2, "file:")
+			w.line(3, "path: %s", yamlString(sensorConfig.File.Path))
+		}
+	}
+	w.line(0, "")
+
+	w.line(0, "curves:")
+	for i, curveConfig := range config.Curves {
+		if i > 0 {
+			w.line(0, "")
+		}
+		w.comment(fmt.Sprintf("curves[%d]", i), 1)
+		w.line(1, "- id: %s", yamlString(curveConfig.ID))
+		switch {
+		case curveConfig.Linear != nil:
+			c := curveConfig.Linear
+			w.line(2, "linear:")
+			w.line(3, "sensor: %s", yamlString(c.Sensor))
+			if len(c.Steps) > 0 {
+				var temps []int
+				for temp := range c.Steps {
+					temps = append(temps, temp)
+				}
+				sort.Ints(temps)
+				w.line(3, "steps:")
+				for _, temp := range temps {
+					w.line(4, "- %d: %s", temp, strconv.FormatFloat(c.Steps[temp], 'f', -1, 64))
+				}
+			} else {
+				w.line(3, "min: %d", c.Min)
+				w.line(3, "max: %d", c.Max)
+			}
+		case curveConfig.Pid != nil:
+			c := curveConfig.Pid
+			w.line(2, "pid:")
+			w.line(3, "sensor: %s", yamlString(c.Sensor))
+			w.line(3, "setPoint: %s", strconv.FormatFloat(c.SetPoint, 'f', -1, 64))
+			w.line(3, "p: %s", strconv.FormatFloat(c.P, 'f', -1, 64))
+			w.line(3, "i: %s", strconv.FormatFloat(c.I, 'f', -1, 64))
+			w.line(3, "d: %s", strconv.FormatFloat(c.D, 'f', -1, 64))
+		case curveConfig.Function != nil:
+			c := curveConfig.Function
+			w.line(2, "function:")
+			w.line(3, "type: %s", yamlString(c.Type))
+			w.line(3, "curves:")
+			for _, curve := range c.Curves {
+				w.line(4, "- %s", yamlString(curve))
+			}
+		}
+	}
+	w.line(0, "")
+
+	w.line(0, "fans:")
+	for i, fanConfig := range config.Fans {
+		if i > 0 {
+			w.line(0, "")
+		}
+		w.comment(fmt.Sprintf("fans[%d]", i), 1)
+		w.line(1, "- id: %s", yamlString(fanConfig.ID))
+		switch {
+		case fanConfig.HwMon != nil:
+			c := fanConfig.HwMon
+			w.line(2, "hwmon:")
+			w.selector(c.Platform, c.Chip, c.Modalias, c.PciAddress, c.DevicePath, c.Index, c.Channel, c.Label)
+		case fanConfig.File != nil:
+			w.line(2, "file:")
+			w.line(3, "path: %s", yamlString(fanConfig.File.Path))
+		}
+		w.line(2, "curve: %s", yamlString(fanConfig.Curve))
+		w.line(2, "neverStop: %t", fanConfig.NeverStop)
+		if fanConfig.StartPwm != nil {
+			w.line(2, "startPwm: %d", *fanConfig.StartPwm)
+		}
+	}
+
+	if w.err != nil {
+		return w.err
+	}
+	return w.writer.Flush()
+}
+
+type yamlWriter struct {
+	writer   *bufio.Writer
+	comments Comments
+	err      error
+}
+
+func (w *yamlWriter) line(indent int, format string, a ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	text := fmt.Sprintf(format, a...)
+	if len(text) > 0 {
+		text = strings.Repeat("  ", indent) + text
+	}
+	_, w.err = w.writer.WriteString(text + "\n")
+}
+
+func (w *yamlWriter) comment(path string, indent int) {
+	for _, comment := range w.comments[path] {
+		w.line(indent, "%s", strings.TrimSpace("# "+comment))
+	}
+}
+
+// writes the non-empty options used to select a hwmon device and channel
+func (w *yamlWriter) selector(platform, chip, modalias, pciAddress, devicePath string, index, channel int, label string) {
+	options := []struct {
+		key   string
+		value string
+	}{
+		{"platform", platform},
+		{"chip", chip},
+		{"modalias", modalias},
+		{"pciAddress", pciAddress},
+		{"devicePath", devicePath},
+	}
+	for _, option := range options {
+		if len(option.value) > 0 {
+			w.line(3, "%s: %s", option.key, yamlString(option.value))
+		}
+	}
+	if index > 0 {
+		w.line(3, "index: %d", index)
+	}
+	if channel > 0 {
+		w.line(3, "channel: %d", channel)
+	}
+	if len(label) > 0 {
+		w.line(3, "label: %s", yamlString(label))
+	}
+}
+
+// returns the given string as a YAML scalar, quoted if necessary
+func yamlString(value string) string {
+	if plainYamlRegex.MatchString(value) && !isYamlKeyword(value) {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// returns true if YAML would interpret the given plain value as something else than a string
+func isYamlKeyword(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package configuration
+
+import (
+	"bytes"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWriteYaml(t *testing.T) {
+	// GIVEN
+	config := createValidationTestConfig()
+	config.Sensors[0].HwMon.TempInput = ""
+	config.Fans[0].HwMon.PwmOutput = ""
+	config.Fans[0].HwMon.RpmInput = ""
+	config.Fans[0].HwMon.PciAddress = "0000:09:00.0"
+	config.Fans[0].HwMon.Label = "yes"
+	startPwm := 60
+	config.Fans[0].StartPwm = &startPwm
+	config.Fans[1].NeverStop = true
+	comments := Comments{
+		"":        {"header"},
+		"fans[0]": {"the cpu fan"},
+	}
+
+	// WHEN
+	var buffer bytes.Buffer
+	err := WriteYaml(&buffer, config, comments)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "# header\n")
+	assert.Contains(t, buffer.String(), "  # the cpu fan\n  - id: cpu\n")
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(&buffer))
+	var result Configuration
+	assert.NoError(t, v.Unmarshal(&result))
+	assert.Equal(t, config.Sensors, result.Sensors)
+	assert.Equal(t, config.Curves, result.Curves)
+	assert.Equal(t, config.Fans, result.Fans)
+}
@@ -2,16 +2,20 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"github.com/asecurityteam/rolling"
 	"github.com/markusressel/fan2go/internal/configuration"
 	"github.com/markusressel/fan2go/internal/curves"
 	"github.com/markusressel/fan2go/internal/fans"
 	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/systemd"
 	"github.com/markusressel/fan2go/internal/ui"
 	"github.com/markusressel/fan2go/internal/util"
 	"github.com/oklog/run"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,28 +23,136 @@ const (
 	InitialLastSetPwm = -10
 )
 
+var (
+	// DefaultThirdPartyBackoff is the time a controller yields control for, after a third party changed the PWM of its fan
+	DefaultThirdPartyBackoff = 1 * time.Minute
+
+	// DefaultSpinUpDuration is the time a fan is driven at its spin up PWM when starting from a stand still
+	DefaultSpinUpDuration = 2 * time.Second
+
+	// DefaultClosedLoopConfig is used for fans in closed loop control mode without explicit gains
+	DefaultClosedLoopConfig = configuration.ClosedLoopConfig{
+		P: 0.01,
+		I: 0.005,
+		D: 0,
+	}
+)
+
+const (
+	// DefaultInitializationStepSize is the PWM step size of the coarse sweep during fan initialization
+	DefaultInitializationStepSize = 16
+	// DefaultInitializationSettleTimeout is the max time to wait for a fan to settle during initialization
+	DefaultInitializationSettleTimeout = 10 * time.Second
+	// InitializationRefinementTolerance is the max deviation (as a share of the max RPM) of a
+	// measured RPM value from the linear interpolation of its neighbours, before refining the curve
+	InitializationRefinementTolerance = 0.02
+
+	// number of consecutive RPM measurements that have to be within
+	// the settle threshold to consider a fan speed "settled"
+	settledRpmWindowSize = 3
+)
+
 var InitializationSequenceMutex sync.Mutex
 
 type FanController interface {
 	Run(ctx context.Context) error
 	UpdateFanSpeed() error
+
+	// GetFanId returns the id of the fan controlled by this controller
+	GetFanId() string
+	// GetStatistics returns a snapshot of the statistics of this controller
+	GetStatistics() FanControllerStatistics
+
+	// GetLastHeartbeat returns the time the control loop was last alive,
+	// or the zero time if it has not been started yet
+	GetLastHeartbeat() time.Time
+	// EnterFailsafe stops controlling the fan and applies the given watchdog action,
+	// until ExitFailsafe has been called for every given reason
+	EnterFailsafe(reason string, action string)
+	ExitFailsafe(reason string)
+	// Pause stops controlling and measuring the fan without changing its speed,
+	// until Unpause has been called for every given reason.
+	// Waits for a running update to finish, so the fan may be rebound afterwards.
+	Pause(reason string)
+	Unpause(reason string)
+
+	// HandleResume takes back control of the fan during the next update,
+	// after the system has been resumed from a suspend
+	HandleResume()
+
+	// UpdateConfig applies the given configuration to the fan, without running the
+	// initialization sequence again. The fan has to stay bound to the same device.
+	// Once this returns, all following updates use the new configuration.
+	UpdateConfig(config configuration.FanConfig)
+}
+
+type FanControllerStatistics struct {
+	// ThirdPartyChanges is the number of times a third party changed the PWM value of the fan
+	ThirdPartyChanges int
+	// TargetPwm is the PWM value calculated for the fan during the last update
+	TargetPwm int
 }
 
 type fanController struct {
-	persistence        persistence.Persistence
-	fan                fans.Fan
-	curve              curves.SpeedCurve
-	updateRate         time.Duration
+	persistence persistence.Persistence
+	fan         fans.Fan
+	curve       curves.SpeedCurve
+	updateRate  time.Duration
+	// the clock used by the control logic, defaults to time.Now
+	clock util.Clock
+	// indicates whether the fan is only observed, without ever writing to it
+	dryRun             bool
 	originalPwmEnabled int
 	lastSetPwm         *int
+	// the (unrounded) output value of the last update, after smoothing and ramp rate limits
+	lastOutput *float64
+	lastUpdate time.Time
+	// PID loop used to correct the PWM value in closed loop control mode
+	rpmLoop *util.PidLoop
+	// control is handed back to the fan until this time, after a third party PWM change
+	yieldUntil time.Time
+	// indicates whether control of the fan was handed back permanently
+	relinquished bool
+	// the fan is driven at its spin up PWM until this time, after starting from a stand still
+	spinUpUntil time.Time
+	// indicates whether the fan was stopped by its zero RPM config, and when this last changed
+	zeroRpmStopped    bool
+	zeroRpmLastSwitch time.Time
+
+	statistics   FanControllerStatistics
+	statisticsMu sync.Mutex
+
+	lastHeartbeat time.Time
+	// reasons the failsafe (or a pause) is active for, the fan is not controlled while not empty
+	failsafeReasons map[string]bool
+	// reasons a pause is active for, the fan is not measured either while not empty
+	pauseReasons map[string]bool
+	// indicates whether control of the fan has to be taken back after the failsafe ended
+	failsafeEnded bool
+	failsafeMu    sync.Mutex
+
+	// indicates whether the system has been resumed since the last update (accessed atomically)
+	resumePending int32
+
+	// held during an update, so the configuration is never changed in between
+	updateMu sync.Mutex
 }
 
 func NewFanController(persistence persistence.Persistence, fan fans.Fan, updateRate time.Duration) FanController {
+	return NewFanControllerWithClock(persistence, fan, updateRate, time.Now)
+}
+
+// NewFanControllerWithClock creates a fan controller, whose time based
+// control logic (f.ex. ramp rate limits) uses the given clock
+func NewFanControllerWithClock(persistence persistence.Persistence, fan fans.Fan, updateRate time.Duration, clock util.Clock) FanController {
+	curve, _ := curves.GetSpeedCurve(fan.GetCurveId())
 	return &fanController{
 		persistence: persistence,
 		fan:         fan,
-		curve:       curves.SpeedCurveMap[fan.GetCurveId()],
+		curve:       curve,
 		updateRate:  updateRate,
+		dryRun:      configuration.GetConfig().DryRun,
+		clock:       clock,
 	}
 }
 
@@ -56,31 +168,48 @@ func (f *fanController) Run(ctx context.Context) error {
 
 	ui.Info("Gathering sensor data for %s...", fan.GetId())
 	// wait a bit to gather monitoring data
-	time.Sleep(2*time.Second + configuration.CurrentConfig.TempSensorPollingRate*2)
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(2*time.Second + configuration.GetConfig().TempSensorPollingRate*2):
+	}
 
 	// check if we have data for this fan in persistence,
 	// if not we need to run the initialization sequence
 	ui.Info("Loading fan curve data for fan '%s'...", fan.GetId())
 	fanPwmData, err := f.persistence.LoadFanPwmData(fan)
-	if err != nil {
-		_, ok := fan.(*fans.HwMonFan)
-		if ok {
+	if err != nil && f.dryRun {
+		ui.Warning("No fan curve data found for fan '%s', skipping initialization sequence in dry-run mode", fan.GetId())
+		fanPwmData = map[int]float64{
+			fans.MinPwmValue: fans.MinPwmValue,
+			fans.MaxPwmValue: fans.MaxPwmValue,
+		}
+	} else if err != nil {
+		switch fan.(type) {
+		case *fans.HwMonFan, *fans.SimulatedFan:
 			ui.Warning("No fan curve data found for fan '%s', starting initialization sequence...", fan.GetId())
-			err = f.runInitializationSequence()
+			err = f.runInitializationSequence(ctx)
+			if ctx.Err() != nil {
+				// stopped during the initialization sequence
+				if !f.dryRun {
+					f.restoreFanSettings()
+				}
+				return nil
+			}
 			if err != nil {
 				return err
 			}
-		} else {
+		default:
 			err = f.persistence.SaveFanPwmData(fan)
 			if err != nil {
 				return err
 			}
 		}
-	}
 
-	fanPwmData, err = f.persistence.LoadFanPwmData(fan)
-	if err != nil {
-		return err
+		fanPwmData, err = f.persistence.LoadFanPwmData(fan)
+		if err != nil {
+			return err
+		}
 	}
 
 	err = fan.AttachFanCurveData(&fanPwmData)
@@ -88,17 +217,33 @@ func (f *fanController) Run(ctx context.Context) error {
 		return err
 	}
 
-	ui.Info("Start PWM of %s: %d", fan.GetId(), fan.GetMinPwm())
+	thresholds, err := f.persistence.LoadFanPwmThresholds(fan)
+	if err == nil {
+		ApplyPwmThresholds(fan, thresholds)
+	} else {
+		ui.Info("No measured min PWM found for fan '%s', using its start PWM instead", fan.GetId())
+	}
+
+	if fan.ShouldNeverStop() && !fan.Supports(fans.FeatureRpmSensor) {
+		ui.Warning("Cannot guarantee neverStop option on fan %s, since it has no RPM input.", fan.GetId())
+	}
+
+	ui.Info("Start PWM of %s: %d", fan.GetId(), fan.GetStartPwm())
+	ui.Info("Min PWM of %s: %d", fan.GetId(), fan.GetMinPwm())
 	ui.Info("Max PWM of %s: %d", fan.GetId(), fan.GetMaxPwm())
 
-	trySetManualPwm(fan)
+	if !f.dryRun {
+		trySetManualPwm(fan)
+	}
 
 	ui.Info("Starting controller loop for fan '%s'", fan.GetId())
 
+	ctx, cancel := context.WithCancel(ctx)
+
 	var g run.Group
 	{
 		// === rpm monitoring
-		pollingRate := configuration.CurrentConfig.RpmPollingRate
+		pollingRate := configuration.GetConfig().RpmPollingRate
 
 		g.Add(func() error {
 			tick := time.Tick(pollingRate)
@@ -107,10 +252,11 @@ func (f *fanController) Run(ctx context.Context) error {
 				case <-ctx.Done():
 					return nil
 				case <-tick:
-					measureRpm(fan)
+					f.pollRpm()
 				}
 			}
 		}, func(err error) {
+			cancel()
 			if err != nil {
 				ui.Warning("Error monitoring fan rpm: %v", err)
 			}
@@ -118,7 +264,12 @@ func (f *fanController) Run(ctx context.Context) error {
 	}
 	{
 		g.Add(func() error {
-			time.Sleep(1 * time.Second)
+			f.heartbeat()
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(1 * time.Second):
+			}
 			tick := time.Tick(f.updateRate)
 			for {
 				select {
@@ -128,40 +279,322 @@ func (f *fanController) Run(ctx context.Context) error {
 					err = f.UpdateFanSpeed()
 					if err != nil {
 						ui.Error("Error in FanController for fan %s: %v", fan.GetId(), err)
-						ui.Info("Trying to restore fan settings for %s...", f.fan.GetId())
-
-						// try to reset the pwm_enable value
-						if f.originalPwmEnabled != 1 {
-							err1 := fan.SetPwmEnabled(f.originalPwmEnabled)
-							if err1 == nil {
-								return nil
-							}
-						}
-						// if this fails, try to set it to max speed instead
-						err1 := f.setPwm(fans.MaxPwmValue)
-						if err1 != nil {
-							ui.Warning("Unable to restore fan %s, make sure it is running!", fan.GetId())
-						}
-
 						return nil
 					}
+					f.heartbeat()
 				}
 			}
 		}, func(err error) {
+			cancel()
 			if err != nil {
-				ui.Fatal("Error monitoring fan rpm: %v", err)
+				ui.Error("Error in FanController for fan %s: %v", fan.GetId(), err)
 			}
 		})
 	}
 
 	err = g.Run()
+
+	// hand the fan back, regardless of why the controller loop stopped
+	if !f.dryRun {
+		f.restoreFanSettings()
+	}
+
 	return err
 }
 
+// restores the pwm_enable value the fan had before fan2go took over,
+// or drives it at max speed if this is not possible
+func (f *fanController) restoreFanSettings() {
+	fan := f.fan
+	ui.Info("Trying to restore fan settings for %s...", fan.GetId())
+
+	// try to reset the pwm_enable value
+	if f.originalPwmEnabled != 1 {
+		err := fan.SetPwmEnabled(f.originalPwmEnabled)
+		if err == nil {
+			return
+		}
+		ui.Warning("Unable to restore pwm_enable value of %s: %v", fan.GetId(), err)
+	}
+
+	// if this fails, try to set it to max speed instead
+	err := fan.SetPwm(fans.MaxPwmValue)
+	if err != nil {
+		ui.Warning("Unable to restore fan %s, make sure it is running!", fan.GetId())
+	}
+}
+
+func (f *fanController) GetFanId() string {
+	return f.fan.GetId()
+}
+
+func (f *fanController) GetStatistics() FanControllerStatistics {
+	f.statisticsMu.Lock()
+	defer f.statisticsMu.Unlock()
+	return f.statistics
+}
+
+// returns the current time of the clock of this controller
+func (f *fanController) now() time.Time {
+	if f.clock == nil {
+		return time.Now()
+	}
+	return f.clock()
+}
+
+func (f *fanController) GetLastHeartbeat() time.Time {
+	f.failsafeMu.Lock()
+	defer f.failsafeMu.Unlock()
+	return f.lastHeartbeat
+}
+
+func (f *fanController) heartbeat() {
+	f.failsafeMu.Lock()
+	defer f.failsafeMu.Unlock()
+	f.lastHeartbeat = time.Now()
+}
+
+func (f *fanController) addFailsafeReason(reason string) {
+	f.failsafeMu.Lock()
+	defer f.failsafeMu.Unlock()
+	if f.failsafeReasons == nil {
+		f.failsafeReasons = map[string]bool{}
+	}
+	f.failsafeReasons[reason] = true
+}
+
+func (f *fanController) Pause(reason string) {
+	f.updateMu.Lock()
+	defer f.updateMu.Unlock()
+
+	f.addFailsafeReason(reason)
+	f.failsafeMu.Lock()
+	if f.pauseReasons == nil {
+		f.pauseReasons = map[string]bool{}
+	}
+	f.pauseReasons[reason] = true
+	f.failsafeMu.Unlock()
+	ui.Warning("Control of fan %s paused (%s)", f.fan.GetId(), reason)
+}
+
+func (f *fanController) Unpause(reason string) {
+	f.failsafeMu.Lock()
+	delete(f.pauseReasons, reason)
+	f.failsafeMu.Unlock()
+	f.ExitFailsafe(reason)
+}
+
+func (f *fanController) isPaused() bool {
+	f.failsafeMu.Lock()
+	defer f.failsafeMu.Unlock()
+	return len(f.pauseReasons) > 0
+}
+
+// measures the RPM of the fan, unless it is paused (f.ex. while its device is missing)
+func (f *fanController) pollRpm() {
+	f.updateMu.Lock()
+	defer f.updateMu.Unlock()
+	if f.isPaused() {
+		return
+	}
+	measureRpm(f.fan)
+}
+
+func (f *fanController) EnterFailsafe(reason string, action string) {
+	fan := f.fan
+
+	f.addFailsafeReason(reason)
+	ui.Warning("Failsafe of fan %s activated (%s)", fan.GetId(), reason)
+	if f.dryRun {
+		return
+	}
+
+	if action == configuration.WatchdogActionAuto {
+		pwmEnabled := f.originalPwmEnabled
+		if pwmEnabled == 1 {
+			// manual control is exactly what we are trying to get rid of
+			pwmEnabled = 2
+		}
+		err := fan.SetPwmEnabled(pwmEnabled)
+		if err == nil {
+			return
+		}
+		ui.Warning("Unable to hand fan %s back to hardware: %v", fan.GetId(), err)
+	}
+
+	err := fan.SetPwm(fans.MaxPwmValue)
+	if err != nil {
+		ui.Error("Unable to set fan %s to max speed, make sure it is running!", fan.GetId())
+	}
+}
+
+func (f *fanController) ExitFailsafe(reason string) {
+	f.failsafeMu.Lock()
+	defer f.failsafeMu.Unlock()
+	if !f.failsafeReasons[reason] {
+		return
+	}
+	delete(f.failsafeReasons, reason)
+	if len(f.failsafeReasons) <= 0 {
+		ui.Info("Failsafe of fan %s deactivated", f.fan.GetId())
+		f.failsafeEnded = true
+	}
+}
+
+// returns whether the failsafe is active, and whether it ended since the last call
+func (f *fanController) checkFailsafe() (active bool, ended bool) {
+	f.failsafeMu.Lock()
+	defer f.failsafeMu.Unlock()
+	ended = f.failsafeEnded
+	f.failsafeEnded = false
+	return len(f.failsafeReasons) > 0, ended
+}
+
+func (f *fanController) HandleResume() {
+	atomic.StoreInt32(&f.resumePending, 1)
+}
+
+// the hardware may have reset pwm_enable during a suspend,
+// in this case its value is the new mode to restore on exit
+func (f *fanController) readPwmEnabledAfterResume() {
+	fan := f.fan
+	pwmEnabled, err := fan.GetPwmEnabled()
+	if err != nil {
+		ui.Warning("Cannot read pwm_enable value of %s", fan.GetId())
+	} else if pwmEnabled != 1 {
+		f.originalPwmEnabled = pwmEnabled
+	}
+}
+
+// re-applies manual control of the fan, which may have been reset by the hardware during a suspend
+func (f *fanController) reapplyAfterResume() {
+	fan := f.fan
+	ui.Info("Re-applying control of fan %s after resume", fan.GetId())
+
+	f.lastSetPwm = nil
+	f.lastOutput = nil
+	trySetManualPwm(fan)
+
+	if configuration.GetConfig().Resume.SpinTest {
+		f.runSpinTest()
+	}
+}
+
+// briefly drives the fan at max speed and checks whether it is spinning
+func (f *fanController) runSpinTest() {
+	fan := f.fan
+	if !fan.Supports(fans.FeatureRpmSensor) {
+		return
+	}
+
+	err := fan.SetPwm(fans.MaxPwmValue)
+	if err != nil {
+		ui.Warning("Unable to run spin test of fan %s: %v", fan.GetId(), err)
+		return
+	}
+	time.Sleep(DefaultSpinUpDuration)
+
+	rpm := fan.GetRpm()
+	if rpm <= 0 {
+		ui.Error("Fan %s is not spinning after resume, make sure it is working!", fan.GetId())
+	} else {
+		ui.Info("Fan %s is spinning after resume (%d RPM)", fan.GetId(), rpm)
+	}
+}
+
+func (f *fanController) UpdateConfig(config configuration.FanConfig) {
+	f.updateMu.Lock()
+	defer f.updateMu.Unlock()
+
+	ui.Info("Applying new configuration of fan %s", f.fan.GetId())
+	oldStartPwm := f.fan.GetConfig().StartPwm
+	f.fan.SetConfig(config)
+	if !equalIntPtr(oldStartPwm, config.StartPwm) {
+		f.applyStartPwm(config)
+	}
+	f.curve, _ = curves.GetSpeedCurve(config.Curve)
+	// the gains may have changed
+	f.rpmLoop = nil
+	if config.ZeroRpm == nil {
+		f.zeroRpmStopped = false
+	}
+}
+
+// applies the start PWM of the given configuration to the fan, or derives it from
+// the fan curve data and the measured PWM thresholds like on startup, when it is not set
+func (f *fanController) applyStartPwm(config configuration.FanConfig) {
+	fan := f.fan
+	if config.StartPwm != nil {
+		fan.SetStartPwm(*config.StartPwm)
+	} else {
+		if fan.GetFanCurveData() != nil {
+			fan.SetStartPwm(fans.MaxPwmValue)
+			startPwm, _ := fans.ComputePwmBoundaries(fan)
+			fan.SetStartPwm(startPwm)
+		}
+		thresholds, err := f.persistence.LoadFanPwmThresholds(fan)
+		if err == nil {
+			ApplyPwmThresholds(fan, thresholds)
+		}
+	}
+	if fan.GetMinPwm() > fan.GetStartPwm() {
+		fan.SetMinPwm(fan.GetStartPwm())
+	}
+	ui.Info("Start PWM of %s: %d", fan.GetId(), fan.GetStartPwm())
+}
+
+func equalIntPtr(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 func (f *fanController) UpdateFanSpeed() error {
+	f.updateMu.Lock()
+	defer f.updateMu.Unlock()
+
 	fan := f.fan
-	target := util.Round(f.calculateTargetPwm())
+
+	if f.relinquished {
+		return nil
+	}
+	failsafe, failsafeEnded := f.checkFailsafe()
+	if failsafe {
+		return nil
+	}
+	if failsafeEnded && !f.dryRun {
+		ui.Info("Taking back control of fan %s", fan.GetId())
+		f.lastSetPwm = nil
+		f.lastOutput = nil
+		trySetManualPwm(fan)
+	}
+	if atomic.CompareAndSwapInt32(&f.resumePending, 1, 0) && !f.dryRun {
+		f.readPwmEnabledAfterResume()
+		// while yielding, control is taken back once the backoff has passed anyway
+		if f.yieldUntil.IsZero() {
+			f.reapplyAfterResume()
+		}
+	}
+	if !f.yieldUntil.IsZero() {
+		if f.now().Before(f.yieldUntil) {
+			return nil
+		}
+		ui.Info("Taking back control of fan %s", fan.GetId())
+		f.yieldUntil = time.Time{}
+		f.lastSetPwm = nil
+		f.lastOutput = nil
+		trySetManualPwm(fan)
+	}
+
+	// in dry-run mode the PWM value is never set, so it cannot be compared
+	if !f.dryRun && f.handleThirdPartyChange() {
+		return nil
+	}
+
+	target := f.calculateTargetPwm()
 	if target >= 0 {
+		target = util.Round(f.limitPwmChange(target))
 		err := f.setPwm(target)
 		if err != nil {
 			ui.Error("Error setting %s: %v", fan.GetId(), err)
@@ -172,9 +605,109 @@ func (f *fanController) UpdateFanSpeed() error {
 	return nil
 }
 
+// checks whether the PWM value of the fan was changed by a third party since the last update,
+// and applies the configured policy if so. Returns true if the fan should not be controlled
+// during this update.
+func (f *fanController) handleThirdPartyChange() bool {
+	fan := f.fan
+	if f.lastSetPwm == nil {
+		return false
+	}
+
+	lastSetPwm := *(f.lastSetPwm)
+	currentPwm := fan.GetPwm()
+	if lastSetPwm == currentPwm {
+		return false
+	}
+
+	f.statisticsMu.Lock()
+	f.statistics.ThirdPartyChanges++
+	f.statisticsMu.Unlock()
+
+	ui.Warning("PWM of %s was changed by third party! Last set PWM value was: %d but is now: %d",
+		fan.GetId(), lastSetPwm, currentPwm)
+
+	config := configuration.ThirdPartyConfig{
+		Policy:  configuration.ThirdPartyPolicyOverride,
+		Backoff: DefaultThirdPartyBackoff,
+	}
+	if fan.GetConfig().ThirdParty != nil {
+		if len(fan.GetConfig().ThirdParty.Policy) > 0 {
+			config.Policy = fan.GetConfig().ThirdParty.Policy
+		}
+		if fan.GetConfig().ThirdParty.Backoff > 0 {
+			config.Backoff = fan.GetConfig().ThirdParty.Backoff
+		}
+	}
+
+	switch config.Policy {
+	case configuration.ThirdPartyPolicyYield:
+		ui.Warning("Yielding control of fan %s for %v", fan.GetId(), config.Backoff)
+		f.yieldUntil = f.now().Add(config.Backoff)
+		return true
+	case configuration.ThirdPartyPolicyRelinquish:
+		ui.Warning("Relinquishing control of fan %s", fan.GetId())
+		f.relinquished = true
+		err := fan.SetPwmEnabled(f.originalPwmEnabled)
+		if err != nil {
+			ui.Warning("Unable to restore pwm_enable value of %s: %v", fan.GetId(), err)
+		}
+		return true
+	}
+
+	return false
+}
+
+// smoothes the given target PWM value and limits its rate of change,
+// so the fan approaches the target gradually instead of jumping to it
+func (f *fanController) limitPwmChange(target int) int {
+	now := f.now()
+	var last float64
+	var dt float64
+	if f.lastOutput == nil {
+		last = float64(f.fan.GetPwm())
+		dt = f.updateRate.Seconds()
+	} else {
+		last = *f.lastOutput
+		dt = now.Sub(f.lastUpdate).Seconds()
+	}
+
+	output := float64(target)
+	if !f.isSpinningUp() {
+		output = applyPwmChangeLimits(f.fan.GetConfig(), last, float64(target), dt)
+	}
+	f.lastOutput = &output
+	f.lastUpdate = now
+
+	return int(math.Round(output))
+}
+
+// applies the smoothing and ramp rate settings of the given fan config
+// when moving from the last output value towards target within dt seconds
+func applyPwmChangeLimits(config configuration.FanConfig, last float64, target float64, dt float64) float64 {
+	output := target
+	if config.Smoothing > 0 {
+		output = config.Smoothing*last + (1-config.Smoothing)*target
+	}
+
+	if config.RampRate != nil {
+		if config.RampRate.Increase > 0 {
+			output = math.Min(output, last+config.RampRate.Increase*dt)
+		}
+		if config.RampRate.Decrease > 0 {
+			output = math.Max(output, last-config.RampRate.Decrease*dt)
+		}
+	}
+
+	return output
+}
+
 // runs an initialization sequence for the given fan
-// to determine an estimation of its fan curve
-func (f *fanController) runInitializationSequence() (err error) {
+// to determine an estimation of its fan curve.
+// The PWM range is swept using a coarse step size first, which is then
+// refined only where the measured curve deviates from a linear interpolation.
+// The sequence is aborted, returning the error of the context, when the given context is done.
+func (f *fanController) runInitializationSequence(ctx context.Context) (err error) {
 	fan := f.fan
 
 	if fan.GetFanCurveData() == nil {
@@ -187,74 +720,284 @@ func (f *fanController) runInitializationSequence() (err error) {
 		}
 	}
 
-	if configuration.CurrentConfig.RunFanInitializationInParallel == false {
+	if configuration.GetConfig().RunFanInitializationInParallel == false {
 		InitializationSequenceMutex.Lock()
 		defer InitializationSequenceMutex.Unlock()
 	}
 
+	_, _ = systemd.NotifyStatus("Running initialization sequence for fan %s...", fan.GetId())
+
 	trySetManualPwm(fan)
 
-	for pwm := 0; pwm <= fans.MaxPwmValue; pwm++ {
-		// set a pwm
-		err = fan.SetPwm(pwm)
+	config := getInitializationConfig(fan)
+	measurements := map[int]float64{}
+
+	// coarse sweep
+	var coarsePwmValues []int
+	for pwm := fans.MinPwmValue; pwm < fans.MaxPwmValue; pwm += config.StepSize {
+		coarsePwmValues = append(coarsePwmValues, pwm)
+	}
+	coarsePwmValues = append(coarsePwmValues, fans.MaxPwmValue)
+
+	for _, pwm := range coarsePwmValues {
+		_, err = f.measureSettledRpm(ctx, measurements, pwm, config)
 		if err != nil {
 			ui.Error("Unable to run initialization sequence on %s: %v", fan.GetId(), err)
 			return err
 		}
+	}
 
-		if pwm == 0 {
-			// TODO: this "waiting" logic could also be applied to the other measurements
-			diffThreshold := configuration.CurrentConfig.MaxRpmDiffForSettledFan
-
-			measuredRpmDiffWindow := util.CreateRollingWindow(10)
-			fillWindow(measuredRpmDiffWindow, 10, 2*diffThreshold)
-			measuredRpmDiffMax := 2 * diffThreshold
-			oldRpm := 0
-			for !(measuredRpmDiffMax < diffThreshold) {
-				ui.Debug("Waiting for fan %s to settle (current RPM max diff: %f)...", fan.GetId(), measuredRpmDiffMax)
-				currentRpm := fan.GetPwm()
-				measuredRpmDiffWindow.Append(math.Abs(float64(currentRpm - oldRpm)))
-				oldRpm = currentRpm
-				measuredRpmDiffMax = math.Ceil(getWindowMax(measuredRpmDiffWindow))
-				time.Sleep(1 * time.Second)
-			}
-			ui.Debug("Fan %s has settled (current RPM max diff: %f)", fan.GetId(), measuredRpmDiffMax)
-		} else {
-			// wait a bit to allow the fan speed to settle.
-			// since most sensors are update only each second,
-			// we wait double that to make sure we get
-			// the most recent measurement
-			time.Sleep(2 * time.Second)
+	// refinement
+	maxRpm := 0.0
+	for _, rpm := range measurements {
+		maxRpm = math.Max(maxRpm, rpm)
+	}
+	tolerance := math.Max(config.MaxRpmDiffForSettledFan, maxRpm*InitializationRefinementTolerance)
+	for i := 0; i < len(coarsePwmValues)-1; i++ {
+		err = f.refineMeasurements(ctx, measurements, coarsePwmValues[i], coarsePwmValues[i+1], tolerance, config)
+		if err != nil {
+			ui.Error("Unable to run initialization sequence on %s: %v", fan.GetId(), err)
+			return err
 		}
+	}
+	ui.Debug("Measured %d data points for fan %s", len(measurements), fan.GetId())
 
-		// TODO:
-		// on some fans it is not possible to use the full pwm of 0..255
-		// so we try what values work and save them for later
+	// downward sweep
+	startPwm := fans.MaxPwmValue + 1
+	for pwm, rpm := range measurements {
+		if rpm > 0 && pwm < startPwm {
+			startPwm = pwm
+		}
+	}
+	minPwm := startPwm
+	if startPwm <= fans.MaxPwmValue {
+		minPwm, err = f.measureMinPwm(ctx, startPwm, config)
+		if err != nil {
+			ui.Error("Unable to run initialization sequence on %s: %v", fan.GetId(), err)
+			return err
+		}
+		ui.Debug("Measured start PWM of %d and min PWM of %d for fan %s", startPwm, minPwm, fan.GetId())
+	}
 
-		ui.Debug("Measuring RPM of %s at PWM: %d", fan.GetId(), pwm)
-		// update rpm curve
-		measureRpm(fan)
-		ui.Debug("Measured RPM of %d at PWM %d for fan %s", int(fan.GetRpmAvg()), pwm, fan.GetId())
+	err = fan.AttachFanCurveData(&measurements)
+	if err != nil {
+		return err
 	}
 
 	// save to database to restore it on restarts
 	err = f.persistence.SaveFanPwmData(fan)
 	if err != nil {
 		ui.Error("Failed to save fan PWM data for %s: %v", fan.GetId(), err)
+		return err
+	}
+
+	if startPwm <= fans.MaxPwmValue {
+		err = f.persistence.SaveFanPwmThresholds(fan, persistence.FanPwmThresholds{
+			StartPwm: startPwm,
+			MinPwm:   minPwm,
+		})
+		if err != nil {
+			ui.Error("Failed to save fan PWM thresholds for %s: %v", fan.GetId(), err)
+		}
 	}
 	return err
 }
 
-// read the current value of a fan RPM sensor and append it to the moving window
+// ApplyPwmThresholds applies measured PWM thresholds to the given fan.
+// The measured start PWM replaces the one derived from the fan curve data,
+// unless the start PWM is set in the fan configuration.
+func ApplyPwmThresholds(fan fans.Fan, thresholds persistence.FanPwmThresholds) {
+	if fan.GetConfig().StartPwm == nil && thresholds.StartPwm >= fans.MinPwmValue && thresholds.StartPwm <= fans.MaxPwmValue {
+		fan.SetStartPwm(thresholds.StartPwm)
+	}
+	if thresholds.MinPwm <= fan.GetStartPwm() {
+		fan.SetMinPwm(thresholds.MinPwm)
+	}
+}
+
+// measures the lowest PWM value at which the fan keeps spinning, when it was spinning
+// previously, using a binary search between 0 and the given start PWM
+func (f *fanController) measureMinPwm(ctx context.Context, startPwm int, config configuration.InitializationConfig) (int, error) {
+	fan := f.fan
+
+	// the fan is known to be spinning at upper, and known to stop at lower
+	lower := fans.MinPwmValue - 1
+	upper := startPwm
+	spinning := false
+	for upper-lower > 1 {
+		if !spinning {
+			err := fan.SetPwm(startPwm)
+			if err != nil {
+				return upper, err
+			}
+			_, err = f.waitForSettledRpm(ctx, config)
+			if err != nil {
+				return upper, err
+			}
+		}
+
+		mid := (lower + upper) / 2
+		err := fan.SetPwm(mid)
+		if err != nil {
+			return upper, err
+		}
+		rpm, err := f.waitForSettledRpm(ctx, config)
+		if err != nil {
+			return upper, err
+		}
+		ui.Debug("Measured RPM of %d at PWM %d (spinning down) for fan %s", int(rpm), mid, fan.GetId())
+
+		spinning = rpm > 0
+		if spinning {
+			upper = mid
+		} else {
+			lower = mid
+		}
+	}
+
+	return upper, nil
+}
+
+// returns the initialization config of the given fan, using default values for unset fields
+func getInitializationConfig(fan fans.Fan) configuration.InitializationConfig {
+	config := configuration.InitializationConfig{
+		StepSize:                DefaultInitializationStepSize,
+		SettleTimeout:           DefaultInitializationSettleTimeout,
+		MaxRpmDiffForSettledFan: configuration.GetConfig().MaxRpmDiffForSettledFan,
+	}
+
+	fanConfig := fan.GetConfig().Initialization
+	if fanConfig != nil {
+		if fanConfig.StepSize > 0 {
+			config.StepSize = fanConfig.StepSize
+		}
+		if fanConfig.SettleTimeout > 0 {
+			config.SettleTimeout = fanConfig.SettleTimeout
+		}
+		if fanConfig.MaxRpmDiffForSettledFan > 0 {
+			config.MaxRpmDiffForSettledFan = fanConfig.MaxRpmDiffForSettledFan
+		}
+	}
+
+	return config
+}
+
+// recursively measures the PWM values between lower and upper, as long as the measured
+// RPM deviates from the linear interpolation between both by more than the given tolerance.
+// The transition between a stopped and a spinning fan is always refined down to a single PWM step.
+func (f *fanController) refineMeasurements(ctx context.Context, measurements map[int]float64, lower int, upper int, tolerance float64, config configuration.InitializationConfig) error {
+	if upper-lower <= 1 {
+		return nil
+	}
+
+	lowerRpm := measurements[lower]
+	upperRpm := measurements[upper]
+	if lowerRpm <= 0 && upperRpm <= 0 {
+		// fan is stopped within the whole range
+		return nil
+	}
+
+	mid := (lower + upper) / 2
+	if lowerRpm <= 0 {
+		// make sure we measure the speed at which the fan starts from a stand still
+		err := f.fan.SetPwm(fans.MinPwmValue)
+		if err != nil {
+			return err
+		}
+		_, err = f.waitForSettledRpm(ctx, config)
+		if err != nil {
+			return err
+		}
+	}
+
+	midRpm, err := f.measureSettledRpm(ctx, measurements, mid, config)
+	if err != nil {
+		return err
+	}
+
+	if lowerRpm > 0 {
+		expected := lowerRpm + util.Ratio(float64(mid), float64(lower), float64(upper))*(upperRpm-lowerRpm)
+		if math.Abs(midRpm-expected) <= tolerance {
+			return nil
+		}
+	}
+
+	err = f.refineMeasurements(ctx, measurements, lower, mid, tolerance, config)
+	if err != nil {
+		return err
+	}
+	return f.refineMeasurements(ctx, measurements, mid, upper, tolerance, config)
+}
+
+// sets the given PWM value, waits for the fan speed to settle and stores the measured RPM.
+// Already measured PWM values are not measured again.
+func (f *fanController) measureSettledRpm(ctx context.Context, measurements map[int]float64, pwm int, config configuration.InitializationConfig) (float64, error) {
+	fan := f.fan
+	if rpm, ok := measurements[pwm]; ok {
+		return rpm, nil
+	}
+
+	err := fan.SetPwm(pwm)
+	if err != nil {
+		return 0, err
+	}
+
+	rpm, err := f.waitForSettledRpm(ctx, config)
+	if err != nil {
+		return 0, err
+	}
+	ui.Debug("Measured RPM of %d at PWM %d for fan %s", int(rpm), pwm, fan.GetId())
+	measurements[pwm] = rpm
+	return rpm, nil
+}
+
+// waits until consecutive RPM measurements of the fan differ by less than the configured
+// threshold, or the configured timeout is reached, and returns the last measured RPM value.
+// Returns the error of the given context, if it is done before.
+func (f *fanController) waitForSettledRpm(ctx context.Context, config configuration.InitializationConfig) (float64, error) {
+	fan := f.fan
+	diffThreshold := config.MaxRpmDiffForSettledFan
+	sampleRate := configuration.GetConfig().RpmPollingRate
+
+	measuredRpmDiffWindow := util.CreateRollingWindow(settledRpmWindowSize)
+	fillWindow(measuredRpmDiffWindow, settledRpmWindowSize, 2*diffThreshold)
+	measuredRpmDiffMax := 2 * diffThreshold
+	oldRpm := fan.GetRpm()
+
+	timeout := time.After(config.SettleTimeout)
+	tick := time.NewTicker(sampleRate)
+	defer tick.Stop()
+	for !(measuredRpmDiffMax < diffThreshold) {
+		select {
+		case <-ctx.Done():
+			return float64(oldRpm), ctx.Err()
+		case <-timeout:
+			ui.Warning("Fan %s did not settle within %v (current RPM max diff: %f)", fan.GetId(), config.SettleTimeout, measuredRpmDiffMax)
+			return float64(oldRpm), nil
+		case <-tick.C:
+			currentRpm := fan.GetRpm()
+			measuredRpmDiffWindow.Append(math.Abs(float64(currentRpm - oldRpm)))
+			oldRpm = currentRpm
+			measuredRpmDiffMax = math.Ceil(getWindowMax(measuredRpmDiffWindow))
+			ui.Debug("Waiting for fan %s to settle (current RPM max diff: %f)...", fan.GetId(), measuredRpmDiffMax)
+		}
+	}
+
+	return float64(oldRpm), nil
+}
+
+// read the current value of a fan RPM sensor and append it to the moving window.
+// The attached fan curve is not updated, since it is read by the controller
+// (f.ex. in rpm and closedLoop mode) and only changes during initialization.
 func measureRpm(fan fans.Fan) {
-	pwm := fan.GetPwm()
 	rpm := fan.GetRpm()
+	if rpm < 0 {
+		// the RPM input cannot be read
+		return
+	}
 
-	updatedRpmAvg := util.UpdateSimpleMovingAvg(fan.GetRpmAvg(), configuration.CurrentConfig.RpmRollingWindowSize, float64(rpm))
+	updatedRpmAvg := util.UpdateSimpleMovingAvg(fan.GetRpmAvg(), configuration.GetConfig().RpmRollingWindowSize, float64(rpm))
 	fan.SetRpmAvg(updatedRpmAvg)
-
-	pwmRpmMap := fan.GetFanCurveData()
-	(*pwmRpmMap)[pwm] = float64(rpm)
 }
 
 func trySetManualPwm(fan fans.Fan) {
@@ -270,18 +1013,22 @@ func trySetManualPwm(fan fans.Fan) {
 // calculates the target speed for a given device output
 func (f *fanController) calculateOptimalPwm(fan fans.Fan) (int, error) {
 	curveConfigId := fan.GetCurveId()
-	speedCurve := curves.SpeedCurveMap[curveConfigId]
+	speedCurve, ok := curves.GetSpeedCurve(curveConfigId)
+	if !ok {
+		return 0, fmt.Errorf("curve %s not found", curveConfigId)
+	}
 	return speedCurve.Evaluate()
 }
 
 // calculates the optimal pwm for a fan with the given target level.
-// returns -1 if no rpm is detected even at fan.maxPwm
+// returns -1 if the target cannot be calculated (f.ex. because a sensor is gone),
+// or if no rpm is detected even at fan.maxPwm
 func (f *fanController) calculateTargetPwm() int {
 	fan := f.fan
-	currentPwm := fan.GetPwm()
 	target, err := f.calculateOptimalPwm(fan)
 	if err != nil {
-		ui.Fatal("Unable to calculate optimal PWM value for %s: %v", fan.GetId(), err)
+		ui.Error("Unable to calculate optimal PWM value for %s: %v", fan.GetId(), err)
+		return -1
 	}
 
 	// ensure target value is within bounds of possible values
@@ -295,20 +1042,28 @@ func (f *fanController) calculateTargetPwm() int {
 
 	// map the target value to the possible range of this fan
 	maxPwm := fan.GetMaxPwm()
-	// minPwm := fan.GetMinPwm()
+	minPwm := fan.GetMinPwm()
 
-	// TODO: this assumes a linear curve, but it might be something else
-	// target = minPwm + int((float64(target)/fans.MaxPwmValue)*(float64(maxPwm)-float64(minPwm)))
+	target = f.applyZeroRpm(target)
+	keepSpinning := fan.ShouldNeverStop() || (fan.GetConfig().ZeroRpm != nil && !f.zeroRpmStopped)
 
-	if f.lastSetPwm != nil {
-		lastSetPwm := *(f.lastSetPwm)
-		if lastSetPwm != currentPwm {
-			ui.Warning("PWM of %s was changed by third party! Last set PWM value was: %d but is now: %d",
-				fan.GetId(), lastSetPwm, currentPwm)
-		}
+	switch fan.GetConfig().ControlMode {
+	case configuration.ControlModeRpm:
+		target = mapCurveValueToPwm(fan, target)
+	case configuration.ControlModeClosedLoop:
+		target = f.calculateClosedLoopPwm(target)
 	}
 
-	if fan.Supports(fans.FeatureRpmSensor) {
+	if keepSpinning && target < minPwm {
+		target = minPwm
+	}
+
+	// fans may need a higher PWM value to start from a stand still,
+	// than to keep spinning
+	target = f.applySpinUp(target)
+	kicked := f.isSpinningUp()
+
+	if !kicked && !f.dryRun && fan.Supports(fans.FeatureRpmSensor) {
 		// make sure fans never stop by validating the current RPM
 		// and adjusting the target PWM value upwards if necessary
 		shouldNeverStop := fan.ShouldNeverStop()
@@ -336,8 +1091,180 @@ func (f *fanController) calculateTargetPwm() int {
 	return target
 }
 
+// stops the fan once the stop threshold of its zero RPM config is reached, and starts it again
+// once the start threshold is reached, respecting the configured min on and off times.
+// Returns 0 while the fan is stopped, the given target otherwise.
+func (f *fanController) applyZeroRpm(target int) int {
+	config := f.fan.GetConfig().ZeroRpm
+	if config == nil {
+		return target
+	}
+
+	value := float64(target)
+	if len(config.Sensor) > 0 {
+		sensor, ok := sensors.GetSensor(config.Sensor)
+		if !ok {
+			ui.Warning("Zero RPM sensor %s of fan %s not found", config.Sensor, f.fan.GetId())
+			return target
+		}
+		// milli-degree to degree
+		value = sensor.GetMovingAvg() / 1000
+	}
+
+	now := f.now()
+	elapsed := now.Sub(f.zeroRpmLastSwitch)
+	if f.zeroRpmStopped {
+		if value > config.StartAbove && elapsed >= config.MinOffTime {
+			ui.Info("Starting fan %s, zero RPM start threshold reached", f.fan.GetId())
+			f.zeroRpmStopped = false
+			f.zeroRpmLastSwitch = now
+		}
+	} else {
+		if value < config.StopBelow && elapsed >= config.MinOnTime {
+			ui.Info("Stopping fan %s, zero RPM stop threshold reached", f.fan.GetId())
+			f.zeroRpmStopped = true
+			f.zeroRpmLastSwitch = now
+		}
+	}
+
+	if f.zeroRpmStopped {
+		return fans.MinPwmValue
+	}
+	return target
+}
+
+// drives the fan at its spin up PWM for the configured duration, when it
+// is supposed to move from a stand still to the given target. Targets below
+// the min PWM of the fan don't start it, since it would stall again right away.
+func (f *fanController) applySpinUp(target int) int {
+	fan := f.fan
+	if target <= fans.MinPwmValue {
+		f.spinUpUntil = time.Time{}
+		return target
+	}
+
+	spinUpPwm := fan.GetStartPwm()
+	duration := DefaultSpinUpDuration
+	config := fan.GetConfig().SpinUp
+	if config != nil {
+		if config.Pwm != nil {
+			spinUpPwm = *config.Pwm
+		}
+		if config.Duration > 0 {
+			duration = config.Duration
+		}
+	}
+
+	if !f.isSpinningUp() {
+		if target < fan.GetMinPwm() {
+			return target
+		}
+		wasStopped := f.lastSetPwm != nil && *f.lastSetPwm <= fans.MinPwmValue
+		// in dry-run mode the RPM of the fan doesn't follow the calculated PWM values
+		if !wasStopped && (f.dryRun || !isFanStopped(fan)) {
+			return target
+		}
+		ui.Debug("Starting fan %s from a stand still using PWM %d for %v", fan.GetId(), spinUpPwm, duration)
+		f.spinUpUntil = f.now().Add(duration)
+	}
+
+	if target < spinUpPwm {
+		target = spinUpPwm
+	}
+	return target
+}
+
+// indicates whether the fan is currently driven at its spin up PWM
+func (f *fanController) isSpinningUp() bool {
+	return f.now().Before(f.spinUpUntil)
+}
+
+// indicates whether the given fan is currently standing still
+func isFanStopped(fan fans.Fan) bool {
+	if fan.Supports(fans.FeatureRpmSensor) {
+		return fan.GetRpm() <= 0
+	}
+	return fan.GetPwm() <= fans.MinPwmValue
+}
+
+// maps the given curve value (0..255) to the PWM value which yields the same share
+// of the RPM range (between start and max PWM) of the given fan, using its measured fan curve.
+// A curve value of 0 always maps to 0.
+func mapCurveValueToPwm(fan fans.Fan, value int) int {
+	if value <= 0 {
+		return fans.MinPwmValue
+	}
+	return findPwmForRpm(fan, calculateTargetRpm(fan, value))
+}
+
+// calculates the PWM value required to reach the RPM value which corresponds to the given
+// curve value (0..255). The measured fan curve is used as a feed-forward starting point,
+// which is then corrected based on the difference between the target and the measured RPM.
+func (f *fanController) calculateClosedLoopPwm(value int) int {
+	fan := f.fan
+	if value <= 0 || !fan.Supports(fans.FeatureRpmSensor) {
+		// start from scratch the next time the fan is supposed to spin
+		f.rpmLoop = nil
+		return mapCurveValueToPwm(fan, value)
+	}
+
+	if f.rpmLoop == nil {
+		gains := DefaultClosedLoopConfig
+		if fan.GetConfig().ClosedLoop != nil {
+			gains = *fan.GetConfig().ClosedLoop
+		}
+		f.rpmLoop = util.NewPidLoop(gains.P, gains.I, gains.D, -fans.MaxPwmValue, fans.MaxPwmValue)
+	}
+
+	targetRpm := calculateTargetRpm(fan, value)
+	feedForward := findPwmForRpm(fan, targetRpm)
+	correction := f.rpmLoop.LoopAt(targetRpm-fan.GetRpmAvg(), f.now())
+
+	target := feedForward + int(math.Round(correction))
+	if target > fan.GetMaxPwm() {
+		target = fan.GetMaxPwm()
+	} else if target < fans.MinPwmValue {
+		target = fans.MinPwmValue
+	}
+	return target
+}
+
+// calculates the RPM value which corresponds to the given curve value (0..255),
+// using the RPM range between start and max PWM of the given fan
+func calculateTargetRpm(fan fans.Fan, value int) float64 {
+	pwmRpmMap := *fan.GetFanCurveData()
+	minRpm := pwmRpmMap[fan.GetStartPwm()]
+	maxRpm := pwmRpmMap[fan.GetMaxPwm()]
+	return minRpm + (float64(value)/fans.MaxPwmValue)*(maxRpm-minRpm)
+}
+
+// finds the lowest PWM value (between start and max PWM) at which the
+// measured fan curve of the given fan reaches the given RPM value
+func findPwmForRpm(fan fans.Fan, rpm float64) int {
+	pwmRpmMap := *fan.GetFanCurveData()
+	maxPwm := fan.GetMaxPwm()
+	for pwm := fan.GetStartPwm(); pwm < maxPwm; pwm++ {
+		if pwmRpmMap[pwm] >= rpm {
+			return pwm
+		}
+	}
+	return maxPwm
+}
+
 // set the pwm speed of a fan to the specified value (0..255)
 func (f *fanController) setPwm(target int) (err error) {
+	f.statisticsMu.Lock()
+	f.statistics.TargetPwm = target
+	f.statisticsMu.Unlock()
+
+	if f.dryRun {
+		if f.lastSetPwm == nil || *f.lastSetPwm != target {
+			ui.Info("Dry-run: would set PWM of %s to %d", f.fan.GetId(), target)
+		}
+		f.lastSetPwm = &target
+		return nil
+	}
+
 	current := f.fan.GetPwm()
 	f.lastSetPwm = &target
 	if target == current {
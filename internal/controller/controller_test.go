@@ -1,11 +1,16 @@
 package controller
 
 import (
+	"context"
 	"github.com/markusressel/fan2go/internal/configuration"
 	"github.com/markusressel/fan2go/internal/curves"
 	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/persistence"
 	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/simulation"
 	"github.com/stretchr/testify/assert"
+	"math"
+	"os"
 	"testing"
 	"time"
 )
@@ -53,6 +58,10 @@ func (c MockCurve) Evaluate() (value int, err error) {
 	return c.Value, nil
 }
 
+func (c MockCurve) CurrentValue() (value int, err error) {
+	return c.Value, nil
+}
+
 type MockFan struct {
 	ID              string
 	PWM             int
@@ -60,6 +69,16 @@ type MockFan struct {
 	RPM             int
 	curveId         string
 	shouldNeverStop bool
+	config          configuration.FanConfig
+	PwmEnabled      int
+}
+
+func (fan MockFan) GetConfig() configuration.FanConfig {
+	return fan.config
+}
+
+func (fan *MockFan) SetConfig(config configuration.FanConfig) {
+	fan.config = config
 }
 
 func (fan MockFan) GetStartPwm() int {
@@ -116,11 +135,12 @@ func (fan *MockFan) AttachFanCurveData(curveData *map[int]float64) (err error) {
 }
 
 func (fan MockFan) GetPwmEnabled() (int, error) {
-	panic("implement me")
+	return fan.PwmEnabled, nil
 }
 
 func (fan *MockFan) SetPwmEnabled(value int) (err error) {
-	panic("implement me")
+	fan.PwmEnabled = value
+	return nil
 }
 
 func (fan MockFan) IsPwmAuto() (bool, error) {
@@ -175,9 +195,19 @@ var (
 		50:  50.0,
 		200: 200.0,
 	}
+
+	NonLinearFan = map[int]float64{
+		0:   0.0,
+		50:  0.0,
+		51:  500.0,
+		100: 1500.0,
+		255: 2000.0,
+	}
 )
 
-type mockPersistence struct{}
+type mockPersistence struct {
+	thresholds map[string]persistence.FanPwmThresholds
+}
 
 func (p mockPersistence) SaveFanPwmData(fan fans.Fan) (err error) { return nil }
 func (p mockPersistence) LoadFanPwmData(fan fans.Fan) (map[int]float64, error) {
@@ -185,6 +215,20 @@ func (p mockPersistence) LoadFanPwmData(fan fans.Fan) (map[int]float64, error) {
 	return fanCurveDataMap, nil
 }
 
+func (p mockPersistence) SaveFanPwmThresholds(fan fans.Fan, thresholds persistence.FanPwmThresholds) (err error) {
+	if p.thresholds != nil {
+		p.thresholds[fan.GetId()] = thresholds
+	}
+	return nil
+}
+func (p mockPersistence) LoadFanPwmThresholds(fan fans.Fan) (persistence.FanPwmThresholds, error) {
+	thresholds, ok := p.thresholds[fan.GetId()]
+	if !ok {
+		return thresholds, os.ErrNotExist
+	}
+	return thresholds, nil
+}
+
 func CreateFan(neverStop bool, curveData map[int]float64, startPwm *int) (fan fans.Fan, err error) {
 	configuration.CurrentConfig.RpmRollingWindowSize = 10
 
@@ -353,3 +397,967 @@ func TestFanWithStartPwmConfig(t *testing.T) {
 	assert.Equal(t, startPwm, newStartPwm)
 	assert.Equal(t, 255, maxPwm)
 }
+
+func TestPwmChangeLimitsWithoutConfig(t *testing.T) {
+	// GIVEN
+	config := configuration.FanConfig{}
+
+	// WHEN
+	result := applyPwmChangeLimits(config, 60, 255, 0.2)
+
+	// THEN
+	assert.Equal(t, 255.0, result)
+}
+
+func TestPwmChangeLimitsRampRate(t *testing.T) {
+	// GIVEN
+	config := configuration.FanConfig{
+		RampRate: &configuration.RampRateConfig{
+			Increase: 50,
+			Decrease: 10,
+		},
+	}
+
+	// WHEN
+	increased := applyPwmChangeLimits(config, 60, 255, 0.2)
+	decreased := applyPwmChangeLimits(config, 60, 0, 0.5)
+
+	// THEN
+	assert.Equal(t, 70.0, increased)
+	assert.Equal(t, 55.0, decreased)
+}
+
+func TestPwmChangeLimitsSmoothing(t *testing.T) {
+	// GIVEN
+	config := configuration.FanConfig{
+		Smoothing: 0.75,
+	}
+
+	// WHEN
+	result := applyPwmChangeLimits(config, 100, 200, 0.2)
+
+	// THEN
+	assert.Equal(t, 125.0, result)
+}
+
+func TestUpdateFanSpeedWithRampRate(t *testing.T) {
+	// GIVEN
+	curve := &MockCurve{
+		ID:    "ramp_curve",
+		Value: 255,
+	}
+	curves.SpeedCurveMap[curve.GetId()] = curve
+
+	fan := &MockFan{
+		ID:      "ramp_fan",
+		PWM:     60,
+		RPM:     1000,
+		curveId: curve.GetId(),
+		config: configuration.FanConfig{
+			RampRate: &configuration.RampRateConfig{
+				Increase: 50,
+			},
+		},
+	}
+
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		curve:       curve,
+		updateRate:  200 * time.Millisecond,
+	}
+
+	// WHEN
+	err := controller.UpdateFanSpeed()
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Greater(t, fan.GetPwm(), 60)
+	assert.Less(t, fan.GetPwm(), 255)
+}
+
+func TestUpdateFanSpeedWithoutCurve(t *testing.T) {
+	// GIVEN
+	fan := &MockFan{
+		ID:      "missing_curve_fan",
+		PWM:     100,
+		RPM:     1000,
+		curveId: "missing_curve",
+	}
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		updateRate:  time.Duration(100),
+	}
+
+	// WHEN
+	err := controller.UpdateFanSpeed()
+
+	// THEN
+	// the update is skipped
+	assert.NoError(t, err)
+	assert.Equal(t, 100, fan.GetPwm())
+}
+
+func TestUpdateConfig(t *testing.T) {
+	// GIVEN
+	curve := &MockCurve{
+		ID:    "ramp_curve",
+		Value: 255,
+	}
+	curves.SpeedCurveMap[curve.GetId()] = curve
+
+	fan := &MockFan{
+		ID:      "ramp_fan",
+		PWM:     60,
+		RPM:     1000,
+		curveId: curve.GetId(),
+		config: configuration.FanConfig{
+			Curve: curve.GetId(),
+			RampRate: &configuration.RampRateConfig{
+				Increase: 50,
+			},
+		},
+	}
+
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		curve:       curve,
+		updateRate:  200 * time.Millisecond,
+	}
+
+	// WHEN
+	controller.UpdateConfig(configuration.FanConfig{
+		Curve: curve.GetId(),
+	})
+	err := controller.UpdateFanSpeed()
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Nil(t, fan.GetConfig().RampRate)
+	assert.Equal(t, 255, fan.GetPwm())
+}
+
+func TestUpdateConfigStartPwm(t *testing.T) {
+	// GIVEN
+	startPwm := 50
+	config := configuration.FanConfig{
+		ID:       "start_pwm_fan",
+		StartPwm: &startPwm,
+		HwMon:    &configuration.HwMonFanConfig{Platform: "nct6798", Index: 1},
+	}
+	fan, err := fans.NewFan(config)
+	assert.NoError(t, err)
+	_ = fan.AttachFanCurveData(&map[int]float64{0: 0, 30: 0, 31: 400, 255: 2000})
+	controller := fanController{
+		persistence: mockPersistence{
+			thresholds: map[string]persistence.FanPwmThresholds{
+				fan.GetId(): {StartPwm: 80, MinPwm: 40},
+			},
+		},
+		fan:        fan,
+		updateRate: time.Duration(100),
+	}
+
+	// WHEN
+	newStartPwm := 120
+	config.StartPwm = &newStartPwm
+	controller.UpdateConfig(config)
+
+	// THEN
+	assert.Equal(t, 120, fan.GetStartPwm())
+
+	// WHEN
+	// the start PWM is removed from the configuration
+	config.StartPwm = nil
+	controller.UpdateConfig(config)
+
+	// THEN
+	// the measured thresholds apply again
+	assert.Equal(t, 80, fan.GetStartPwm())
+	assert.Equal(t, 40, fan.GetMinPwm())
+}
+
+func TestMapCurveValueToPwm(t *testing.T) {
+	// GIVEN
+	fan, _ := CreateFan(false, NonLinearFan, nil)
+
+	// WHEN
+	stopped := mapCurveValueToPwm(fan, 0)
+	lowest := mapCurveValueToPwm(fan, 1)
+	half := mapCurveValueToPwm(fan, 127)
+	full := mapCurveValueToPwm(fan, 255)
+
+	// THEN
+	assert.Equal(t, 0, stopped)
+	assert.Equal(t, 52, lowest)
+	assert.Equal(t, 88, half)
+	assert.Equal(t, 255, full)
+}
+
+func TestCalculateClosedLoopPwm(t *testing.T) {
+	// GIVEN
+	fan, _ := CreateFan(false, NonLinearFan, nil)
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		updateRate:  time.Duration(100),
+	}
+	feedForward := mapCurveValueToPwm(fan, 127)
+
+	// WHEN
+	fan.SetRpmAvg(1000)
+	tooSlow := controller.calculateClosedLoopPwm(127)
+	controller.rpmLoop = nil
+	fan.SetRpmAvg(1500)
+	tooFast := controller.calculateClosedLoopPwm(127)
+
+	// THEN
+	assert.Greater(t, tooSlow, feedForward)
+	assert.Less(t, tooFast, feedForward)
+}
+
+func TestCalculateClosedLoopPwmStopped(t *testing.T) {
+	// GIVEN
+	fan, _ := CreateFan(false, NonLinearFan, nil)
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		updateRate:  time.Duration(100),
+	}
+
+	// WHEN
+	fan.SetRpmAvg(1000)
+	result := controller.calculateClosedLoopPwm(0)
+
+	// THEN
+	assert.Equal(t, 0, result)
+	assert.Nil(t, controller.rpmLoop)
+}
+
+func createThirdPartyTestController(policy string) (*fanController, *MockFan) {
+	curve := &MockCurve{
+		ID:    "third_party_curve",
+		Value: 102,
+	}
+	curves.SpeedCurveMap[curve.GetId()] = curve
+
+	fan := &MockFan{
+		ID:         "third_party_fan",
+		PWM:        102,
+		RPM:        1000,
+		curveId:    curve.GetId(),
+		PwmEnabled: 1,
+		config: configuration.FanConfig{
+			ThirdParty: &configuration.ThirdPartyConfig{
+				Policy:  policy,
+				Backoff: 1 * time.Minute,
+			},
+		},
+	}
+
+	controller := &fanController{
+		persistence:        mockPersistence{},
+		fan:                fan,
+		curve:              curve,
+		updateRate:         time.Duration(100),
+		originalPwmEnabled: 2,
+	}
+	return controller, fan
+}
+
+func TestThirdPartyChangeOverride(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyOverride)
+	_ = controller.UpdateFanSpeed()
+
+	// WHEN
+	fan.PWM = 200
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, 102, fan.GetPwm())
+	assert.Equal(t, 1, controller.GetStatistics().ThirdPartyChanges)
+}
+
+func TestThirdPartyChangeYield(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyYield)
+	_ = controller.UpdateFanSpeed()
+
+	// WHEN
+	fan.PWM = 200
+	_ = controller.UpdateFanSpeed()
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, 200, fan.GetPwm())
+	assert.Equal(t, 1, controller.GetStatistics().ThirdPartyChanges)
+	assert.True(t, controller.yieldUntil.After(time.Now()))
+
+	// WHEN
+	controller.yieldUntil = time.Now().Add(-1 * time.Second)
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, 102, fan.GetPwm())
+	assert.Equal(t, 1, controller.GetStatistics().ThirdPartyChanges)
+}
+
+func TestThirdPartyChangeRelinquish(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyRelinquish)
+	_ = controller.UpdateFanSpeed()
+
+	// WHEN
+	fan.PWM = 200
+	_ = controller.UpdateFanSpeed()
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, 200, fan.GetPwm())
+	assert.Equal(t, 2, fan.PwmEnabled)
+	assert.True(t, controller.relinquished)
+}
+
+// a fan that starts spinning from a stand still at PWM 40, keeps spinning
+// down to PWM 25 and follows a non-linear curve in between
+type responsiveMockFan struct {
+	MockFan
+	spinning  bool
+	curveData *map[int]float64
+}
+
+func (fan *responsiveMockFan) SetPwm(pwm int) (err error) {
+	fan.PWM = pwm
+	if pwm >= 40 {
+		fan.spinning = true
+	} else if pwm < 25 {
+		fan.spinning = false
+	}
+	return nil
+}
+
+func (fan responsiveMockFan) GetRpm() int {
+	if !fan.spinning {
+		return 0
+	}
+	return int(2000 * math.Sqrt(float64(fan.PWM)/fans.MaxPwmValue))
+}
+
+func (fan responsiveMockFan) GetFanCurveData() *map[int]float64 {
+	return fan.curveData
+}
+
+func (fan *responsiveMockFan) AttachFanCurveData(curveData *map[int]float64) (err error) {
+	fan.curveData = curveData
+	return nil
+}
+
+func TestRunInitializationSequence(t *testing.T) {
+	// GIVEN
+	configuration.CurrentConfig.RpmPollingRate = 1 * time.Millisecond
+	configuration.CurrentConfig.RunFanInitializationInParallel = true
+	fan := &responsiveMockFan{
+		MockFan: MockFan{
+			ID: "init_fan",
+			config: configuration.FanConfig{
+				Initialization: &configuration.InitializationConfig{
+					StepSize:                32,
+					SettleTimeout:           1 * time.Second,
+					MaxRpmDiffForSettledFan: 10,
+				},
+			},
+		},
+	}
+	p := mockPersistence{
+		thresholds: map[string]persistence.FanPwmThresholds{},
+	}
+	controller := fanController{
+		persistence: p,
+		fan:         fan,
+		updateRate:  time.Duration(100),
+	}
+
+	// WHEN
+	err := controller.runInitializationSequence(context.Background())
+
+	// THEN
+	assert.NoError(t, err)
+	curveData := *fan.GetFanCurveData()
+	assert.Equal(t, 0.0, curveData[39])
+	assert.Greater(t, curveData[40], 0.0)
+	assert.Equal(t, 2000.0, curveData[fans.MaxPwmValue])
+	// less than a full sweep
+	assert.Less(t, len(curveData), 64)
+	assert.Equal(t, persistence.FanPwmThresholds{StartPwm: 40, MinPwm: 25}, p.thresholds[fan.GetId()])
+}
+
+func TestRunInitializationSequenceCanceled(t *testing.T) {
+	// GIVEN
+	oldPollingRate := configuration.CurrentConfig.RpmPollingRate
+	configuration.CurrentConfig.RpmPollingRate = 1 * time.Hour
+	defer func() { configuration.CurrentConfig.RpmPollingRate = oldPollingRate }()
+	configuration.CurrentConfig.RunFanInitializationInParallel = true
+	fan := &responsiveMockFan{
+		MockFan: MockFan{
+			ID: "canceled_init_fan",
+			config: configuration.FanConfig{
+				Initialization: &configuration.InitializationConfig{
+					SettleTimeout: 1 * time.Hour,
+				},
+			},
+		},
+	}
+	p := mockPersistence{
+		thresholds: map[string]persistence.FanPwmThresholds{},
+	}
+	controller := fanController{
+		persistence: p,
+		fan:         fan,
+		updateRate:  time.Duration(100),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// WHEN
+	err := controller.runInitializationSequence(ctx)
+
+	// THEN
+	assert.Equal(t, context.Canceled, err)
+	assert.NotContains(t, p.thresholds, fan.GetId())
+}
+
+func TestRunCanceledWhileGatheringSensorData(t *testing.T) {
+	// GIVEN
+	fan := &MockFan{
+		ID:         "canceled_fan",
+		PwmEnabled: 2,
+	}
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		updateRate:  time.Duration(100),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// WHEN
+	start := time.Now()
+	err := controller.Run(ctx)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(1*time.Second))
+}
+
+func TestCalculateTargetPwmStartsStoppedFan(t *testing.T) {
+	// GIVEN
+	fan, _ := CreateFan(true, NonLinearFan, nil)
+	ApplyPwmThresholds(fan, persistence.FanPwmThresholds{StartPwm: 51, MinPwm: 30})
+	curve := &MockCurve{
+		ID:    "curve",
+		Value: 40,
+	}
+	curves.SpeedCurveMap[curve.GetId()] = curve
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		curve:       curve,
+		updateRate:  time.Duration(100),
+	}
+
+	// WHEN
+	// the RPM input of the fan doesn't exist, so it is considered to be stopped
+	target := controller.calculateTargetPwm()
+
+	// THEN
+	assert.Equal(t, 30, fan.GetMinPwm())
+	assert.Equal(t, 51, target)
+}
+
+func TestApplyPwmThresholds(t *testing.T) {
+	// GIVEN
+	fan, _ := CreateFan(true, NonLinearFan, nil)
+
+	// WHEN
+	ApplyPwmThresholds(fan, persistence.FanPwmThresholds{StartPwm: 60, MinPwm: 40})
+
+	// THEN
+	assert.Equal(t, 60, fan.GetStartPwm())
+	assert.Equal(t, 40, fan.GetMinPwm())
+}
+
+func TestApplyPwmThresholdsWithStartPwmConfig(t *testing.T) {
+	// GIVEN
+	startPwm := 70
+	fan, _ := CreateFan(true, NonLinearFan, &startPwm)
+
+	// WHEN
+	ApplyPwmThresholds(fan, persistence.FanPwmThresholds{StartPwm: 60, MinPwm: 40})
+
+	// THEN
+	assert.Equal(t, 70, fan.GetStartPwm())
+	assert.Equal(t, 40, fan.GetMinPwm())
+}
+
+func TestSpinUpFromStandStill(t *testing.T) {
+	// GIVEN
+	curve := &MockCurve{
+		ID:    "spin_up_curve",
+		Value: 20,
+	}
+	curves.SpeedCurveMap[curve.GetId()] = curve
+
+	spinUpPwm := 102
+	fan := &MockFan{
+		ID:      "spin_up_fan",
+		PWM:     0,
+		RPM:     0,
+		curveId: curve.GetId(),
+		config: configuration.FanConfig{
+			SpinUp: &configuration.SpinUpConfig{
+				Pwm:      &spinUpPwm,
+				Duration: 1 * time.Minute,
+			},
+			RampRate: &configuration.RampRateConfig{
+				Increase: 1,
+			},
+		},
+	}
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		curve:       curve,
+		updateRate:  time.Duration(100),
+	}
+
+	// WHEN
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, spinUpPwm, fan.GetPwm())
+
+	// WHEN
+	fan.RPM = 500
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, spinUpPwm, fan.GetPwm())
+
+	// WHEN
+	controller.spinUpUntil = time.Now().Add(-1 * time.Second)
+	target := controller.calculateTargetPwm()
+
+	// THEN
+	assert.Equal(t, 20, target)
+}
+
+func TestSpinUpBelowMinPwm(t *testing.T) {
+	// GIVEN
+	curve := &MockCurve{
+		ID:    "spin_up_curve",
+		Value: 20,
+	}
+	curves.SpeedCurveMap[curve.GetId()] = curve
+
+	spinUpPwm := 102
+	fan := &MockFan{
+		ID:      "spin_up_fan",
+		PWM:     0,
+		RPM:     0,
+		MinPWM:  30,
+		curveId: curve.GetId(),
+		config: configuration.FanConfig{
+			SpinUp: &configuration.SpinUpConfig{
+				Pwm:      &spinUpPwm,
+				Duration: 1 * time.Minute,
+			},
+		},
+	}
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		curve:       curve,
+		updateRate:  time.Duration(100),
+	}
+
+	// WHEN
+	// the fan would stall at this target
+	_ = controller.UpdateFanSpeed()
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.False(t, controller.isSpinningUp())
+	assert.Equal(t, 20, fan.GetPwm())
+
+	// WHEN
+	curve.Value = 40
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.True(t, controller.isSpinningUp())
+	assert.Equal(t, spinUpPwm, fan.GetPwm())
+}
+
+func TestZeroRpmHysteresis(t *testing.T) {
+	// GIVEN
+	curve := &MockCurve{
+		ID:    "zero_rpm_curve",
+		Value: 100,
+	}
+	curves.SpeedCurveMap[curve.GetId()] = curve
+
+	fan := &MockFan{
+		ID:      "zero_rpm_fan",
+		PWM:     100,
+		RPM:     1000,
+		MinPWM:  30,
+		curveId: curve.GetId(),
+		config: configuration.FanConfig{
+			ZeroRpm: &configuration.ZeroRpmConfig{
+				StopBelow:  20,
+				StartAbove: 60,
+				MinOnTime:  1 * time.Minute,
+				MinOffTime: 1 * time.Minute,
+			},
+		},
+	}
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		curve:       curve,
+		updateRate:  time.Duration(100),
+	}
+
+	// WHEN
+	running := controller.calculateTargetPwm()
+	curve.Value = 10
+	stopped := controller.calculateTargetPwm()
+	curve.Value = 70
+	stillStopped := controller.calculateTargetPwm()
+
+	// THEN
+	assert.Equal(t, 100, running)
+	assert.Equal(t, 0, stopped)
+	assert.Equal(t, 0, stillStopped)
+
+	// WHEN
+	controller.zeroRpmLastSwitch = time.Now().Add(-2 * time.Minute)
+	restarted := controller.calculateTargetPwm()
+	curve.Value = 10
+	stillRunning := controller.calculateTargetPwm()
+
+	// THEN
+	assert.Equal(t, 70, restarted)
+	assert.Equal(t, 30, stillRunning)
+}
+
+func TestDryRunNeverSetsPwm(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyRelinquish)
+	controller.dryRun = true
+	fan.PWM = 200
+
+	// WHEN
+	_ = controller.UpdateFanSpeed()
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, 200, fan.GetPwm())
+	assert.Equal(t, 1, fan.PwmEnabled)
+	assert.Equal(t, 102, controller.GetStatistics().TargetPwm)
+	assert.Equal(t, 0, controller.GetStatistics().ThirdPartyChanges)
+	assert.False(t, controller.relinquished)
+}
+
+func TestDryRunIgnoresRpm(t *testing.T) {
+	// GIVEN
+	curve := &MockCurve{
+		ID:    "dry_run_curve",
+		Value: 102,
+	}
+	curves.SpeedCurveMap[curve.GetId()] = curve
+
+	fan := &MockFan{
+		ID:              "dry_run_fan",
+		PWM:             100,
+		RPM:             0,
+		MinPWM:          50,
+		curveId:         curve.GetId(),
+		shouldNeverStop: true,
+	}
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		curve:       curve,
+		updateRate:  time.Duration(100),
+		dryRun:      true,
+	}
+
+	// WHEN
+	_ = controller.UpdateFanSpeed()
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.False(t, controller.isSpinningUp())
+	assert.Equal(t, 50, fan.GetMinPwm())
+	assert.Equal(t, 102, controller.GetStatistics().TargetPwm)
+}
+
+func TestRestoreFanSettings(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyOverride)
+
+	// WHEN
+	controller.restoreFanSettings()
+
+	// THEN
+	assert.Equal(t, 2, fan.PwmEnabled)
+	assert.Equal(t, 102, fan.GetPwm())
+}
+
+func TestRestoreFanSettingsManualPwmEnabled(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyOverride)
+	controller.originalPwmEnabled = 1
+
+	// WHEN
+	controller.restoreFanSettings()
+
+	// THEN
+	assert.Equal(t, 1, fan.PwmEnabled)
+	assert.Equal(t, fans.MaxPwmValue, fan.GetPwm())
+}
+
+func TestFailsafe(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyOverride)
+	_ = controller.UpdateFanSpeed()
+
+	// WHEN
+	controller.EnterFailsafe("sensor/cpu", configuration.WatchdogActionMaxPwm)
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, fans.MaxPwmValue, fan.GetPwm())
+
+	// WHEN
+	controller.ExitFailsafe("sensor/cpu")
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, 102, fan.GetPwm())
+	assert.Equal(t, 0, controller.GetStatistics().ThirdPartyChanges)
+}
+
+func TestFailsafeAuto(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyOverride)
+
+	// WHEN
+	controller.EnterFailsafe("controller/third_party_fan", configuration.WatchdogActionAuto)
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, 2, fan.PwmEnabled)
+	assert.Equal(t, 102, fan.GetPwm())
+}
+
+func TestHandleResume(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyOverride)
+	_ = controller.UpdateFanSpeed()
+
+	// the hardware resets the fan to automatic mode during suspend
+	fan.PwmEnabled = 2
+	fan.PWM = 200
+	controller.originalPwmEnabled = 5
+
+	// WHEN
+	controller.HandleResume()
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.Equal(t, 1, fan.PwmEnabled)
+	assert.Equal(t, 2, controller.originalPwmEnabled)
+	assert.Equal(t, 102, fan.GetPwm())
+	assert.Equal(t, 0, controller.GetStatistics().ThirdPartyChanges)
+}
+
+func TestHandleResumeWhileYielding(t *testing.T) {
+	// GIVEN
+	controller, fan := createThirdPartyTestController(configuration.ThirdPartyPolicyYield)
+	_ = controller.UpdateFanSpeed()
+	fan.PWM = 200
+	_ = controller.UpdateFanSpeed()
+
+	// the hardware resets the fan to automatic mode during suspend
+	fan.PwmEnabled = 2
+	controller.originalPwmEnabled = 5
+
+	// WHEN
+	controller.HandleResume()
+	_ = controller.UpdateFanSpeed()
+
+	// THEN
+	assert.True(t, controller.yieldUntil.After(time.Now()))
+	assert.Equal(t, 2, fan.PwmEnabled)
+	assert.Equal(t, 2, controller.originalPwmEnabled)
+}
+
+func TestSimulatedClosedLoop(t *testing.T) {
+	// GIVEN
+	now := time.Unix(0, 0)
+	model := simulation.NewModel(func() time.Time {
+		return now
+	})
+
+	fan := fans.NewSimulatedFan(configuration.FanConfig{
+		ID:    "simulated_fan",
+		Curve: "simulated_curve",
+		Simulated: &configuration.SimulatedFanConfig{
+			MaxRpm:   2000,
+			StartPwm: 60,
+			StopPwm:  40,
+			Inertia:  2 * time.Second,
+		},
+	}, model)
+	fans.FanMap[fan.GetId()] = fan
+
+	sensor := sensors.NewSimulatedSensor(configuration.SensorConfig{
+		ID: "simulated_sensor",
+		Simulated: &configuration.SimulatedSensorConfig{
+			Ambient: 25,
+			Power:   60,
+			Cooling: 2,
+			Fans:    []string{fan.GetId()},
+			Inertia: 20 * time.Second,
+		},
+	}, model)
+	sensors.SensorMap[sensor.GetId()] = sensor
+
+	curve, err := curves.NewSpeedCurve(configuration.CurveConfig{
+		ID: "simulated_curve",
+		Linear: &configuration.LinearCurveConfig{
+			Sensor: sensor.GetId(),
+			Min:    40,
+			Max:    80,
+		},
+	})
+	assert.NoError(t, err)
+	curves.SpeedCurveMap[curve.GetId()] = curve
+
+	// measure the fan curve, like the initialization sequence would
+	curveData := map[int]float64{}
+	for pwm := 0; pwm <= 255; pwm += 5 {
+		_ = fan.SetPwm(pwm)
+		now = now.Add(10 * time.Second)
+		curveData[pwm] = float64(fan.GetRpm())
+	}
+	_ = fan.SetPwm(0)
+	now = now.Add(10 * time.Second)
+	err = fan.AttachFanCurveData(&curveData)
+	assert.NoError(t, err)
+
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		curve:       curve,
+		updateRate:  1 * time.Second,
+	}
+
+	// WHEN
+	var temperatures []float64
+	for i := 0; i < 600; i++ {
+		now = now.Add(1 * time.Second)
+		value, _ := sensor.GetValue()
+		sensor.SetMovingAvg(value)
+		fan.SetRpmAvg(float64(fan.GetRpm()))
+		err = controller.UpdateFanSpeed()
+		assert.NoError(t, err)
+		temperatures = append(temperatures, value/1000)
+	}
+
+	// THEN
+	// without any airflow the heat source would reach 85 degree,
+	// at full speed it would cool down to 45 degree
+	final := temperatures[len(temperatures)-1]
+	assert.InDelta(t, 60, fan.GetStartPwm(), 5)
+	assert.Greater(t, final, 50.0)
+	assert.Less(t, final, 65.0)
+	assert.InDelta(t, temperatures[len(temperatures)-60], final, 0.5)
+	assert.Greater(t, fan.GetPwm(), fan.GetStartPwm())
+	assert.Less(t, fan.GetPwm(), fans.MaxPwmValue)
+}
+
+func TestMeasureRpmKeepsFanCurve(t *testing.T) {
+	// GIVEN
+	now := time.Unix(0, 0)
+	model := simulation.NewModel(func() time.Time {
+		return now
+	})
+	fan := fans.NewSimulatedFan(configuration.FanConfig{
+		ID: "measured_fan",
+		Simulated: &configuration.SimulatedFanConfig{
+			MaxRpm:   2000,
+			StartPwm: 60,
+			StopPwm:  40,
+		},
+	}, model)
+	curveData := map[int]float64{0: 0, 255: 1000}
+	err := fan.AttachFanCurveData(&curveData)
+	assert.NoError(t, err)
+	expected := map[int]float64{}
+	for pwm, rpm := range *fan.GetFanCurveData() {
+		expected[pwm] = rpm
+	}
+
+	_ = fan.SetPwm(255)
+	now = now.Add(10 * time.Second)
+
+	// WHEN
+	measureRpm(fan)
+
+	// THEN
+	assert.Equal(t, expected, *fan.GetFanCurveData())
+	assert.NotEqual(t, 0.0, fan.GetRpmAvg())
+}
+
+func TestPollRpmWhilePaused(t *testing.T) {
+	// GIVEN
+	now := time.Unix(0, 0)
+	model := simulation.NewModel(func() time.Time {
+		return now
+	})
+	fan := fans.NewSimulatedFan(configuration.FanConfig{
+		ID: "paused_fan",
+		Simulated: &configuration.SimulatedFanConfig{
+			MaxRpm:   2000,
+			StartPwm: 60,
+			StopPwm:  40,
+		},
+	}, model)
+	_ = fan.SetPwm(255)
+	now = now.Add(10 * time.Second)
+	controller := fanController{
+		persistence: mockPersistence{},
+		fan:         fan,
+		updateRate:  time.Duration(100),
+	}
+
+	// WHEN
+	controller.Pause("test")
+	controller.pollRpm()
+
+	// THEN
+	assert.Equal(t, 0.0, fan.GetRpmAvg())
+
+	// WHEN
+	controller.Unpause("test")
+	controller.pollRpm()
+
+	// THEN
+	assert.Greater(t, fan.GetRpmAvg(), 0.0)
+}
@@ -7,13 +7,20 @@ import (
 	"github.com/markusressel/fan2go/internal/ui"
 	"github.com/markusressel/fan2go/internal/util"
 	"math"
+	"sync"
+	"time"
 )
 
 type SpeedCurve interface {
 	GetId() string
 	// Evaluate calculates the current value of the given curve,
-	// returns a value in [0..255]
+	// returns a value in [0..255].
+	// This is called once per tick by the controller of each fan using the curve.
 	Evaluate() (value int, err error)
+	// CurrentValue returns the current value of the given curve like Evaluate,
+	// but without advancing the state of stateful curves (f.ex. a PID loop),
+	// for readers other than the fan controllers
+	CurrentValue() (value int, err error)
 }
 
 type functionSpeedCurve struct {
@@ -30,11 +37,55 @@ type linearSpeedCurve struct {
 	steps    map[int]float64
 }
 
+type pidSpeedCurve struct {
+	ID       string
+	sensorId string
+	setPoint float64
+	pidLoop  *util.PidLoop
+	clock    util.Clock
+	// minimum time between two advances of the PID loop
+	minInterval time.Duration
+
+	mu       sync.Mutex
+	lastLoop time.Time
+	value    int
+}
+
 var (
 	SpeedCurveMap = map[string]SpeedCurve{}
+	// guards SpeedCurveMap, which is replaced when the configuration is reloaded
+	speedCurveMapMu sync.RWMutex
 )
 
+// GetSpeedCurve returns the curve with the given id
+func GetSpeedCurve(id string) (SpeedCurve, bool) {
+	speedCurveMapMu.RLock()
+	defer speedCurveMapMu.RUnlock()
+	curve, ok := SpeedCurveMap[id]
+	return curve, ok
+}
+
+// GetSpeedCurves returns all curves, the returned map must not be modified
+func GetSpeedCurves() map[string]SpeedCurve {
+	speedCurveMapMu.RLock()
+	defer speedCurveMapMu.RUnlock()
+	return SpeedCurveMap
+}
+
+// SetSpeedCurves replaces all curves
+func SetSpeedCurves(curveMap map[string]SpeedCurve) {
+	speedCurveMapMu.Lock()
+	defer speedCurveMapMu.Unlock()
+	SpeedCurveMap = curveMap
+}
+
 func NewSpeedCurve(config configuration.CurveConfig) (SpeedCurve, error) {
+	return NewSpeedCurveWithClock(config, time.Now)
+}
+
+// NewSpeedCurveWithClock creates a speed curve, whose time based
+// calculations (f.ex. of a PID loop) use the given clock
+func NewSpeedCurveWithClock(config configuration.CurveConfig, clock util.Clock) (SpeedCurve, error) {
 	if config.Linear != nil {
 		return &linearSpeedCurve{
 			ID:       config.ID,
@@ -53,6 +104,24 @@ func NewSpeedCurve(config configuration.CurveConfig) (SpeedCurve, error) {
 		}, nil
 	}
 
+	if config.Pid != nil {
+		return &pidSpeedCurve{
+			ID:       config.ID,
+			sensorId: config.Pid.Sensor,
+			setPoint: config.Pid.SetPoint,
+			pidLoop: util.NewPidLoop(
+				config.Pid.P,
+				config.Pid.I,
+				config.Pid.D,
+				0,
+				255,
+			),
+			clock: clock,
+			// the curve may be shared by multiple fans, each evaluating it once per tick
+			minInterval: configuration.GetConfig().ControllerAdjustmentTickRate / 2,
+		}, nil
+	}
+
 	return nil, fmt.Errorf("no matching curve type for curve: %s", config.ID)
 }
 
@@ -60,8 +129,15 @@ func (c linearSpeedCurve) GetId() string {
 	return c.ID
 }
 
+func (c linearSpeedCurve) CurrentValue() (value int, err error) {
+	return c.Evaluate()
+}
+
 func (c linearSpeedCurve) Evaluate() (value int, err error) {
-	sensor := sensors.SensorMap[c.sensorId]
+	sensor, ok := sensors.GetSensor(c.sensorId)
+	if !ok {
+		return 0, fmt.Errorf("sensor %s not found", c.sensorId)
+	}
 	var avgTemp = sensor.GetMovingAvg()
 
 	steps := c.steps
@@ -86,19 +162,75 @@ func (c linearSpeedCurve) Evaluate() (value int, err error) {
 	return value, nil
 }
 
+func (c *pidSpeedCurve) GetId() string {
+	return c.ID
+}
+
+// Evaluate advances the PID loop of this curve, trying to hold the
+// sensor at the configured set point. If the curve is shared by multiple fans,
+// the loop is only advanced by the first of them in each tick, all others
+// get the same output.
+func (c *pidSpeedCurve) Evaluate() (value int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock()
+	if !c.lastLoop.IsZero() && now.Sub(c.lastLoop) < c.minInterval {
+		return c.value, nil
+	}
+
+	sensor, ok := sensors.GetSensor(c.sensorId)
+	if !ok {
+		return 0, fmt.Errorf("sensor %s not found", c.sensorId)
+	}
+	var avgTemp = sensor.GetMovingAvg()
+
+	// the sensor value is in milli-degree, the set point in degree.
+	// a positive error means the sensor is too hot, requiring more airflow.
+	setPointError := avgTemp/1000 - c.setPoint
+
+	output := c.pidLoop.LoopAt(setPointError, now)
+	c.lastLoop = now
+	c.value = int(math.Round(output))
+	return c.value, nil
+}
+
+// CurrentValue returns the last output of the PID loop
+func (c *pidSpeedCurve) CurrentValue() (value int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastLoop.IsZero() {
+		return 0, fmt.Errorf("curve %s has not been evaluated yet", c.ID)
+	}
+	return c.value, nil
+}
+
 func (c functionSpeedCurve) GetId() string {
 	return c.ID
 }
 
 func (c functionSpeedCurve) Evaluate() (value int, err error) {
+	return c.evaluate(SpeedCurve.Evaluate)
+}
+
+func (c functionSpeedCurve) CurrentValue() (value int, err error) {
+	return c.evaluate(SpeedCurve.CurrentValue)
+}
+
+// applies the function of this curve to the values of its curves, using the given method to get them
+func (c functionSpeedCurve) evaluate(valueOf func(SpeedCurve) (int, error)) (value int, err error) {
 	var curves []SpeedCurve
 	for _, curveId := range c.curveIds {
-		curves = append(curves, SpeedCurveMap[curveId])
+		curve, ok := GetSpeedCurve(curveId)
+		if !ok {
+			return 0, fmt.Errorf("curve %s not found", curveId)
+		}
+		curves = append(curves, curve)
 	}
 
 	var values []int
 	for _, curve := range curves {
-		v, err := curve.Evaluate()
+		v, err := valueOf(curve)
 		if err != nil {
 			return 0, err
 		}
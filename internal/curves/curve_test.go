@@ -5,6 +5,7 @@ import (
 	"github.com/markusressel/fan2go/internal/sensors"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 // helper function to create a linear curve configuration
@@ -71,6 +72,28 @@ func createLinearCurveConfigWithSteps(
 	return curve
 }
 
+// helper function to create a pid curve configuration
+func createPidCurveConfig(
+	id string,
+	sensorId string,
+	setPoint float64,
+	p float64,
+	i float64,
+	d float64,
+) (curve configuration.CurveConfig) {
+	curve = configuration.CurveConfig{
+		ID: id,
+		Pid: &configuration.PidCurveConfig{
+			Sensor:   sensorId,
+			SetPoint: setPoint,
+			P:        p,
+			I:        i,
+			D:        d,
+		},
+	}
+	return curve
+}
+
 // helper function to create a function curve configuration
 func createFunctionCurveConfig(
 	id string,
@@ -379,3 +402,111 @@ func TestFunctionCurveMaximum(t *testing.T) {
 	// THEN
 	assert.Equal(t, 255, result)
 }
+
+func TestPidCurveAboveSetPoint(t *testing.T) {
+	// GIVEN
+	avgTmp := 70000.0
+	s := MockSensor{
+		ID:        "pid_sensor1",
+		Name:      "sensor",
+		MovingAvg: avgTmp,
+	}
+	sensors.SensorMap[s.GetId()] = &s
+
+	curveConfig := createPidCurveConfig(
+		"pid_curve1",
+		s.GetId(),
+		60,
+		10,
+		0,
+		0,
+	)
+	curve, err := NewSpeedCurve(curveConfig)
+
+	// WHEN
+	result, err := curve.Evaluate()
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+
+	// THEN
+	assert.Equal(t, 100, result)
+}
+
+func TestPidCurveBelowSetPoint(t *testing.T) {
+	// GIVEN
+	avgTmp := 50000.0
+	s := MockSensor{
+		ID:        "pid_sensor2",
+		Name:      "sensor",
+		MovingAvg: avgTmp,
+	}
+	sensors.SensorMap[s.GetId()] = &s
+
+	curveConfig := createPidCurveConfig(
+		"pid_curve2",
+		s.GetId(),
+		60,
+		10,
+		1,
+		0,
+	)
+	curve, err := NewSpeedCurve(curveConfig)
+
+	// WHEN
+	result, err := curve.Evaluate()
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+
+	// THEN
+	assert.Equal(t, 0, result)
+}
+
+func TestPidCurveAdvancesOncePerTick(t *testing.T) {
+	// GIVEN
+	s := MockSensor{
+		ID:        "pid_sensor3",
+		Name:      "sensor",
+		MovingAvg: 70000.0,
+	}
+	sensors.SensorMap[s.GetId()] = &s
+
+	oldTickRate := configuration.CurrentConfig.ControllerAdjustmentTickRate
+	configuration.CurrentConfig.ControllerAdjustmentTickRate = 200 * time.Millisecond
+	defer func() { configuration.CurrentConfig.ControllerAdjustmentTickRate = oldTickRate }()
+
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+
+	curveConfig := createPidCurveConfig(
+		"pid_curve3",
+		s.GetId(),
+		60,
+		1,
+		1,
+		0,
+	)
+	curve, err := NewSpeedCurveWithClock(curveConfig, clock)
+	assert.NoError(t, err)
+
+	_, err = curve.CurrentValue()
+	assert.Error(t, err)
+
+	// WHEN
+	first, _ := curve.Evaluate()
+	current, _ := curve.CurrentValue()
+	current, _ = curve.CurrentValue()
+	// a second fan using the same curve within the same tick
+	now = now.Add(50 * time.Millisecond)
+	shared, _ := curve.Evaluate()
+	now = now.Add(150 * time.Millisecond)
+	next, _ := curve.Evaluate()
+
+	// THEN
+	assert.Equal(t, 10, first)
+	assert.Equal(t, 10, current)
+	assert.Equal(t, 10, shared)
+	// integral of 10 degrees over 0.2 seconds
+	assert.Equal(t, 12, next)
+}
@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"context"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/controller"
+	"github.com/markusressel/fan2go/internal/curves"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/statistics"
+	"github.com/markusressel/fan2go/internal/watchdog"
+	"github.com/prometheus/client_golang/prometheus"
+	"sort"
+	"sync"
+)
+
+// deviceManager runs the sensor monitors and fan controllers of the daemon.
+// Sensors and fans can be started and stopped individually while the daemon
+// is running, f.ex. when the configuration is reloaded.
+type deviceManager struct {
+	ctx context.Context
+	// stops the daemon, used when a fan controller stopped on its own
+	cancel  context.CancelFunc
+	pers    persistence.Persistence
+	hotplug *hotplugMonitor
+	// nil if the watchdog is disabled
+	watchdog *watchdog.Watchdog
+
+	mu             sync.Mutex
+	sensorMonitors map[string]*deviceActor
+	monitors       map[string]SensorMonitor
+	controllers    map[string]controller.FanController
+	fanActors      map[string]*deviceActor
+	collectors     []prometheus.Collector
+
+	wg sync.WaitGroup
+}
+
+// deviceActor is a goroutine running until it is stopped, or the daemon shuts down
+type deviceActor struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stops the actor and waits for it to return
+func (a *deviceActor) stop() {
+	a.cancel()
+	<-a.done
+}
+
+func newDeviceManager(ctx context.Context, cancel context.CancelFunc, pers persistence.Persistence, hotplug *hotplugMonitor, w *watchdog.Watchdog) *deviceManager {
+	return &deviceManager{
+		ctx:            ctx,
+		cancel:         cancel,
+		pers:           pers,
+		hotplug:        hotplug,
+		watchdog:       w,
+		sensorMonitors: map[string]*deviceActor{},
+		monitors:       map[string]SensorMonitor{},
+		controllers:    map[string]controller.FanController{},
+		fanActors:      map[string]*deviceActor{},
+	}
+}
+
+// runs the given function in a new goroutine, with a context which is done
+// once the actor has been stopped or the daemon shuts down
+func (d *deviceManager) spawn(run func(ctx context.Context)) *deviceActor {
+	ctx, cancel := context.WithCancel(d.ctx)
+	actor := &deviceActor{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer close(actor.done)
+		run(ctx)
+	}()
+	return actor
+}
+
+// Wait blocks until all sensor monitors and fan controllers have returned,
+// after the daemon has been shut down
+func (d *deviceManager) Wait() {
+	d.wg.Wait()
+}
+
+// startSensor starts monitoring the given sensor
+func (d *deviceManager) startSensor(sensor sensors.Sensor) {
+	sensorId := sensor.GetId()
+	mon := NewSensorMonitor(sensor, configuration.GetConfig().TempSensorPollingRate)
+	d.hotplug.addSensor(sensor.GetConfig(), mon)
+
+	if d.watchdog != nil {
+		action := configuration.GetConfig().Watchdog.Action
+		watchdogId := "sensor/" + sensorId
+		d.watchdog.Register(watchdogId, mon.GetLastHeartbeat, func() {
+			for _, fanId := range getFansUsingSensor(sensorId) {
+				if c, ok := d.getController(fanId); ok {
+					c.EnterFailsafe(watchdogId, action)
+				}
+			}
+		}, func() {
+			// the affected fans may have changed in the meantime
+			for _, c := range d.getControllers() {
+				c.ExitFailsafe(watchdogId)
+			}
+		})
+	}
+
+	actor := d.spawn(func(ctx context.Context) {
+		err := mon.Run(ctx)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sensorMonitors[sensorId] = actor
+	d.monitors[sensorId] = mon
+}
+
+// stopSensor stops monitoring the sensor with the given id
+func (d *deviceManager) stopSensor(sensorId string) {
+	d.mu.Lock()
+	actor, ok := d.sensorMonitors[sensorId]
+	delete(d.sensorMonitors, sensorId)
+	delete(d.monitors, sensorId)
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	actor.stop()
+	if d.watchdog != nil {
+		d.watchdog.Unregister("sensor/" + sensorId)
+	}
+	d.hotplug.removeSensor(sensorId)
+}
+
+// startFan starts controlling the given fan, once its device is available
+func (d *deviceManager) startFan(fan fans.Fan) {
+	fanId := fan.GetId()
+	c := controller.NewFanController(d.pers, fan, configuration.GetConfig().ControllerAdjustmentTickRate)
+	d.hotplug.addFan(fan.GetConfig(), c)
+
+	if d.watchdog != nil {
+		action := configuration.GetConfig().Watchdog.Action
+		watchdogId := "controller/" + fanId
+		d.watchdog.Register(watchdogId, c.GetLastHeartbeat, func() {
+			c.EnterFailsafe(watchdogId, action)
+		}, func() {
+			c.ExitFailsafe(watchdogId)
+		})
+	}
+
+	d.mu.Lock()
+	d.controllers[fanId] = c
+	d.mu.Unlock()
+
+	actor := d.spawn(func(ctx context.Context) {
+		if !d.hotplug.WaitForFan(ctx, fanId) {
+			return
+		}
+		// returns only after the fan has been handed back
+		err := c.Run(ctx)
+		if err != nil {
+			panic(err)
+		}
+		if ctx.Err() == nil {
+			// the controller stopped on its own
+			d.cancel()
+		}
+	})
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fanActors[fanId] = actor
+}
+
+// stopFan stops controlling the fan with the given id, and waits until it has been handed back
+func (d *deviceManager) stopFan(fanId string) {
+	d.mu.Lock()
+	actor, ok := d.fanActors[fanId]
+	delete(d.fanActors, fanId)
+	delete(d.controllers, fanId)
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	actor.stop()
+	if d.watchdog != nil {
+		d.watchdog.Unregister("controller/" + fanId)
+	}
+	d.hotplug.removeFan(fanId)
+}
+
+func (d *deviceManager) getController(fanId string) (controller.FanController, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, ok := d.controllers[fanId]
+	return c, ok
+}
+
+// returns the controllers of all fans, ordered by fan id
+func (d *deviceManager) getControllers() []controller.FanController {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make([]controller.FanController, 0, len(d.controllers))
+	for _, c := range d.controllers {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetFanId() < result[j].GetFanId()
+	})
+	return result
+}
+
+// returns the monitors of all sensors
+func (d *deviceManager) getMonitors() []SensorMonitor {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make([]SensorMonitor, 0, len(d.monitors))
+	for _, mon := range d.monitors {
+		result = append(result, mon)
+	}
+	return result
+}
+
+// registerCollectors (re-)registers the prometheus collectors of all sensors, curves, fans and controllers
+func (d *deviceManager) registerCollectors() {
+	var sensorList []sensors.Sensor
+	for _, sensor := range sensors.GetSensors() {
+		sensorList = append(sensorList, sensor)
+	}
+	var curveList []curves.SpeedCurve
+	for _, curve := range curves.GetSpeedCurves() {
+		curveList = append(curveList, curve)
+	}
+	var fanList []fans.Fan
+	for _, fan := range fans.GetFans() {
+		fanList = append(fanList, fan)
+	}
+
+	collectors := []prometheus.Collector{
+		statistics.NewSensorCollector(sensorList),
+		statistics.NewCurveCollector(curveList),
+		statistics.NewFanCollector(fanList),
+		statistics.NewControllerCollector(d.getControllers()),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, collector := range d.collectors {
+		statistics.Unregister(collector)
+	}
+	for _, collector := range collectors {
+		statistics.Register(collector)
+	}
+	d.collectors = collectors
+}
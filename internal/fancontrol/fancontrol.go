@@ -0,0 +1,363 @@
+package fancontrol
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// options which are handled when importing a configuration
+const (
+	optionInterval = "INTERVAL"
+	optionDevPath  = "DEVPATH"
+	optionDevName  = "DEVNAME"
+	optionFcTemps  = "FCTEMPS"
+	optionFcFans   = "FCFANS"
+	optionMinTemp  = "MINTEMP"
+	optionMaxTemp  = "MAXTEMP"
+	optionMinStart = "MINSTART"
+	optionMinStop  = "MINSTOP"
+	optionMinPwm   = "MINPWM"
+	optionMaxPwm   = "MAXPWM"
+	optionAverage  = "AVERAGE"
+)
+
+// matches the name of a hwmon file, f.ex. "pwm2", "temp1_input" or "fan3_input"
+var fileRegex = regexp.MustCompile(`^(pwm|temp|fan)(\d+)(_input)?$`)
+
+// Config holds the options of a fancontrol configuration file, as written by pwmconfig.
+// Options which are defined per device or per PWM output map the device or PWM output to its value.
+type Config struct {
+	Options map[string]string
+	// order in which the PWM outputs appear in FCTEMPS
+	outputs []string
+}
+
+// Parse reads a fancontrol configuration file (usually /etc/fancontrol)
+func Parse(r io.Reader) (*Config, error) {
+	config := &Config{Options: map[string]string{}}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) <= 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := splitAssignment(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected an assignment, got '%s'", lineNumber, line)
+		}
+		config.Options[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(config.Options[optionFcTemps]) <= 0 {
+		return nil, fmt.Errorf("no %s found, this is not a fancontrol configuration", optionFcTemps)
+	}
+	for _, entry := range strings.Fields(config.Options[optionFcTemps]) {
+		output, _, _ := splitAssignment(entry)
+		config.outputs = append(config.outputs, output)
+	}
+	return config, nil
+}
+
+// splits "KEY=value" at the first '=', without any surrounding quotes of the value
+func splitAssignment(s string) (string, string, bool) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(s[:i]), strings.Trim(strings.TrimSpace(s[i+1:]), `"'`), true
+}
+
+// returns the per device or per PWM output values of the given option
+func (c *Config) values(option string) map[string]string {
+	result := map[string]string{}
+	for _, entry := range strings.Fields(c.Options[option]) {
+		key, value, ok := splitAssignment(entry)
+		if ok {
+			result[normalizePath(key)] = value
+		}
+	}
+	return result
+}
+
+// hwmonFile is a file of a hwmon device, f.ex. "hwmon1/pwm2"
+type hwmonFile struct {
+	device  string
+	kind    string
+	channel int
+}
+
+// normalizePath strips the prefixes used by older fancontrol versions,
+// f.ex. "/sys/class/hwmon/hwmon1/device/pwm2" becomes "hwmon1/pwm2"
+func normalizePath(p string) string {
+	p = strings.TrimPrefix(p, "/sys/class/hwmon/")
+	elements := strings.Split(p, "/")
+	if len(elements) >= 2 {
+		return elements[0] + "/" + elements[len(elements)-1]
+	}
+	return p
+}
+
+func parseFile(p string) (hwmonFile, error) {
+	p = normalizePath(p)
+	device, name := path.Split(p)
+	match := fileRegex.FindStringSubmatch(name)
+	if len(device) <= 0 || match == nil {
+		return hwmonFile{}, fmt.Errorf("unsupported path '%s'", p)
+	}
+	channel, _ := strconv.Atoi(match[2])
+	return hwmonFile{
+		device:  strings.TrimSuffix(device, "/"),
+		kind:    match[1],
+		channel: channel,
+	}, nil
+}
+
+// Result is a fan2go configuration converted from a fancontrol configuration
+type Result struct {
+	Config configuration.Configuration
+	// comments describing the origin of each entry
+	Comments configuration.Comments
+	// settings which could not be converted
+	Warnings []string
+}
+
+// Convert maps the given fancontrol configuration to fan2go sensors, curves and fans.
+// Each temperature input becomes a sensor, each MINTEMP/MAXTEMP pair of a PWM output a linear curve
+// (combined using the maximum, if a PWM output uses more than one temperature input)
+// and each PWM output a hwmon fan using this curve.
+func Convert(c *Config) *Result {
+	result := &Result{Comments: configuration.Comments{}}
+	devNames := c.values(optionDevName)
+	devPaths := c.values(optionDevPath)
+	fcTemps := c.values(optionFcTemps)
+	fcFans := c.values(optionFcFans)
+	minTemps := c.values(optionMinTemp)
+	maxTemps := c.values(optionMaxTemp)
+	minStarts := c.values(optionMinStart)
+	minStops := c.values(optionMinStop)
+	minPwms := c.values(optionMinPwm)
+	maxPwms := c.values(optionMaxPwm)
+
+	warn := func(format string, a ...interface{}) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, a...))
+	}
+
+	if interval, ok := c.Options[optionInterval]; ok {
+		warn("%s=%s is not imported, fan2go uses its own polling and adjustment rates (see tempSensorPollingRate, controllerAdjustmentTickRate)", optionInterval, interval)
+	}
+	if average, ok := c.Options[optionAverage]; ok {
+		warn("%s is not imported, fan2go averages sensor values over tempRollingWindowSize samples instead (%s)", optionAverage, average)
+	}
+	var keys []string
+	for key := range c.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch key {
+		case optionInterval, optionDevPath, optionDevName, optionFcTemps, optionFcFans, optionMinTemp, optionMaxTemp,
+			optionMinStart, optionMinStop, optionMinPwm, optionMaxPwm, optionAverage:
+		default:
+			warn("Unknown option %s is not imported", key)
+		}
+	}
+
+	// device names used by more than one device, which have to be selected by their path
+	nameCount := map[string]int{}
+	for _, name := range devNames {
+		nameCount[name]++
+	}
+
+	// returns the platform and device path used to select the given device
+	selector := func(device string) (string, string, error) {
+		name := devNames[device]
+		devPath := devPaths[device]
+		var devicePath string
+		if len(devPath) > 0 && (len(name) <= 0 || nameCount[name] > 1) {
+			devicePath = "/sys/" + strings.TrimPrefix(devPath, "/sys/")
+		}
+		if len(name) <= 0 && len(devicePath) <= 0 {
+			return "", "", fmt.Errorf("neither %s nor %s is defined for %s", optionDevName, optionDevPath, device)
+		}
+		return name, devicePath, nil
+	}
+
+	// returns a name for the given device, used in the generated IDs
+	deviceId := func(device string) string {
+		name := devNames[device]
+		if len(name) <= 0 || nameCount[name] > 1 {
+			return device
+		}
+		return name
+	}
+
+	sensorIds := map[string]string{}
+	addSensor := func(input string) (string, error) {
+		file, err := parseFile(input)
+		if err != nil {
+			return "", err
+		}
+		if file.kind != "temp" {
+			return "", fmt.Errorf("'%s' is not a temperature input", input)
+		}
+		key := normalizePath(input)
+		if id, ok := sensorIds[key]; ok {
+			return id, nil
+		}
+
+		platform, devicePath, err := selector(file.device)
+		if err != nil {
+			return "", err
+		}
+		id := fmt.Sprintf("%s_temp%d", deviceId(file.device), file.channel)
+		sensorIds[key] = id
+		result.Comments[fmt.Sprintf("sensors[%d]", len(result.Config.Sensors))] = []string{"imported from " + key}
+		result.Config.Sensors = append(result.Config.Sensors, configuration.SensorConfig{
+			ID: id,
+			HwMon: &configuration.HwMonSensorConfig{
+				Platform:   platform,
+				DevicePath: devicePath,
+				Channel:    file.channel,
+			},
+		})
+		return id, nil
+	}
+
+	for _, output := range c.outputs {
+		output = normalizePath(output)
+		file, err := parseFile(output)
+		if err == nil && file.kind != "pwm" {
+			err = fmt.Errorf("'%s' is not a PWM output", output)
+		}
+		if err != nil {
+			warn("Skipping %s: %v", output, err)
+			continue
+		}
+
+		minTemp, err := strconv.Atoi(minTemps[output])
+		if err != nil {
+			warn("Skipping %s: invalid or missing %s", output, optionMinTemp)
+			continue
+		}
+		maxTemp, err := strconv.Atoi(maxTemps[output])
+		if err != nil {
+			warn("Skipping %s: invalid or missing %s", output, optionMaxTemp)
+			continue
+		}
+
+		var sensorIdList []string
+		for _, input := range strings.Split(fcTemps[output], "+") {
+			id, err := addSensor(input)
+			if err != nil {
+				warn("Skipping temperature input '%s' of %s: %v", input, output, err)
+				continue
+			}
+			sensorIdList = append(sensorIdList, id)
+		}
+		if len(sensorIdList) <= 0 {
+			warn("Skipping %s: no usable temperature input", output)
+			continue
+		}
+
+		platform, devicePath, err := selector(file.device)
+		if err != nil {
+			warn("Skipping %s: %v", output, err)
+			continue
+		}
+
+		fanId := fmt.Sprintf("%s_pwm%d", deviceId(file.device), file.channel)
+		curveId := fanId + "_curve"
+		curveComment := fmt.Sprintf("%s=%d and %s=%d of %s", optionMinTemp, minTemp, optionMaxTemp, maxTemp, output)
+		if len(sensorIdList) == 1 {
+			result.Comments[fmt.Sprintf("curves[%d]", len(result.Config.Curves))] = []string{curveComment}
+			result.Config.Curves = append(result.Config.Curves, newLinearCurve(curveId, sensorIdList[0], minTemp, maxTemp))
+		} else {
+			// fancontrol uses the highest temperature of all inputs
+			var curveIds []string
+			for _, sensorId := range sensorIdList {
+				id := fmt.Sprintf("%s_%s_curve", fanId, sensorId)
+				curveIds = append(curveIds, id)
+				result.Comments[fmt.Sprintf("curves[%d]", len(result.Config.Curves))] = []string{curveComment}
+				result.Config.Curves = append(result.Config.Curves, newLinearCurve(id, sensorId, minTemp, maxTemp))
+			}
+			result.Comments[fmt.Sprintf("curves[%d]", len(result.Config.Curves))] = []string{"highest speed of all temperature inputs of " + output}
+			result.Config.Curves = append(result.Config.Curves, configuration.CurveConfig{
+				ID: curveId,
+				Function: &configuration.FunctionCurveConfig{
+					Type:   configuration.FunctionMaximum,
+					Curves: curveIds,
+				},
+			})
+		}
+
+		// the index of a fan is the number of its PWM output, while the index of a sensor
+		// depends on the other sensors of the device, so sensors are selected by their channel
+		fanConfig := configuration.FanConfig{
+			ID:    fanId,
+			Curve: curveId,
+			HwMon: &configuration.HwMonFanConfig{
+				Platform:   platform,
+				DevicePath: devicePath,
+				Index:      file.channel,
+			},
+		}
+		comments := []string{"imported from " + output}
+
+		if value, ok := minStarts[output]; ok {
+			if minStart, err := strconv.Atoi(value); err == nil {
+				fanConfig.StartPwm = &minStart
+			} else {
+				warn("%s: invalid %s '%s'", output, optionMinStart, value)
+			}
+		}
+		if value, ok := minStops[output]; ok {
+			warn("%s: %s=%s is not imported, fan2go measures the min PWM of this fan during initialization", output, optionMinStop, value)
+		}
+		if value, ok := minPwms[output]; ok && value != "0" {
+			// the fan never stops completely
+			fanConfig.NeverStop = true
+			comments = append(comments, fmt.Sprintf("%s=%s is imported as neverStop", optionMinPwm, value))
+		}
+		if value, ok := maxPwms[output]; ok && value != "255" {
+			warn("%s: %s=%s is not imported, the fan will reach its full speed at %s", output, optionMaxPwm, value, optionMaxTemp)
+		}
+		for _, input := range strings.Split(fcFans[output], "+") {
+			if len(input) <= 0 {
+				continue
+			}
+			rpmFile, err := parseFile(input)
+			if err != nil || rpmFile.kind != "fan" || rpmFile.device != file.device || rpmFile.channel != file.channel {
+				warn("%s: RPM input '%s' is not imported, fan2go uses the RPM input with the same channel as the PWM output", output, input)
+			}
+		}
+
+		result.Comments[fmt.Sprintf("fans[%d]", len(result.Config.Fans))] = comments
+		result.Config.Fans = append(result.Config.Fans, fanConfig)
+	}
+
+	return result
+}
+
+func newLinearCurve(id string, sensorId string, minTemp int, maxTemp int) configuration.CurveConfig {
+	return configuration.CurveConfig{
+		ID: id,
+		Linear: &configuration.LinearCurveConfig{
+			Sensor: sensorId,
+			Min:    minTemp,
+			Max:    maxTemp,
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package fancontrol
+
+import (
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testConfig = `# Configuration file generated by pwmconfig, changes will be lost
+INTERVAL=10
+DEVPATH=hwmon1=devices/platform/coretemp.0 hwmon3=devices/platform/nct6775.656
+DEVNAME=hwmon1=coretemp hwmon3=nct6798
+FCTEMPS=hwmon3/pwm1=hwmon1/temp1_input hwmon3/pwm2=hwmon1/temp1_input+hwmon3/temp2_input
+FCFANS=hwmon3/pwm1=hwmon3/fan1_input hwmon3/pwm2=hwmon3/fan2_input
+MINTEMP=hwmon3/pwm1=40 hwmon3/pwm2=35
+MAXTEMP=hwmon3/pwm1=80 hwmon3/pwm2=70
+MINSTART=hwmon3/pwm1=150 hwmon3/pwm2=100
+MINSTOP=hwmon3/pwm1=100 hwmon3/pwm2=60
+MINPWM=hwmon3/pwm2=30
+MAXPWM=hwmon3/pwm1=200
+`
+
+func TestParse(t *testing.T) {
+	// GIVEN
+	reader := strings.NewReader(testConfig)
+
+	// WHEN
+	config, err := Parse(reader)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, "10", config.Options[optionInterval])
+	assert.Equal(t, []string{"hwmon3/pwm1", "hwmon3/pwm2"}, config.outputs)
+	assert.Equal(t, map[string]string{"hwmon1": "coretemp", "hwmon3": "nct6798"}, config.values(optionDevName))
+}
+
+func TestParseInvalid(t *testing.T) {
+	// GIVEN
+	reader := strings.NewReader("INTERVAL=10\n")
+
+	// WHEN
+	_, err := Parse(reader)
+
+	// THEN
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	// GIVEN
+	config, err := Parse(strings.NewReader(testConfig))
+	assert.NoError(t, err)
+
+	// WHEN
+	result := Convert(config)
+
+	// THEN
+	assert.Equal(t, []configuration.SensorConfig{
+		{ID: "coretemp_temp1", HwMon: &configuration.HwMonSensorConfig{Platform: "coretemp", Channel: 1}},
+		{ID: "nct6798_temp2", HwMon: &configuration.HwMonSensorConfig{Platform: "nct6798", Channel: 2}},
+	}, result.Config.Sensors)
+
+	assert.Len(t, result.Config.Curves, 4)
+	assert.Equal(t, configuration.CurveConfig{
+		ID:     "nct6798_pwm1_curve",
+		Linear: &configuration.LinearCurveConfig{Sensor: "coretemp_temp1", Min: 40, Max: 80},
+	}, result.Config.Curves[0])
+	assert.Equal(t, configuration.CurveConfig{
+		ID: "nct6798_pwm2_curve",
+		Function: &configuration.FunctionCurveConfig{
+			Type:   configuration.FunctionMaximum,
+			Curves: []string{"nct6798_pwm2_coretemp_temp1_curve", "nct6798_pwm2_nct6798_temp2_curve"},
+		},
+	}, result.Config.Curves[3])
+
+	assert.Len(t, result.Config.Fans, 2)
+	fan := result.Config.Fans[0]
+	assert.Equal(t, "nct6798_pwm1", fan.ID)
+	assert.Equal(t, "nct6798_pwm1_curve", fan.Curve)
+	assert.Equal(t, &configuration.HwMonFanConfig{Platform: "nct6798", Index: 1}, fan.HwMon)
+	assert.Equal(t, 150, *fan.StartPwm)
+	assert.False(t, fan.NeverStop)
+	assert.True(t, result.Config.Fans[1].NeverStop)
+
+	assert.Len(t, result.Warnings, 4)
+	assert.Contains(t, result.Warnings[0], "INTERVAL")
+	assert.Contains(t, result.Warnings[1], "hwmon3/pwm1: MINSTOP=100")
+	assert.Contains(t, result.Warnings[2], "MAXPWM=200")
+	assert.Contains(t, result.Warnings[3], "hwmon3/pwm2: MINSTOP=60")
+}
+
+func TestConvertDuplicateDeviceNames(t *testing.T) {
+	// GIVEN
+	config, err := Parse(strings.NewReader(`DEVPATH=hwmon1=devices/pci0000:00/0000:00:01.0/0000:01:00.0 hwmon2=devices/pci0000:00/0000:00:03.0/0000:02:00.0
+DEVNAME=hwmon1=amdgpu hwmon2=amdgpu
+FCTEMPS=/sys/class/hwmon/hwmon2/device/pwm1=/sys/class/hwmon/hwmon2/device/temp1_input
+MINTEMP=/sys/class/hwmon/hwmon2/device/pwm1=50
+MAXTEMP=/sys/class/hwmon/hwmon2/device/pwm1=90
+`))
+	assert.NoError(t, err)
+
+	// WHEN
+	result := Convert(config)
+
+	// THEN
+	assert.Empty(t, result.Warnings)
+	assert.Len(t, result.Config.Fans, 1)
+	assert.Equal(t, "hwmon2_pwm1", result.Config.Fans[0].ID)
+	assert.Equal(t, &configuration.HwMonFanConfig{
+		Platform:   "amdgpu",
+		DevicePath: "/sys/devices/pci0000:00/0000:00:03.0/0000:02:00.0",
+		Index:      1,
+	}, result.Config.Fans[0].HwMon)
+}
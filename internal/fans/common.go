@@ -3,10 +3,12 @@ package fans
 import (
 	"fmt"
 	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/simulation"
 	"github.com/markusressel/fan2go/internal/ui"
 	"github.com/markusressel/fan2go/internal/util"
 	"os"
 	"sort"
+	"sync"
 )
 
 const (
@@ -20,11 +22,39 @@ const (
 
 var (
 	FanMap = map[string]Fan{}
+	// guards FanMap, which is replaced when the configuration is reloaded
+	fanMapMu sync.RWMutex
 )
 
+// GetFan returns the fan with the given id
+func GetFan(id string) (Fan, bool) {
+	fanMapMu.RLock()
+	defer fanMapMu.RUnlock()
+	fan, ok := FanMap[id]
+	return fan, ok
+}
+
+// GetFans returns all fans, the returned map must not be modified
+func GetFans() map[string]Fan {
+	fanMapMu.RLock()
+	defer fanMapMu.RUnlock()
+	return FanMap
+}
+
+// SetFans replaces all fans
+func SetFans(fanMap map[string]Fan) {
+	fanMapMu.Lock()
+	defer fanMapMu.Unlock()
+	FanMap = fanMap
+}
+
 type Fan interface {
 	GetId() string
 
+	GetConfig() configuration.FanConfig
+	// SetConfig replaces the configuration of this fan, without changing the device it is bound to
+	SetConfig(config configuration.FanConfig)
+
 	// GetStartPwm returns the min PWM at which the fan starts to rotate from a stand still
 	GetStartPwm() int
 	SetStartPwm(pwm int)
@@ -88,6 +118,10 @@ func NewFan(config configuration.FanConfig) (Fan, error) {
 		}, nil
 	}
 
+	if config.Simulated != nil {
+		return NewSimulatedFan(config, simulation.DefaultModel), nil
+	}
+
 	return nil, fmt.Errorf("no matching fan type for fan: %s", config.ID)
 }
 
@@ -102,13 +136,16 @@ func (fan *HwMonFan) AttachFanCurveData(curveData *map[int]float64) (err error)
 	}
 
 	interpolatedCurve := util.InterpolateLinearly(curveData, 0, 255)
+	fan.mu.Lock()
 	fan.FanCurveData = &interpolatedCurve
+	fan.mu.Unlock()
 
 	startPwm, maxPwm := ComputePwmBoundaries(fan)
 	fan.SetStartPwm(startPwm)
 	fan.SetMaxPwm(maxPwm)
 
-	// TODO: we don't have a way to determine this yet
+	// the min PWM is measured separately (see persistence.FanPwmThresholds),
+	// so we fall back to the start PWM until it is known
 	fan.SetMinPwm(startPwm)
 
 	return err
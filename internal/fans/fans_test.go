@@ -1 +1,60 @@
 package fans
+
+import (
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/util"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// helper function to create a hwmon device with the PWM outputs pwm1 and pwm3,
+// returns the path of the device
+func createPwmEnableTestDevice(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pwm1":        "100",
+		"pwm1_enable": "2",
+		"pwm3":        "150",
+		"pwm3_enable": "5",
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestHwMonFanPwmEnabledSelectedByChannel(t *testing.T) {
+	// GIVEN
+	dir := createPwmEnableTestDevice(t)
+	fan, err := NewFan(configuration.FanConfig{
+		ID: "channel_fan",
+		HwMon: &configuration.HwMonFanConfig{
+			Chip:      "nct6798",
+			Channel:   3,
+			PwmOutput: filepath.Join(dir, "pwm3"),
+		},
+	})
+	assert.NoError(t, err)
+
+	// WHEN
+	pwmEnabled, err := fan.GetPwmEnabled()
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, 5, pwmEnabled)
+
+	// WHEN
+	err = fan.SetPwmEnabled(1)
+
+	// THEN
+	assert.NoError(t, err)
+	value, _ := util.ReadIntFromFile(filepath.Join(dir, "pwm3_enable"))
+	assert.Equal(t, 1, value)
+	value, _ = util.ReadIntFromFile(filepath.Join(dir, "pwm1_enable"))
+	assert.Equal(t, 2, value)
+}
@@ -7,6 +7,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 type FileFan struct {
@@ -15,13 +16,28 @@ type FileFan struct {
 	FilePath  string
 	Config    configuration.FanConfig
 	MovingAvg float64
+
+	// guards the configuration, which may be replaced (see SetConfig) while the fan is in use
+	mu sync.RWMutex
 }
 
-func (fan FileFan) GetId() string {
+func (fan *FileFan) GetId() string {
 	return fan.ID
 }
 
-func (fan FileFan) GetStartPwm() int {
+func (fan *FileFan) GetConfig() configuration.FanConfig {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.Config
+}
+
+func (fan *FileFan) SetConfig(config configuration.FanConfig) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.Config = config
+}
+
+func (fan *FileFan) GetStartPwm() int {
 	return 1
 }
 
@@ -29,7 +45,7 @@ func (fan *FileFan) SetStartPwm(pwm int) {
 	return
 }
 
-func (fan FileFan) GetMinPwm() int {
+func (fan *FileFan) GetMinPwm() int {
 	return MinPwmValue
 }
 
@@ -38,7 +54,7 @@ func (fan *FileFan) SetMinPwm(pwm int) {
 	return
 }
 
-func (fan FileFan) GetMaxPwm() int {
+func (fan *FileFan) GetMaxPwm() int {
 	return MaxPwmValue
 }
 
@@ -47,11 +63,11 @@ func (fan *FileFan) SetMaxPwm(pwm int) {
 	return
 }
 
-func (fan FileFan) GetRpm() int {
+func (fan *FileFan) GetRpm() int {
 	return 0
 }
 
-func (fan FileFan) GetRpmAvg() float64 {
+func (fan *FileFan) GetRpmAvg() float64 {
 	return 0
 }
 
@@ -60,7 +76,7 @@ func (fan *FileFan) SetRpmAvg(rpm float64) {
 	return
 }
 
-func (fan FileFan) GetPwm() (result int) {
+func (fan *FileFan) GetPwm() (result int) {
 	filePath := fan.FilePath
 	// resolve home dir path
 	if strings.HasPrefix(filePath, "~") {
@@ -101,7 +117,7 @@ func (fan *FileFan) SetPwm(pwm int) (err error) {
 
 var interpolated = util.InterpolateLinearly(&map[int]float64{0: 0, 255: 255}, 0, 255)
 
-func (fan FileFan) GetFanCurveData() *map[int]float64 {
+func (fan *FileFan) GetFanCurveData() *map[int]float64 {
 	return &interpolated
 }
 
@@ -110,15 +126,15 @@ func (fan *FileFan) AttachFanCurveData(curveData *map[int]float64) (err error) {
 	return
 }
 
-func (fan FileFan) GetCurveId() string {
-	return fan.Config.Curve
+func (fan *FileFan) GetCurveId() string {
+	return fan.GetConfig().Curve
 }
 
-func (fan FileFan) ShouldNeverStop() bool {
-	return fan.Config.NeverStop
+func (fan *FileFan) ShouldNeverStop() bool {
+	return fan.GetConfig().NeverStop
 }
 
-func (fan FileFan) GetPwmEnabled() (int, error) {
+func (fan *FileFan) GetPwmEnabled() (int, error) {
 	return 1, nil
 }
 
@@ -127,11 +143,11 @@ func (fan *FileFan) SetPwmEnabled(value int) (err error) {
 	return nil
 }
 
-func (fan FileFan) IsPwmAuto() (bool, error) {
+func (fan *FileFan) IsPwmAuto() (bool, error) {
 	return true, nil
 }
 
-func (fan FileFan) Supports(feature int) bool {
+func (fan *FileFan) Supports(feature int) bool {
 	switch feature {
 	case FeatureRpmSensor:
 		// TODO: maybe we could support this in the future
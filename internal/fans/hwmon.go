@@ -6,7 +6,7 @@ import (
 	"github.com/markusressel/fan2go/internal/configuration"
 	"github.com/markusressel/fan2go/internal/ui"
 	"github.com/markusressel/fan2go/internal/util"
-	"path/filepath"
+	"sync"
 )
 
 type HwMonFan struct {
@@ -15,18 +15,55 @@ type HwMonFan struct {
 	RpmInput     string                  `json:"rpminput"`
 	RpmMovingAvg float64                 `json:"rpmmovingavg"`
 	PwmOutput    string                  `json:"pwmoutput"`
+	PwmEnabled   int                     `json:"pwmenabled"` // pwm_enable value at the time the device was detected, -1 if not available
+	MinRpm       int                     `json:"minrpm"`     // RPM limits of the tachometer (fanN_min, fanN_max), -1 if not available
+	MaxRpm       int                     `json:"maxrpm"`
 	Config       configuration.FanConfig `json:"config"`
 	StartPwm     *int                    `json:"startpwm"` // the min PWM at which the fan starts to rotate from a stand still
 	MinPwm       int                     `json:"minpwm"`   // lowest PWM value where the fans are still spinning, when spinning previously
 	MaxPwm       int                     `json:"maxpwm"`   // highest PWM value that yields an RPM increase
 	FanCurveData *map[int]float64        `json:"fancurvedata"`
+
+	// guards all fields, since the paths may be rebound (see SetPaths)
+	// while the fan is in use
+	mu sync.RWMutex
+}
+
+func (fan *HwMonFan) GetId() string {
+	return fan.GetConfig().ID
+}
+
+func (fan *HwMonFan) GetConfig() configuration.FanConfig {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.Config
+}
+
+func (fan *HwMonFan) SetConfig(config configuration.FanConfig) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.Config = config
 }
 
-func (fan HwMonFan) GetId() string {
-	return fan.Config.ID
+// GetPaths returns the PWM output and RPM input of this fan
+func (fan *HwMonFan) GetPaths() (pwmOutput string, rpmInput string) {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.PwmOutput, fan.RpmInput
 }
 
-func (fan HwMonFan) GetStartPwm() int {
+// SetPaths binds this fan to the given PWM output and RPM input,
+// f.ex. when its device has been renumbered
+func (fan *HwMonFan) SetPaths(pwmOutput string, rpmInput string) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.PwmOutput = pwmOutput
+	fan.RpmInput = rpmInput
+}
+
+func (fan *HwMonFan) GetStartPwm() int {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
 	if fan.StartPwm != nil {
 		return *fan.StartPwm
 	} else {
@@ -35,52 +72,59 @@ func (fan HwMonFan) GetStartPwm() int {
 }
 
 func (fan *HwMonFan) SetStartPwm(pwm int) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
 	fan.StartPwm = &pwm
 }
 
-func (fan HwMonFan) GetMinPwm() int {
-	// if the fan is never supposed to stop,
-	// use the lowest pwm value where the fan is still spinning
-	if fan.ShouldNeverStop() {
-		if len(fan.RpmInput) <= 0 {
-			ui.Warning("WARN: cannot guarantee neverStop option on fan %s, since it has no RPM input.", fan.GetId())
-		}
-		return fan.MinPwm
-	}
-
-	return MinPwmValue
+func (fan *HwMonFan) GetMinPwm() int {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.MinPwm
 }
 
 func (fan *HwMonFan) SetMinPwm(pwm int) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
 	fan.MinPwm = pwm
 }
 
-func (fan HwMonFan) GetMaxPwm() int {
+func (fan *HwMonFan) GetMaxPwm() int {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
 	return fan.MaxPwm
 }
 
 func (fan *HwMonFan) SetMaxPwm(pwm int) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
 	fan.MaxPwm = pwm
 }
 
-func (fan HwMonFan) GetRpm() int {
-	value, err := util.ReadIntFromFile(fan.RpmInput)
+func (fan *HwMonFan) GetRpm() int {
+	_, rpmInput := fan.GetPaths()
+	value, err := util.ReadIntFromFile(rpmInput)
 	if err != nil {
 		value = -1
 	}
 	return value
 }
 
-func (fan HwMonFan) GetRpmAvg() float64 {
+func (fan *HwMonFan) GetRpmAvg() float64 {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
 	return fan.RpmMovingAvg
 }
 
 func (fan *HwMonFan) SetRpmAvg(rpm float64) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
 	fan.RpmMovingAvg = rpm
 }
 
-func (fan HwMonFan) GetPwm() int {
-	value, err := util.ReadIntFromFile(fan.PwmOutput)
+func (fan *HwMonFan) GetPwm() int {
+	pwmOutput, _ := fan.GetPaths()
+	value, err := util.ReadIntFromFile(pwmOutput)
 	if err != nil {
 		value = MinPwmValue
 	}
@@ -89,29 +133,37 @@ func (fan HwMonFan) GetPwm() int {
 
 func (fan *HwMonFan) SetPwm(pwm int) (err error) {
 	ui.Debug("Setting Fan PWM of '%s' to %d ...", fan.GetId(), util.Round(pwm))
-	err = util.WriteIntToFile(util.Round(pwm), fan.PwmOutput)
+	pwmOutput, _ := fan.GetPaths()
+	err = util.WriteIntToFile(util.Round(pwm), pwmOutput)
 	return err
 }
 
-func (fan HwMonFan) GetFanCurveData() *map[int]float64 {
+func (fan *HwMonFan) GetFanCurveData() *map[int]float64 {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
 	return fan.FanCurveData
 }
 
-func (fan HwMonFan) GetCurveId() string {
-	return fan.Config.Curve
+func (fan *HwMonFan) GetCurveId() string {
+	return fan.GetConfig().Curve
 }
 
-func (fan HwMonFan) ShouldNeverStop() bool {
-	return fan.Config.NeverStop
+func (fan *HwMonFan) ShouldNeverStop() bool {
+	return fan.GetConfig().NeverStop
 }
 
-func (fan HwMonFan) GetPwmEnabled() (int, error) {
-	folder, _ := filepath.Split(fan.PwmOutput)
-	pwmEnabledFilePath := fmt.Sprintf("%spwm%d_enable", folder, fan.Index)
-	return util.ReadIntFromFile(pwmEnabledFilePath)
+func (fan *HwMonFan) GetPwmEnabled() (int, error) {
+	return util.ReadIntFromFile(fan.getPwmEnabledPath())
 }
 
-func (fan HwMonFan) IsPwmAuto() (bool, error) {
+// returns the path of the pwm_enable file belonging to the PWM output of this fan,
+// f.ex. "/sys/class/hwmon/hwmon4/pwm1_enable" for the output "/sys/class/hwmon/hwmon4/pwm1"
+func (fan *HwMonFan) getPwmEnabledPath() string {
+	pwmOutput, _ := fan.GetPaths()
+	return pwmOutput + "_enable"
+}
+
+func (fan *HwMonFan) IsPwmAuto() (bool, error) {
 	value, err := fan.GetPwmEnabled()
 	if err != nil {
 		return false, err
@@ -125,10 +177,7 @@ func (fan HwMonFan) IsPwmAuto() (bool, error) {
 // 1 - manual pwm control
 // 2 - motherboard pwm control
 func (fan *HwMonFan) SetPwmEnabled(value int) (err error) {
-	folder, _ := filepath.Split(fan.PwmOutput)
-	pwmEnabledFilePath := fmt.Sprintf("%s/pwm%d_enable", folder, fan.Index)
-
-	// /hwmon4/pwm1_enable
+	pwmEnabledFilePath := fan.getPwmEnabledPath()
 
 	err = util.WriteIntToFile(value, pwmEnabledFilePath)
 	if err == nil {
@@ -140,10 +189,11 @@ func (fan *HwMonFan) SetPwmEnabled(value int) (err error) {
 	return err
 }
 
-func (fan HwMonFan) Supports(feature int) bool {
+func (fan *HwMonFan) Supports(feature int) bool {
 	switch feature {
 	case FeatureRpmSensor:
-		return len(fan.RpmInput) > 0
+		_, rpmInput := fan.GetPaths()
+		return len(rpmInput) > 0
 	}
 	return false
 }
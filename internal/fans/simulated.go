@@ -0,0 +1,189 @@
+package fans
+
+import (
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/simulation"
+	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/markusressel/fan2go/internal/util"
+	"os"
+	"sync"
+)
+
+// SimulatedFan is a fan backed by a simulation.Model instead of real hardware
+type SimulatedFan struct {
+	Model        *simulation.Model       `json:"-"`
+	RpmMovingAvg float64                 `json:"rpmmovingavg"`
+	PwmEnabled   int                     `json:"pwmenabled"`
+	Config       configuration.FanConfig `json:"config"`
+	StartPwm     *int                    `json:"startpwm"` // the min PWM at which the fan starts to rotate from a stand still
+	MinPwm       int                     `json:"minpwm"`   // lowest PWM value where the fans are still spinning, when spinning previously
+	MaxPwm       int                     `json:"maxpwm"`   // highest PWM value that yields an RPM increase
+	FanCurveData *map[int]float64        `json:"fancurvedata"`
+
+	// guards all fields, since the configuration may be replaced (see SetConfig)
+	// while the fan is in use
+	mu sync.RWMutex
+}
+
+// NewSimulatedFan adds a fan with the given configuration to the given model
+func NewSimulatedFan(config configuration.FanConfig, model *simulation.Model) *SimulatedFan {
+	model.AddFan(config.ID, simulation.FanParams{
+		MaxRpm:   float64(config.Simulated.MaxRpm),
+		StartPwm: config.Simulated.StartPwm,
+		StopPwm:  config.Simulated.StopPwm,
+		Inertia:  config.Simulated.Inertia,
+		Noise:    config.Simulated.Noise,
+	})
+
+	return &SimulatedFan{
+		Model:      model,
+		PwmEnabled: 2,
+		Config:     config,
+		StartPwm:   config.StartPwm,
+		MinPwm:     MinPwmValue,
+		MaxPwm:     MaxPwmValue,
+	}
+}
+
+func (fan *SimulatedFan) GetId() string {
+	return fan.GetConfig().ID
+}
+
+func (fan *SimulatedFan) GetConfig() configuration.FanConfig {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.Config
+}
+
+func (fan *SimulatedFan) SetConfig(config configuration.FanConfig) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.Config = config
+}
+
+func (fan *SimulatedFan) GetStartPwm() int {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	if fan.StartPwm != nil {
+		return *fan.StartPwm
+	} else {
+		return MaxPwmValue
+	}
+}
+
+func (fan *SimulatedFan) SetStartPwm(pwm int) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.StartPwm = &pwm
+}
+
+func (fan *SimulatedFan) GetMinPwm() int {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.MinPwm
+}
+
+func (fan *SimulatedFan) SetMinPwm(pwm int) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.MinPwm = pwm
+}
+
+func (fan *SimulatedFan) GetMaxPwm() int {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.MaxPwm
+}
+
+func (fan *SimulatedFan) SetMaxPwm(pwm int) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.MaxPwm = pwm
+}
+
+func (fan *SimulatedFan) GetRpm() int {
+	return int(fan.Model.GetRpm(fan.GetId()))
+}
+
+func (fan *SimulatedFan) GetRpmAvg() float64 {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.RpmMovingAvg
+}
+
+func (fan *SimulatedFan) SetRpmAvg(rpm float64) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.RpmMovingAvg = rpm
+}
+
+func (fan *SimulatedFan) GetPwm() int {
+	return fan.Model.GetPwm(fan.GetId())
+}
+
+func (fan *SimulatedFan) SetPwm(pwm int) (err error) {
+	ui.Debug("Setting Fan PWM of '%s' to %d ...", fan.GetId(), util.Round(pwm))
+	fan.Model.SetPwm(fan.GetId(), util.Round(pwm))
+	return nil
+}
+
+func (fan *SimulatedFan) GetFanCurveData() *map[int]float64 {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.FanCurveData
+}
+
+// AttachFanCurveData attaches fan curve data from persistence to a fan,
+// see HwMonFan.AttachFanCurveData
+func (fan *SimulatedFan) AttachFanCurveData(curveData *map[int]float64) (err error) {
+	if curveData == nil || len(*curveData) <= 0 {
+		ui.Error("Cant attach empty fan curve data to fan %s", fan.GetId())
+		return os.ErrInvalid
+	}
+
+	interpolatedCurve := util.InterpolateLinearly(curveData, 0, 255)
+	fan.mu.Lock()
+	fan.FanCurveData = &interpolatedCurve
+	fan.mu.Unlock()
+
+	startPwm, maxPwm := ComputePwmBoundaries(fan)
+	fan.SetStartPwm(startPwm)
+	fan.SetMaxPwm(maxPwm)
+	fan.SetMinPwm(startPwm)
+
+	return err
+}
+
+func (fan *SimulatedFan) GetCurveId() string {
+	return fan.GetConfig().Curve
+}
+
+func (fan *SimulatedFan) ShouldNeverStop() bool {
+	return fan.GetConfig().NeverStop
+}
+
+func (fan *SimulatedFan) GetPwmEnabled() (int, error) {
+	fan.mu.RLock()
+	defer fan.mu.RUnlock()
+	return fan.PwmEnabled, nil
+}
+
+func (fan *SimulatedFan) SetPwmEnabled(value int) (err error) {
+	fan.mu.Lock()
+	defer fan.mu.Unlock()
+	fan.PwmEnabled = value
+	return nil
+}
+
+func (fan *SimulatedFan) IsPwmAuto() (bool, error) {
+	pwmEnabled, err := fan.GetPwmEnabled()
+	return pwmEnabled > 1, err
+}
+
+func (fan *SimulatedFan) Supports(feature int) bool {
+	switch feature {
+	case FeatureRpmSensor:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"github.com/markusressel/fan2go/internal/curves"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/ui"
+	"time"
+)
+
+// HistorySampleRate is the rate at which values are sampled, before they are averaged and recorded
+const HistorySampleRate = 1 * time.Second
+
+// historyRecorder periodically samples the values of all sensors, curves and fans
+// and records their average over each interval to the history
+type historyRecorder struct {
+	history    *persistence.History
+	sampleRate time.Duration
+	interval   time.Duration
+	isFanBound func(fanId string) bool
+
+	// start of the interval currently being sampled
+	intervalStart time.Time
+	sums          map[string]float64
+	counts        map[string]int
+}
+
+func newHistoryRecorder(
+	history *persistence.History,
+	sampleRate time.Duration,
+	interval time.Duration,
+	isFanBound func(fanId string) bool,
+) *historyRecorder {
+	return &historyRecorder{
+		history:    history,
+		sampleRate: sampleRate,
+		interval:   interval,
+		isFanBound: isFanBound,
+		sums:       map[string]float64{},
+		counts:     map[string]int{},
+	}
+}
+
+func (r *historyRecorder) Run(ctx context.Context) error {
+	err := r.history.Prune()
+	if err != nil {
+		ui.Warning("Unable to prune history: %v", err)
+	}
+
+	tick := time.NewTicker(r.sampleRate)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// keep the values of the current, incomplete interval
+			r.flush()
+			return nil
+		case now := <-tick.C:
+			intervalStart := now.Truncate(r.interval)
+			if !intervalStart.Equal(r.intervalStart) {
+				r.flush()
+				r.intervalStart = intervalStart
+			}
+			r.sample()
+		}
+	}
+}
+
+func (r *historyRecorder) add(key string, value float64) {
+	r.sums[key] += value
+	r.counts[key]++
+}
+
+func (r *historyRecorder) sample() {
+	for id, sensor := range sensors.GetSensors() {
+		r.add(persistence.HistorySensorKey(id), sensor.GetMovingAvg())
+	}
+	for id, curve := range curves.GetSpeedCurves() {
+		value, err := curve.CurrentValue()
+		if err != nil {
+			continue
+		}
+		r.add(persistence.HistoryCurveKey(id), float64(value))
+	}
+	for id, fan := range fans.GetFans() {
+		if !r.isFanBound(id) {
+			// waiting for its device to appear
+			continue
+		}
+		r.add(persistence.HistoryFanPwmKey(id), float64(fan.GetPwm()))
+		if fan.Supports(fans.FeatureRpmSensor) {
+			r.add(persistence.HistoryFanRpmKey(id), fan.GetRpmAvg())
+		}
+	}
+}
+
+// records the averages of all values sampled since the last flush
+func (r *historyRecorder) flush() {
+	if len(r.sums) <= 0 {
+		return
+	}
+
+	values := map[string]float64{}
+	for key, sum := range r.sums {
+		values[key] = sum / float64(r.counts[key])
+	}
+	r.sums = map[string]float64{}
+	r.counts = map[string]int{}
+
+	err := r.history.Save(r.intervalStart, values)
+	if err != nil {
+		ui.Warning("Unable to record history: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/controller"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/hwmon"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/markusressel/fan2go/internal/util"
+	"sync"
+	"time"
+)
+
+// HotplugPollingRate is the rate at which hwmon devices are checked for changes
+const HotplugPollingRate = 2 * time.Second
+
+// hotplugMonitor binds hwmon fans and sensors to their device when it appears,
+// pauses them while it is missing and re-resolves their paths when it is renumbered.
+// Fans and sensors have to be registered using addFan and addSensor.
+type hotplugMonitor struct {
+	pollingRate    time.Duration
+	controllers    map[string]controller.FanController
+	sensorMonitors map[string]SensorMonitor
+
+	mu           sync.Mutex
+	boundFans    map[string]bool
+	boundSensors map[string]bool
+	// closed once the fan has been bound for the first time
+	fanAvailable map[string]chan struct{}
+	lastState    string
+}
+
+func newHotplugMonitor(pollingRate time.Duration) *hotplugMonitor {
+	m := &hotplugMonitor{
+		pollingRate:    pollingRate,
+		controllers:    map[string]controller.FanController{},
+		sensorMonitors: map[string]SensorMonitor{},
+		boundFans:      map[string]bool{},
+		boundSensors:   map[string]bool{},
+		fanAvailable:   map[string]chan struct{}{},
+	}
+
+	m.lastState, _ = hwmon.GetDeviceState(hwmon.GetClassPath(configuration.GetConfig().SysfsRoot))
+
+	return m
+}
+
+// addFan registers the controller of the given fan, the fan is available
+// right away, unless it is a hwmon fan whose device has not been found yet
+func (m *hotplugMonitor) addFan(config configuration.FanConfig, c controller.FanController) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.controllers[config.ID] = c
+	m.fanAvailable[config.ID] = make(chan struct{})
+	m.boundFans[config.ID] = false
+	if config.HwMon == nil || len(config.HwMon.PwmOutput) > 0 {
+		m.boundFans[config.ID] = true
+		close(m.fanAvailable[config.ID])
+	}
+
+	// the fan may depend on a sensor whose device is missing
+	for sensorId, bound := range m.boundSensors {
+		if !bound && util.ContainsString(getFansUsingSensor(sensorId), config.ID) {
+			c.EnterFailsafe(hotplugReason("sensor", sensorId), configuration.GetConfig().Watchdog.Action)
+		}
+	}
+}
+
+func (m *hotplugMonitor) removeFan(fanId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.controllers, fanId)
+	delete(m.boundFans, fanId)
+	delete(m.fanAvailable, fanId)
+}
+
+// addSensor registers the monitor of the given sensor, the monitor is paused
+// (and all affected fans are put into failsafe) while the device of a hwmon sensor is missing
+func (m *hotplugMonitor) addSensor(config configuration.SensorConfig, mon SensorMonitor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sensorMonitors[config.ID] = mon
+	if config.HwMon == nil || len(config.HwMon.TempInput) > 0 {
+		m.boundSensors[config.ID] = true
+		return
+	}
+	m.unbindSensor(config.ID)
+}
+
+func (m *hotplugMonitor) removeSensor(sensorId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.boundSensors[sensorId] {
+		for _, c := range m.controllers {
+			c.ExitFailsafe(hotplugReason("sensor", sensorId))
+		}
+	}
+	delete(m.sensorMonitors, sensorId)
+	delete(m.boundSensors, sensorId)
+}
+
+// WaitForFan blocks until the device of the given fan is available.
+// Returns false if the given context is done before.
+func (m *hotplugMonitor) WaitForFan(ctx context.Context, fanId string) bool {
+	m.mu.Lock()
+	available, ok := m.fanAvailable[fanId]
+	m.mu.Unlock()
+	if !ok {
+		return true
+	}
+
+	select {
+	case <-available:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// IsFanBound returns true if the device of the given fan is currently available
+func (m *hotplugMonitor) IsFanBound(fanId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.boundFans[fanId]
+}
+
+func (m *hotplugMonitor) Run(ctx context.Context) error {
+	tick := time.NewTicker(m.pollingRate)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+			state, err := hwmon.GetDeviceState(hwmon.GetClassPath(configuration.GetConfig().SysfsRoot))
+			if err != nil {
+				ui.Warning("Unable to read hwmon devices: %v", err)
+				continue
+			}
+			if state == m.lastState {
+				continue
+			}
+			m.lastState = state
+
+			ui.Info("Detected change of hwmon devices, updating fans and sensors...")
+			m.update(hwmon.GetChips())
+		}
+	}
+}
+
+// update binds, unbinds or rebinds all hwmon fans and sensors according to the given devices
+func (m *hotplugMonitor) update(controllers []*hwmon.HwMonController) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currentConfig := configuration.GetConfig()
+	for _, config := range currentConfig.Sensors {
+		if config.HwMon == nil {
+			continue
+		}
+		s, _ := sensors.GetSensor(config.ID)
+		sensor, ok := s.(*sensors.HwmonSensor)
+		if !ok {
+			continue
+		}
+
+		tempInput, err := resolveSensorHwMonPaths(config, controllers)
+		if err != nil && !errors.Is(err, hwmon.ErrDeviceNotFound) {
+			ui.Warning("Sensor %s: %v", config.ID, err)
+		}
+		found := err == nil
+		bound := m.boundSensors[config.ID]
+		switch {
+		case !found && bound:
+			ui.Warning("Device of sensor %s disappeared", config.ID)
+			m.unbindSensor(config.ID)
+		case found && (!bound || tempInput != sensor.GetInput()):
+			ui.Info("Binding sensor %s to %s", config.ID, tempInput)
+			if mon, ok := m.sensorMonitors[config.ID]; ok {
+				mon.SetPaused(true)
+			}
+			sensor.SetInput(tempInput)
+			m.bindSensor(config.ID)
+		}
+	}
+
+	for _, config := range currentConfig.Fans {
+		if config.HwMon == nil {
+			continue
+		}
+		f, _ := fans.GetFan(config.ID)
+		fan, ok := f.(*fans.HwMonFan)
+		if !ok {
+			continue
+		}
+		c, ok := m.controllers[config.ID]
+		if !ok {
+			continue
+		}
+
+		pwmOutput, rpmInput, err := resolveFanHwMonPaths(config, controllers)
+		if err != nil && !errors.Is(err, hwmon.ErrDeviceNotFound) {
+			ui.Warning("Fan %s: %v", config.ID, err)
+		}
+		found := err == nil
+		bound := m.boundFans[config.ID]
+		currentPwmOutput, currentRpmInput := fan.GetPaths()
+		switch {
+		case !found && bound:
+			ui.Warning("Device of fan %s disappeared", config.ID)
+			m.boundFans[config.ID] = false
+			c.Pause(hotplugReason("fan", config.ID))
+		case found && (!bound || pwmOutput != currentPwmOutput || rpmInput != currentRpmInput):
+			ui.Info("Binding fan %s to %s", config.ID, pwmOutput)
+			c.Pause(hotplugReason("fan", config.ID))
+			fan.SetPaths(pwmOutput, rpmInput)
+			m.boundFans[config.ID] = true
+
+			select {
+			case <-m.fanAvailable[config.ID]:
+				// the device may have been reset, so treat it like a resume
+				c.HandleResume()
+			default:
+				// the controller has not been started yet
+				close(m.fanAvailable[config.ID])
+			}
+			c.Unpause(hotplugReason("fan", config.ID))
+		}
+	}
+}
+
+// pauses the monitor of the given sensor and activates the failsafe of all affected fans
+func (m *hotplugMonitor) unbindSensor(sensorId string) {
+	m.boundSensors[sensorId] = false
+	if mon, ok := m.sensorMonitors[sensorId]; ok {
+		mon.SetPaused(true)
+	}
+	for _, fanId := range getFansUsingSensor(sensorId) {
+		if c, ok := m.controllers[fanId]; ok {
+			c.EnterFailsafe(hotplugReason("sensor", sensorId), configuration.GetConfig().Watchdog.Action)
+		}
+	}
+}
+
+func (m *hotplugMonitor) bindSensor(sensorId string) {
+	m.boundSensors[sensorId] = true
+	if mon, ok := m.sensorMonitors[sensorId]; ok {
+		mon.SetPaused(false)
+	}
+	for _, fanId := range getFansUsingSensor(sensorId) {
+		if c, ok := m.controllers[fanId]; ok {
+			c.ExitFailsafe(hotplugReason("sensor", sensorId))
+		}
+	}
+}
+
+func hotplugReason(kind string, id string) string {
+	return "hotplug/" + kind + "/" + id
+}
+
+// resolves the input path of the given hwmon sensor configuration
+func resolveSensorHwMonPaths(config configuration.SensorConfig, controllers []*hwmon.HwMonController) (tempInput string, err error) {
+	sensor, err := hwmon.ResolveSensor(hwmon.NewSensorSelector(*config.HwMon), controllers)
+	if err != nil {
+		return "", err
+	}
+	return sensor.Input, nil
+}
+
+// resolves the output and input paths of the given hwmon fan configuration
+func resolveFanHwMonPaths(config configuration.FanConfig, controllers []*hwmon.HwMonController) (pwmOutput string, rpmInput string, err error) {
+	fan, err := hwmon.ResolveFan(hwmon.NewFanSelector(*config.HwMon), controllers)
+	if err != nil {
+		return "", "", err
+	}
+	return fan.PwmOutput, fan.RpmInput, nil
+}
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"context"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/hwmon"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type hotplugMockController struct {
+	mockController
+	paused   map[string]bool
+	failsafe map[string]bool
+	resumed  int
+}
+
+func (c *hotplugMockController) Pause(reason string) {
+	c.paused[reason] = true
+}
+
+func (c *hotplugMockController) Unpause(reason string) {
+	delete(c.paused, reason)
+}
+
+func (c *hotplugMockController) EnterFailsafe(reason string, action string) {
+	c.failsafe[reason] = true
+}
+
+func (c *hotplugMockController) ExitFailsafe(reason string) {
+	delete(c.failsafe, reason)
+}
+
+func (c *hotplugMockController) HandleResume() {
+	c.resumed++
+}
+
+type hotplugMockMonitor struct {
+	mockMonitor
+	paused bool
+}
+
+func (m *hotplugMockMonitor) SetPaused(paused bool) {
+	m.paused = paused
+}
+
+// helper function to create a device, as it would be found in /sys/class/hwmon/hwmon<number>
+func createHotplugTestDevice(number string) []*hwmon.HwMonController {
+	path := "/sys/class/hwmon/hwmon" + number
+	return []*hwmon.HwMonController{
+		{
+			Name:       "nct6798-isa-656",
+			ChipName:   "nct6798",
+			Platform:   "nct6775.656",
+			Path:       path,
+			DevicePath: "/sys/devices/platform/nct6775.656",
+			Fans: []*fans.HwMonFan{
+				{Index: 1, PwmOutput: path + "/pwm1", RpmInput: path + "/fan1_input"},
+			},
+			Sensors: []*sensors.HwmonSensor{
+				{Index: 1, Input: path + "/temp1_input"},
+			},
+		},
+	}
+}
+
+// helper function to create a hotplug monitor with a fan and a sensor whose device has not been found yet
+func createHotplugTestMonitor() (*hotplugMonitor, *hotplugMockController, *hotplugMockMonitor) {
+	sensorConfig := configuration.SensorConfig{
+		ID:    "hotplug_sensor",
+		HwMon: &configuration.HwMonSensorConfig{Chip: "nct6798", Index: 1},
+	}
+	fanConfig := configuration.FanConfig{
+		ID:    "hotplug_fan",
+		Curve: "hotplug_curve",
+		HwMon: &configuration.HwMonFanConfig{Chip: "nct6798", Index: 1},
+	}
+	configuration.CurrentConfig = configuration.Configuration{
+		Sensors: []configuration.SensorConfig{sensorConfig},
+		Curves: []configuration.CurveConfig{
+			{
+				ID:     "hotplug_curve",
+				Linear: &configuration.LinearCurveConfig{Sensor: sensorConfig.ID, Min: 40, Max: 80},
+			},
+		},
+		Fans: []configuration.FanConfig{fanConfig},
+	}
+	sensors.SensorMap = map[string]sensors.Sensor{
+		sensorConfig.ID: &sensors.HwmonSensor{Config: sensorConfig},
+	}
+	fans.FanMap = map[string]fans.Fan{
+		fanConfig.ID: &fans.HwMonFan{Config: fanConfig},
+	}
+
+	c := &hotplugMockController{
+		mockController: mockController{id: fanConfig.ID},
+		paused:         map[string]bool{},
+		failsafe:       map[string]bool{},
+	}
+	mon := &hotplugMockMonitor{}
+
+	m := newHotplugMonitor(time.Second)
+	m.addFan(fanConfig, c)
+	m.addSensor(sensorConfig, mon)
+	return m, c, mon
+}
+
+func TestHotplugBind(t *testing.T) {
+	// GIVEN
+	m, c, mon := createHotplugTestMonitor()
+	fan := fans.FanMap["hotplug_fan"].(*fans.HwMonFan)
+	sensor := sensors.SensorMap["hotplug_sensor"].(*sensors.HwmonSensor)
+	assert.False(t, m.IsFanBound("hotplug_fan"))
+	assert.True(t, mon.paused)
+	assert.Contains(t, c.failsafe, hotplugReason("sensor", "hotplug_sensor"))
+
+	// WHEN
+	m.update(createHotplugTestDevice("2"))
+
+	// THEN
+	assert.True(t, m.IsFanBound("hotplug_fan"))
+	assert.True(t, m.WaitForFan(context.Background(), "hotplug_fan"))
+	pwmOutput, rpmInput := fan.GetPaths()
+	assert.Equal(t, "/sys/class/hwmon/hwmon2/pwm1", pwmOutput)
+	assert.Equal(t, "/sys/class/hwmon/hwmon2/fan1_input", rpmInput)
+	assert.Equal(t, "/sys/class/hwmon/hwmon2/temp1_input", sensor.GetInput())
+	assert.False(t, mon.paused)
+	assert.Empty(t, c.failsafe)
+	assert.Empty(t, c.paused)
+	// the controller has not been started yet
+	assert.Equal(t, 0, c.resumed)
+}
+
+func TestHotplugUnbind(t *testing.T) {
+	// GIVEN
+	m, c, mon := createHotplugTestMonitor()
+	m.update(createHotplugTestDevice("2"))
+
+	// WHEN
+	m.update(nil)
+
+	// THEN
+	assert.False(t, m.IsFanBound("hotplug_fan"))
+	assert.Contains(t, c.paused, hotplugReason("fan", "hotplug_fan"))
+	assert.True(t, mon.paused)
+	assert.Contains(t, c.failsafe, hotplugReason("sensor", "hotplug_sensor"))
+}
+
+func TestHotplugRebind(t *testing.T) {
+	// GIVEN
+	m, c, mon := createHotplugTestMonitor()
+	fan := fans.FanMap["hotplug_fan"].(*fans.HwMonFan)
+	sensor := sensors.SensorMap["hotplug_sensor"].(*sensors.HwmonSensor)
+	m.update(createHotplugTestDevice("2"))
+
+	// WHEN
+	// the device has been renumbered
+	m.update(createHotplugTestDevice("3"))
+
+	// THEN
+	assert.True(t, m.IsFanBound("hotplug_fan"))
+	pwmOutput, rpmInput := fan.GetPaths()
+	assert.Equal(t, "/sys/class/hwmon/hwmon3/pwm1", pwmOutput)
+	assert.Equal(t, "/sys/class/hwmon/hwmon3/fan1_input", rpmInput)
+	assert.Equal(t, "/sys/class/hwmon/hwmon3/temp1_input", sensor.GetInput())
+	assert.False(t, mon.paused)
+	assert.Empty(t, c.failsafe)
+	assert.Empty(t, c.paused)
+	// the device may have been reset
+	assert.Equal(t, 1, c.resumed)
+}
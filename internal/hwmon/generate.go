@@ -0,0 +1,208 @@
+package hwmon
+
+import (
+	"fmt"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/fans"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// temperature range of the generated curves
+	generatedCurveMinTemp = 40
+	generatedCurveMaxTemp = 80
+)
+
+// chips providing the temperature of the CPU, preferred as input of the generated curves
+var cpuChipNames = []string{"coretemp", "k10temp", "zenpower", "cpu_thermal"}
+
+var invalidIdCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// GenerateConfig creates a starter configuration for the given devices, with a sensor for each
+// temperature input and a fan, using a linear curve, for each controllable PWM output.
+// If chips are given, only the devices with a matching chip name or identifier are used.
+// The returned comments describe the generated entries.
+func GenerateConfig(controllers []*HwMonController, chips []string) (configuration.Configuration, configuration.Comments) {
+	var config configuration.Configuration
+	comments := configuration.Comments{}
+
+	var selected []*HwMonController
+	for _, controller := range controllers {
+		if len(controller.Fans) <= 0 && len(controller.Sensors) <= 0 {
+			continue
+		}
+		if len(chips) > 0 && !matchesAnyChip(controller, chips) {
+			continue
+		}
+		selected = append(selected, controller)
+	}
+
+	// the sensor used by the curves of each device
+	curveSensors := map[*HwMonController]string{}
+	var cpuSensor, anySensor string
+
+	for i, controller := range selected {
+		chip, devicePath := getStableSelector(controller, controllers)
+		prefix := getIdPrefix(controller, chip, devicePath, i)
+
+		for _, sensor := range controller.Sensors {
+			channel := GetChannel(sensor.Input)
+			index := 0
+			if channel <= 0 {
+				index = sensor.Index
+			}
+			id := fmt.Sprintf("%s_temp%d", prefix, channel+index)
+			_, file := filepath.Split(sensor.Input)
+			comments[fmt.Sprintf("sensors[%d]", len(config.Sensors))] = []string{fmt.Sprintf("%s (%s)", sensor.Label, file)}
+			config.Sensors = append(config.Sensors, configuration.SensorConfig{
+				ID: id,
+				HwMon: &configuration.HwMonSensorConfig{
+					Chip:       chip,
+					DevicePath: devicePath,
+					Index:      index,
+					Channel:    channel,
+				},
+			})
+
+			if _, ok := curveSensors[controller]; !ok {
+				curveSensors[controller] = id
+			}
+			if len(anySensor) <= 0 {
+				anySensor = id
+			}
+			if len(cpuSensor) <= 0 && isCpuChip(controller) {
+				cpuSensor = id
+			}
+		}
+	}
+
+	for i, controller := range selected {
+		chip, devicePath := getStableSelector(controller, controllers)
+		prefix := getIdPrefix(controller, chip, devicePath, i)
+
+		// prefer the CPU temperature, then a sensor of the same device
+		sensorId := cpuSensor
+		if len(sensorId) <= 0 {
+			sensorId = curveSensors[controller]
+		}
+		if len(sensorId) <= 0 {
+			sensorId = anySensor
+		}
+
+		for _, fan := range controller.Fans {
+			channel := GetChannel(fan.PwmOutput)
+			index := 0
+			if channel <= 0 {
+				index = fan.Index
+			}
+			_, file := filepath.Split(fan.PwmOutput)
+			if !isPwmWritable(fan) {
+				comments[""] = append(comments[""], fmt.Sprintf("skipped the read-only PWM output %s of %s", file, controller.Name))
+				continue
+			}
+			if len(sensorId) <= 0 {
+				// a fan cannot be configured without a curve
+				comments[""] = append(comments[""], fmt.Sprintf("skipped the PWM output %s of %s, no sensor is available for its curve", file, controller.Name))
+				continue
+			}
+
+			fanId := fmt.Sprintf("%s_pwm%d", prefix, channel+index)
+			curveId := fanId + "_curve"
+
+			comments[fmt.Sprintf("curves[%d]", len(config.Curves))] = []string{
+				fmt.Sprintf("curve of fan %s, adjust the sensor and temperatures to your needs", fanId),
+			}
+			config.Curves = append(config.Curves, configuration.CurveConfig{
+				ID: curveId,
+				Linear: &configuration.LinearCurveConfig{
+					Sensor: sensorId,
+					Min:    generatedCurveMinTemp,
+					Max:    generatedCurveMaxTemp,
+				},
+			})
+
+			fanComments := []string{fmt.Sprintf("%s (%s)", fan.Label, file)}
+			if !fan.Supports(fans.FeatureRpmSensor) {
+				fanComments = append(fanComments, "no RPM sensor available, the fan curve cannot be measured")
+			}
+			comments[fmt.Sprintf("fans[%d]", len(config.Fans))] = fanComments
+			config.Fans = append(config.Fans, configuration.FanConfig{
+				ID:        fanId,
+				NeverStop: true,
+				Curve:     curveId,
+				HwMon: &configuration.HwMonFanConfig{
+					Chip:       chip,
+					DevicePath: devicePath,
+					Index:      index,
+					Channel:    channel,
+				},
+			})
+		}
+	}
+
+	return config, comments
+}
+
+func matchesAnyChip(controller *HwMonController, chips []string) bool {
+	for _, chip := range chips {
+		if strings.EqualFold(chip, controller.ChipName) || strings.EqualFold(chip, controller.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCpuChip(controller *HwMonController) bool {
+	for _, name := range cpuChipNames {
+		if strings.EqualFold(name, controller.ChipName) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the chip name or identifier selecting only the given device, independent of the enumeration order.
+// If neither is unique, the device path is returned as well.
+func getStableSelector(controller *HwMonController, controllers []*HwMonController) (chip string, devicePath string) {
+	for _, candidate := range []string{controller.ChipName, controller.Name} {
+		if len(candidate) <= 0 {
+			continue
+		}
+		matches := 0
+		for _, other := range controllers {
+			if strings.EqualFold(candidate, other.ChipName) || strings.EqualFold(candidate, other.Name) {
+				matches++
+			}
+		}
+		if matches == 1 {
+			return candidate, ""
+		}
+	}
+	return controller.ChipName, controller.DevicePath
+}
+
+// returns the prefix of the IDs of all sensors and fans of the given device
+func getIdPrefix(controller *HwMonController, chip string, devicePath string, index int) string {
+	name := chip
+	if len(name) <= 0 {
+		name = controller.Name
+	}
+	prefix := invalidIdCharsRegex.ReplaceAllString(name, "_")
+	if len(devicePath) > 0 {
+		// the chip name is not unique
+		prefix = fmt.Sprintf("%s_%d", prefix, index+1)
+	}
+	return prefix
+}
+
+// returns true if the PWM output of the given fan can be written to
+func isPwmWritable(fan *fans.HwMonFan) bool {
+	info, err := os.Stat(fan.PwmOutput)
+	if err != nil {
+		return false
+	}
+	return info.Mode().Perm()&0222 != 0
+}
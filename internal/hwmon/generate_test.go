@@ -0,0 +1,77 @@
+package hwmon
+
+import (
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateConfig(t *testing.T) {
+	// GIVEN
+	root := createSysfsTree(t)
+	chipPath := filepath.Join(GetClassPath(root), "hwmon2")
+	assert.NoError(t, os.Chmod(filepath.Join(chipPath, "pwm2"), 0444))
+	controllers := GetSysfsChips(root)
+
+	// WHEN
+	config, comments := GenerateConfig(controllers, nil)
+
+	// THEN
+	assert.Equal(t, []configuration.SensorConfig{
+		{ID: "nct6798_temp1", HwMon: &configuration.HwMonSensorConfig{Chip: "nct6798", Channel: 1}},
+	}, config.Sensors)
+	assert.Equal(t, []configuration.CurveConfig{
+		{ID: "nct6798_pwm1_curve", Linear: &configuration.LinearCurveConfig{Sensor: "nct6798_temp1", Min: 40, Max: 80}},
+	}, config.Curves)
+	assert.Equal(t, []configuration.FanConfig{
+		{
+			ID:        "nct6798_pwm1",
+			NeverStop: true,
+			Curve:     "nct6798_pwm1_curve",
+			HwMon:     &configuration.HwMonFanConfig{Chip: "nct6798", Channel: 1},
+		},
+	}, config.Fans)
+
+	assert.Equal(t, []string{"SYSTIN (temp1_input)"}, comments["sensors[0]"])
+	assert.Equal(t, []string{"CPU Fan (pwm1)"}, comments["fans[0]"])
+	assert.Equal(t, []string{"skipped the read-only PWM output pwm2 of nct6798-isa-0"}, comments[""])
+
+	config.HwMonBackend = configuration.HwMonBackendSysfs
+	assert.Empty(t, configuration.Validate(&config))
+}
+
+func TestGenerateConfigWithoutSensors(t *testing.T) {
+	// GIVEN
+	root := createSysfsTree(t)
+	chipPath := filepath.Join(GetClassPath(root), "hwmon2")
+	assert.NoError(t, os.Remove(filepath.Join(chipPath, "temp1_input")))
+	assert.NoError(t, os.Chmod(filepath.Join(chipPath, "pwm2"), 0444))
+	controllers := GetSysfsChips(root)
+
+	// WHEN
+	config, comments := GenerateConfig(controllers, nil)
+
+	// THEN
+	assert.Empty(t, config.Sensors)
+	assert.Empty(t, config.Curves)
+	assert.Empty(t, config.Fans)
+	assert.Equal(t, []string{
+		"skipped the PWM output pwm1 of nct6798-isa-0, no sensor is available for its curve",
+		"skipped the read-only PWM output pwm2 of nct6798-isa-0",
+	}, comments[""])
+}
+
+func TestGenerateConfigWithChipFilter(t *testing.T) {
+	// GIVEN
+	root := createSysfsTree(t)
+	controllers := GetSysfsChips(root)
+
+	// WHEN
+	config, _ := GenerateConfig(controllers, []string{"coretemp"})
+
+	// THEN
+	assert.Empty(t, config.Sensors)
+	assert.Empty(t, config.Fans)
+}
@@ -1,15 +1,15 @@
 package hwmon
 
 import (
-	"errors"
-	"fmt"
+	"github.com/markusressel/fan2go/internal/configuration"
 	"github.com/markusressel/fan2go/internal/fans"
 	"github.com/markusressel/fan2go/internal/sensors"
-	"github.com/md14454/gosensors"
+	"github.com/markusressel/fan2go/internal/ui"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -21,55 +21,59 @@ const (
 	BusTypeHid     = 6
 )
 
+// GetClassPath returns the directory containing all hwmon devices, below the given sysfs root
+func GetClassPath(sysfsRoot string) string {
+	return filepath.Join(sysfsRoot, "class", "hwmon")
+}
+
 type HwMonController struct {
 	Name     string
 	DType    string
 	Modalias string
 	Platform string
 	Path     string
+	// ChipName is the content of the name file of the device, e.g. "nct6798"
+	ChipName string
+	// DevicePath is the resolved sysfs path of the underlying device
+	DevicePath string
 
 	Fans    []*fans.HwMonFan
 	Sensors []*sensors.HwmonSensor
 }
 
+// GetChips detects all devices using the configured backend
 func GetChips() []*HwMonController {
-	gosensors.Init()
-	defer gosensors.Cleanup()
-	chips := gosensors.GetDetectedChips()
-
-	var list []*HwMonController
-
-	for i := 0; i < len(chips); i++ {
-		chip := chips[i]
-
-		var identifier = computeIdentifier(chip)
-		dType := getDeviceType(chip.Path)
-		modalias := getDeviceModalias(chip.Path)
-		platform := findPlatform(chip.Path)
-		if len(platform) <= 0 {
-			platform = identifier
+	switch configuration.GetConfig().HwMonBackend {
+	case configuration.HwMonBackendLibSensors:
+		if !LibSensorsAvailable {
+			ui.Warning("fan2go was built without libsensors support, using the sysfs backend instead")
+			break
 		}
+		return getLibSensorsChips()
+	}
+	return GetSysfsChips(configuration.GetConfig().SysfsRoot)
+}
 
-		fansList := GetFans(chip)
-		sensorsList := GetTempSensors(chip)
-
-		if len(fansList) <= 0 && len(sensorsList) <= 0 {
-			continue
-		}
+// GetDeviceState returns a fingerprint of the hwmon devices present in the given directory,
+// which changes whenever a device appears, disappears or is renumbered
+func GetDeviceState(classPath string) (string, error) {
+	entries, err := ioutil.ReadDir(classPath)
+	if err != nil {
+		return "", err
+	}
 
-		c := &HwMonController{
-			Name:     identifier,
-			DType:    dType,
-			Modalias: modalias,
-			Platform: platform,
-			Path:     chip.Path,
-			Fans:     fansList,
-			Sensors:  sensorsList,
+	var devices []string
+	for _, entry := range entries {
+		// entries are symlinks to the actual device
+		target, err := os.Readlink(filepath.Join(classPath, entry.Name()))
+		if err != nil {
+			target = entry.Name()
 		}
-		list = append(list, c)
+		devices = append(devices, entry.Name()+"="+target)
 	}
+	sort.Strings(devices)
 
-	return list
+	return strings.Join(devices, "\n"), nil
 }
 
 // getDeviceName read the name of a device
@@ -94,142 +98,6 @@ func getDeviceType(devicePath string) string {
 	return strings.TrimSpace(string(content))
 }
 
-func GetTempSensors(chip gosensors.Chip) []*sensors.HwmonSensor {
-	var sensorList []*sensors.HwmonSensor
-
-	features := chip.GetFeatures()
-	for j := 0; j < len(features); j++ {
-		feature := features[j]
-
-		if feature.Type != gosensors.FeatureTypeTemp {
-			continue
-		}
-
-		subfeatures := feature.GetSubFeatures()
-
-		if containsSubFeature(subfeatures, gosensors.SubFeatureTypeTempInput) {
-			inputSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeTempInput)
-			sensorInputPath := fmt.Sprintf("%s/%s", chip.Path, inputSubFeature.Name)
-
-			max := -1
-			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeTempMax) {
-				maxSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeTempMax)
-				max = int(maxSubFeature.GetValue())
-			}
-
-			min := -1
-			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeTempMin) {
-				minSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeTempMin)
-				min = int(minSubFeature.GetValue())
-			}
-
-			label := getLabel(chip.Path, inputSubFeature.Name)
-
-			sensorList = append(
-				sensorList,
-				&sensors.HwmonSensor{
-					Label:     label,
-					Index:     len(sensorList) + 1,
-					Input:     sensorInputPath,
-					Max:       max,
-					Min:       min,
-					MovingAvg: inputSubFeature.GetValue(),
-				})
-		}
-	}
-
-	return sensorList
-}
-
-func GetFans(chip gosensors.Chip) []*fans.HwMonFan {
-	var fanList []*fans.HwMonFan
-
-	features := chip.GetFeatures()
-	for j := 0; j < len(features); j++ {
-		feature := features[j]
-
-		if feature.Type != gosensors.FeatureTypeFan {
-			continue
-		}
-
-		subfeatures := feature.GetSubFeatures()
-
-		if containsSubFeature(subfeatures, gosensors.SubFeatureTypeFanInput) {
-			pwmOutput := fmt.Sprintf("%s/pwm%d", chip.Path, len(fanList)+1)
-
-			if _, err := os.Stat(pwmOutput); err == nil {
-			} else if errors.Is(err, os.ErrNotExist) {
-				// path/to/whatever does *not* exist
-				pwmOutput = ""
-			} else {
-				pwmOutput = ""
-			}
-
-			rpmInput := ""
-			rpmAverage := 0.0
-			inputSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeFanInput)
-			if inputSubFeature != nil {
-				rpmInput = fmt.Sprintf("%s/%s", chip.Path, inputSubFeature.Name)
-				rpmAverage = inputSubFeature.GetValue()
-			}
-
-			max := -1
-			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeFanMax) {
-				maxSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeFanMax)
-				max = int(maxSubFeature.GetValue())
-			} else {
-				max = fans.MaxPwmValue
-			}
-
-			min := -1
-			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeFanMin) {
-				minSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeFanMin)
-				min = int(minSubFeature.GetValue())
-			} else {
-				min = fans.MinPwmValue
-			}
-
-			if len(pwmOutput) <= 0 {
-				continue
-			}
-
-			label := getLabel(chip.Path, inputSubFeature.Name)
-
-			fan := &fans.HwMonFan{
-				Label:        label,
-				Index:        len(fanList) + 1,
-				PwmOutput:    pwmOutput,
-				RpmInput:     rpmInput,
-				RpmMovingAvg: rpmAverage,
-				MinPwm:       min,
-				MaxPwm:       max,
-			}
-
-			fanList = append(fanList, fan)
-		}
-	}
-
-	return fanList
-}
-
-func getSubFeature(subfeatures []gosensors.SubFeature, input gosensors.SubFeatureType) *gosensors.SubFeature {
-	for _, a := range subfeatures {
-		if a.Type == input {
-			return &a
-		}
-	}
-	return nil
-}
-
-func containsSubFeature(s []gosensors.SubFeature, e gosensors.SubFeatureType) bool {
-	for _, a := range s {
-		if a.Type == e {
-			return true
-		}
-	}
-	return false
-}
-
 // getLabel read the label of a in/output of a device
 func getLabel(devicePath string, input string) string {
 	labelPath := strings.TrimSuffix(devicePath+"/"+input, "input") + "label"
@@ -242,35 +110,6 @@ func getLabel(devicePath string, input string) string {
 	return strings.TrimSpace(label)
 }
 
-func computeIdentifier(chip gosensors.Chip) (name string) {
-	name = chip.Prefix
-
-	devicePath := chip.Path
-	if len(name) <= 0 {
-		name = getDeviceName(devicePath)
-	}
-
-	if len(name) <= 0 {
-		_, name = filepath.Split(devicePath)
-	}
-
-	identifier := name
-	switch chip.Bus.Type {
-	case BusTypeIsa:
-		identifier = fmt.Sprintf("%s-isa-%d", identifier, chip.Bus.Nr)
-	case BusTypePci:
-		identifier = fmt.Sprintf("%s-pci-%d%x", identifier, chip.Bus.Nr, chip.Addr)
-	case BusTypeVirtual:
-		identifier = fmt.Sprintf("%s-virtual-%d", identifier, chip.Bus.Nr)
-	case BusTypeAcpi:
-		identifier = fmt.Sprintf("%s-acpi-%d", identifier, chip.Bus.Nr)
-	case BusTypeHid:
-		identifier = fmt.Sprintf("%s-hid-%d-%d", identifier, chip.Bus.Nr, chip.Addr)
-	}
-
-	return identifier
-}
-
 func findPlatform(devicePath string) string {
 	platformRegex := regexp.MustCompile(".*/platform/{}/.*")
 	return platformRegex.FindString(devicePath)
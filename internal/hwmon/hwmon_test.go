@@ -1,77 +1,39 @@
 package hwmon
 
 import (
-	"fmt"
-	"github.com/md14454/gosensors"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
-func TestComputeIdentifierIsa(t *testing.T) {
+func TestFindPlatform(t *testing.T) {
 	// GIVEN
-	c := gosensors.Chip{
-		Prefix: "ucsi_source_psy_USBC000:002",
-		Bus: gosensors.Bus{
-			Type: BusTypeIsa,
-			Nr:   1,
-		},
-		Path: "/sys/class/hwmon/hwmon7",
-	}
-	expected := fmt.Sprintf("%s-isa-%d", c.Prefix, c.Bus.Nr)
+	devicePath := "/sys/devices/pci0000:00/0000:00:0e.0/pci10000:e0/10000:e0:06.0/10000:e1:00.0/nvme/nvme0/hwmon3"
 
 	// WHEN
-	result := computeIdentifier(c)
+	platform := findPlatform(devicePath)
 
 	// THEN
-	assert.Equal(t, expected, result)
+	assert.Equal(t, "", platform)
 }
 
-func TestComputeIdentifierPci(t *testing.T) {
+func TestGetDeviceState(t *testing.T) {
 	// GIVEN
-	c := gosensors.Chip{
-		Prefix: "nvme",
-		Addr:   5,
-		Bus: gosensors.Bus{
-			Type: BusTypePci,
-			Nr:   1,
-		},
-		Path: "/sys/class/hwmon/hwmon4",
-	}
-	expected := fmt.Sprintf("%s-pci-%d%x", c.Prefix, c.Bus.Nr, c.Addr)
+	classPath := t.TempDir()
+	err := os.Symlink("../../devices/platform/nct6775.656/hwmon/hwmon2", filepath.Join(classPath, "hwmon2"))
+	assert.NoError(t, err)
 
-	// WHEN
-	result := computeIdentifier(c)
-
-	// THEN
-	assert.Equal(t, expected, result)
-}
-
-func TestComputeIdentifierAcpi(t *testing.T) {
-	// GIVEN
-	c := gosensors.Chip{
-		Prefix: "nvme",
-		Bus: gosensors.Bus{
-			Type: BusTypeAcpi,
-			Nr:   1,
-		},
-		Path: "/sys/class/hwmon/hwmon4",
-	}
-	expected := fmt.Sprintf("%s-acpi-%d", c.Prefix, c.Bus.Nr)
+	before, err := GetDeviceState(classPath)
+	assert.NoError(t, err)
 
 	// WHEN
-	result := computeIdentifier(c)
+	err = os.Symlink("../../devices/pci0000:00/0000:00:03.1/0000:09:00.0/hwmon/hwmon3", filepath.Join(classPath, "hwmon3"))
+	assert.NoError(t, err)
+	after, err := GetDeviceState(classPath)
+	assert.NoError(t, err)
 
 	// THEN
-	assert.Equal(t, expected, result)
-}
-
-func TestFindPlatform(t *testing.T) {
-	// GIVEN
-	devicePath := "/sys/devices/pci0000:00/0000:00:0e.0/pci10000:e0/10000:e0:06.0/10000:e1:00.0/nvme/nvme0/hwmon3"
-
-	// WHEN
-	platform := findPlatform(devicePath)
-
-	// THEN
-	assert.Equal(t, "", platform)
+	assert.NotEqual(t, before, after)
+	assert.Contains(t, after, "hwmon2=../../devices/platform/nct6775.656/hwmon/hwmon2")
 }
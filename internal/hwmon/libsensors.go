@@ -0,0 +1,237 @@
+//go:build !nolibsensors
+// +build !nolibsensors
+
+package hwmon
+
+import (
+	"errors"
+	"fmt"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/md14454/gosensors"
+	"os"
+	"path/filepath"
+)
+
+// LibSensorsAvailable indicates whether fan2go was built with libsensors support
+const LibSensorsAvailable = true
+
+// getLibSensorsChips detects all devices using libsensors
+func getLibSensorsChips() []*HwMonController {
+	gosensors.Init()
+	defer gosensors.Cleanup()
+	chips := gosensors.GetDetectedChips()
+
+	var list []*HwMonController
+
+	for i := 0; i < len(chips); i++ {
+		chip := chips[i]
+
+		var identifier = computeIdentifier(chip)
+		dType := getDeviceType(chip.Path)
+		modalias := getDeviceModalias(chip.Path)
+		platform := findPlatform(chip.Path)
+		if len(platform) <= 0 {
+			platform = identifier
+		}
+
+		fansList := GetFans(chip)
+		sensorsList := GetTempSensors(chip)
+
+		if len(fansList) <= 0 && len(sensorsList) <= 0 {
+			continue
+		}
+
+		devicePath, err := filepath.EvalSymlinks(chip.Path + "/device")
+		if err != nil {
+			devicePath = ""
+		}
+
+		c := &HwMonController{
+			Name:       identifier,
+			DType:      dType,
+			Modalias:   modalias,
+			Platform:   platform,
+			Path:       chip.Path,
+			ChipName:   getDeviceName(chip.Path),
+			DevicePath: devicePath,
+			Fans:       fansList,
+			Sensors:    sensorsList,
+		}
+		list = append(list, c)
+	}
+
+	return list
+}
+
+func GetTempSensors(chip gosensors.Chip) []*sensors.HwmonSensor {
+	var sensorList []*sensors.HwmonSensor
+
+	features := chip.GetFeatures()
+	for j := 0; j < len(features); j++ {
+		feature := features[j]
+
+		if feature.Type != gosensors.FeatureTypeTemp {
+			continue
+		}
+
+		subfeatures := feature.GetSubFeatures()
+
+		if containsSubFeature(subfeatures, gosensors.SubFeatureTypeTempInput) {
+			inputSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeTempInput)
+			sensorInputPath := fmt.Sprintf("%s/%s", chip.Path, inputSubFeature.Name)
+
+			max := -1
+			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeTempMax) {
+				maxSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeTempMax)
+				max = int(maxSubFeature.GetValue())
+			}
+
+			min := -1
+			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeTempMin) {
+				minSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeTempMin)
+				min = int(minSubFeature.GetValue())
+			}
+
+			crit := -1
+			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeTempCrit) {
+				critSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeTempCrit)
+				crit = int(critSubFeature.GetValue())
+			}
+
+			label := getLabel(chip.Path, inputSubFeature.Name)
+
+			sensorList = append(
+				sensorList,
+				&sensors.HwmonSensor{
+					Label:     label,
+					Index:     len(sensorList) + 1,
+					Input:     sensorInputPath,
+					Max:       max,
+					Min:       min,
+					Crit:      crit,
+					MovingAvg: inputSubFeature.GetValue(),
+				})
+		}
+	}
+
+	return sensorList
+}
+
+func GetFans(chip gosensors.Chip) []*fans.HwMonFan {
+	var fanList []*fans.HwMonFan
+
+	features := chip.GetFeatures()
+	for j := 0; j < len(features); j++ {
+		feature := features[j]
+
+		if feature.Type != gosensors.FeatureTypeFan {
+			continue
+		}
+
+		subfeatures := feature.GetSubFeatures()
+
+		if containsSubFeature(subfeatures, gosensors.SubFeatureTypeFanInput) {
+			pwmOutput := fmt.Sprintf("%s/pwm%d", chip.Path, len(fanList)+1)
+
+			if _, err := os.Stat(pwmOutput); err == nil {
+			} else if errors.Is(err, os.ErrNotExist) {
+				// path/to/whatever does *not* exist
+				pwmOutput = ""
+			} else {
+				pwmOutput = ""
+			}
+
+			rpmInput := ""
+			rpmAverage := 0.0
+			inputSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeFanInput)
+			if inputSubFeature != nil {
+				rpmInput = fmt.Sprintf("%s/%s", chip.Path, inputSubFeature.Name)
+				rpmAverage = inputSubFeature.GetValue()
+			}
+
+			// the limits of the tachometer in RPM, unrelated to the PWM range
+			maxRpm := -1
+			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeFanMax) {
+				maxSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeFanMax)
+				maxRpm = int(maxSubFeature.GetValue())
+			}
+
+			minRpm := -1
+			if containsSubFeature(subfeatures, gosensors.SubFeatureTypeFanMin) {
+				minSubFeature := getSubFeature(subfeatures, gosensors.SubFeatureTypeFanMin)
+				minRpm = int(minSubFeature.GetValue())
+			}
+
+			if len(pwmOutput) <= 0 {
+				continue
+			}
+
+			label := getLabel(chip.Path, inputSubFeature.Name)
+
+			fan := &fans.HwMonFan{
+				Label:        label,
+				Index:        len(fanList) + 1,
+				PwmOutput:    pwmOutput,
+				RpmInput:     rpmInput,
+				RpmMovingAvg: rpmAverage,
+				PwmEnabled:   readPwmEnabled(pwmOutput),
+				MinRpm:       minRpm,
+				MaxRpm:       maxRpm,
+				MinPwm:       fans.MinPwmValue,
+				MaxPwm:       fans.MaxPwmValue,
+			}
+
+			fanList = append(fanList, fan)
+		}
+	}
+
+	return fanList
+}
+
+func getSubFeature(subfeatures []gosensors.SubFeature, input gosensors.SubFeatureType) *gosensors.SubFeature {
+	for _, a := range subfeatures {
+		if a.Type == input {
+			return &a
+		}
+	}
+	return nil
+}
+
+func containsSubFeature(s []gosensors.SubFeature, e gosensors.SubFeatureType) bool {
+	for _, a := range s {
+		if a.Type == e {
+			return true
+		}
+	}
+	return false
+}
+
+func computeIdentifier(chip gosensors.Chip) (name string) {
+	name = chip.Prefix
+
+	devicePath := chip.Path
+	if len(name) <= 0 {
+		name = getDeviceName(devicePath)
+	}
+
+	if len(name) <= 0 {
+		_, name = filepath.Split(devicePath)
+	}
+
+	identifier := name
+	switch chip.Bus.Type {
+	case BusTypeIsa:
+		identifier = fmt.Sprintf("%s-isa-%d", identifier, chip.Bus.Nr)
+	case BusTypePci:
+		identifier = fmt.Sprintf("%s-pci-%d%x", identifier, chip.Bus.Nr, chip.Addr)
+	case BusTypeVirtual:
+		identifier = fmt.Sprintf("%s-virtual-%d", identifier, chip.Bus.Nr)
+	case BusTypeAcpi:
+		identifier = fmt.Sprintf("%s-acpi-%d", identifier, chip.Bus.Nr)
+	case BusTypeHid:
+		identifier = fmt.Sprintf("%s-hid-%d-%d", identifier, chip.Bus.Nr, chip.Addr)
+	}
+
+	return identifier
+}
@@ -0,0 +1,11 @@
+//go:build nolibsensors
+// +build nolibsensors
+
+package hwmon
+
+// LibSensorsAvailable indicates whether fan2go was built with libsensors support
+const LibSensorsAvailable = false
+
+func getLibSensorsChips() []*HwMonController {
+	return nil
+}
@@ -0,0 +1,69 @@
+//go:build !nolibsensors
+// +build !nolibsensors
+
+package hwmon
+
+import (
+	"fmt"
+	"github.com/md14454/gosensors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestComputeIdentifierIsa(t *testing.T) {
+	// GIVEN
+	c := gosensors.Chip{
+		Prefix: "ucsi_source_psy_USBC000:002",
+		Bus: gosensors.Bus{
+			Type: BusTypeIsa,
+			Nr:   1,
+		},
+		Path: "/sys/class/hwmon/hwmon7",
+	}
+	expected := fmt.Sprintf("%s-isa-%d", c.Prefix, c.Bus.Nr)
+
+	// WHEN
+	result := computeIdentifier(c)
+
+	// THEN
+	assert.Equal(t, expected, result)
+}
+
+func TestComputeIdentifierPci(t *testing.T) {
+	// GIVEN
+	c := gosensors.Chip{
+		Prefix: "nvme",
+		Addr:   5,
+		Bus: gosensors.Bus{
+			Type: BusTypePci,
+			Nr:   1,
+		},
+		Path: "/sys/class/hwmon/hwmon4",
+	}
+	expected := fmt.Sprintf("%s-pci-%d%x", c.Prefix, c.Bus.Nr, c.Addr)
+
+	// WHEN
+	result := computeIdentifier(c)
+
+	// THEN
+	assert.Equal(t, expected, result)
+}
+
+func TestComputeIdentifierAcpi(t *testing.T) {
+	// GIVEN
+	c := gosensors.Chip{
+		Prefix: "nvme",
+		Bus: gosensors.Bus{
+			Type: BusTypeAcpi,
+			Nr:   1,
+		},
+		Path: "/sys/class/hwmon/hwmon4",
+	}
+	expected := fmt.Sprintf("%s-acpi-%d", c.Prefix, c.Bus.Nr)
+
+	// WHEN
+	result := computeIdentifier(c)
+
+	// THEN
+	assert.Equal(t, expected, result)
+}
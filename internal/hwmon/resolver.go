@@ -0,0 +1,211 @@
+package hwmon
+
+import (
+	"errors"
+	"fmt"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrDeviceNotFound  = errors.New("no matching hwmon device found")
+	ErrDeviceAmbiguous = errors.New("multiple matching hwmon devices found")
+)
+
+var channelRegex = regexp.MustCompile(`^[a-z]+(\d+)`)
+
+// DeviceSelector holds the criteria used to identify a hwmon device and one of its channels.
+// Empty criteria are ignored.
+type DeviceSelector struct {
+	// regex matched against the platform of the device
+	Platform string
+	// name of the chip, e.g. "nct6798", or the identifier printed by "fan2go detect"
+	Chip       string
+	Modalias   string
+	PciAddress string
+	DevicePath string
+
+	// 1-based index of the channel within the device, as printed by "fan2go detect"
+	Index int
+	// number of the channel, e.g. 2 for pwm2 or temp2_input
+	Channel int
+	// label of the channel, e.g. the content of temp2_label
+	Label string
+}
+
+func NewFanSelector(config configuration.HwMonFanConfig) DeviceSelector {
+	return DeviceSelector{
+		Platform:   config.Platform,
+		Chip:       config.Chip,
+		Modalias:   config.Modalias,
+		PciAddress: config.PciAddress,
+		DevicePath: config.DevicePath,
+		Index:      config.Index,
+		Channel:    config.Channel,
+		Label:      config.Label,
+	}
+}
+
+func NewSensorSelector(config configuration.HwMonSensorConfig) DeviceSelector {
+	return DeviceSelector{
+		Platform:   config.Platform,
+		Chip:       config.Chip,
+		Modalias:   config.Modalias,
+		PciAddress: config.PciAddress,
+		DevicePath: config.DevicePath,
+		Index:      config.Index,
+		Channel:    config.Channel,
+		Label:      config.Label,
+	}
+}
+
+// ResolveFan returns the single fan matching the given selector
+func ResolveFan(selector DeviceSelector, controllers []*HwMonController) (*fans.HwMonFan, error) {
+	var matches []*fans.HwMonFan
+	var devices []string
+	for _, c := range controllers {
+		matched, err := selector.matchesDevice(c)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		for _, fan := range c.Fans {
+			if selector.matchesChannel(fan.Index, fan.PwmOutput, fan.Label) {
+				matches = append(matches, fan)
+				devices = append(devices, c.Name)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w for %s", ErrDeviceNotFound, selector)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%w for %s: %s", ErrDeviceAmbiguous, selector, strings.Join(devices, ", "))
+	}
+}
+
+// ResolveSensor returns the single sensor matching the given selector
+func ResolveSensor(selector DeviceSelector, controllers []*HwMonController) (*sensors.HwmonSensor, error) {
+	var matches []*sensors.HwmonSensor
+	var devices []string
+	for _, c := range controllers {
+		matched, err := selector.matchesDevice(c)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		for _, sensor := range c.Sensors {
+			if selector.matchesChannel(sensor.Index, sensor.Input, sensor.Label) {
+				matches = append(matches, sensor)
+				devices = append(devices, c.Name)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w for %s", ErrDeviceNotFound, selector)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%w for %s: %s", ErrDeviceAmbiguous, selector, strings.Join(devices, ", "))
+	}
+}
+
+func (s DeviceSelector) matchesDevice(c *HwMonController) (bool, error) {
+	if len(s.Platform) > 0 {
+		matched, err := regexp.MatchString("(?i)"+s.Platform, c.Platform)
+		if err != nil {
+			return false, fmt.Errorf("invalid platform regex '%s': %v", s.Platform, err)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	if len(s.Chip) > 0 && !strings.EqualFold(s.Chip, c.ChipName) && !strings.EqualFold(s.Chip, c.Name) {
+		return false, nil
+	}
+	if len(s.Modalias) > 0 && s.Modalias != c.Modalias {
+		return false, nil
+	}
+	if len(s.PciAddress) > 0 && !containsPathElement(c.DevicePath, s.PciAddress) {
+		return false, nil
+	}
+	if len(s.DevicePath) > 0 {
+		devicePath := filepath.Clean(s.DevicePath)
+		if c.DevicePath != devicePath && !strings.HasPrefix(c.DevicePath, devicePath+"/") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (s DeviceSelector) matchesChannel(index int, path string, label string) bool {
+	if s.Index > 0 && s.Index != index {
+		return false
+	}
+	if s.Channel > 0 && s.Channel != GetChannel(path) {
+		return false
+	}
+	if len(s.Label) > 0 && !strings.EqualFold(s.Label, label) {
+		return false
+	}
+	return true
+}
+
+// String returns a human readable representation of all given criteria
+func (s DeviceSelector) String() string {
+	var criteria []string
+	add := func(name string, value string) {
+		if len(value) > 0 {
+			criteria = append(criteria, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	add("platform", s.Platform)
+	add("chip", s.Chip)
+	add("modalias", s.Modalias)
+	add("pciAddress", s.PciAddress)
+	add("devicePath", s.DevicePath)
+	if s.Index > 0 {
+		add("index", strconv.Itoa(s.Index))
+	}
+	if s.Channel > 0 {
+		add("channel", strconv.Itoa(s.Channel))
+	}
+	add("label", s.Label)
+	return strings.Join(criteria, " ")
+}
+
+// GetChannel returns the channel number of the given sysfs file, e.g. 2 for ".../pwm2" or ".../temp2_input"
+func GetChannel(path string) int {
+	match := channelRegex.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return -1
+	}
+	channel, err := strconv.Atoi(match[1])
+	if err != nil {
+		return -1
+	}
+	return channel
+}
+
+func containsPathElement(path string, element string) bool {
+	for _, e := range strings.Split(path, "/") {
+		if e == element {
+			return true
+		}
+	}
+	return false
+}
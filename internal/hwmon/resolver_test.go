@@ -0,0 +1,133 @@
+package hwmon
+
+import (
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// helper function to create the devices used in the resolver tests
+func createResolverTestControllers() []*HwMonController {
+	return []*HwMonController{
+		{
+			Name:       "nct6798-isa-656",
+			ChipName:   "nct6798",
+			Platform:   "nct6775.656",
+			Path:       "/sys/class/hwmon/hwmon2",
+			DevicePath: "/sys/devices/platform/nct6775.656",
+			Modalias:   "platform:nct6775",
+			Fans: []*fans.HwMonFan{
+				{Index: 1, Label: "CPU Fan", PwmOutput: "/sys/class/hwmon/hwmon2/pwm1"},
+				{Index: 2, Label: "Pump Fan", PwmOutput: "/sys/class/hwmon/hwmon2/pwm3"},
+			},
+			Sensors: []*sensors.HwmonSensor{
+				{Index: 1, Label: "SYSTIN", Input: "/sys/class/hwmon/hwmon2/temp1_input"},
+				{Index: 2, Label: "CPUTIN", Input: "/sys/class/hwmon/hwmon2/temp2_input"},
+			},
+		},
+		{
+			Name:       "amdgpu-pci-0900",
+			ChipName:   "amdgpu",
+			Platform:   "amdgpu-pci-0900",
+			Path:       "/sys/class/hwmon/hwmon5",
+			DevicePath: "/sys/devices/pci0000:00/0000:00:03.1/0000:09:00.0",
+			Modalias:   "pci:v00001002d0000731Fsv00001DA2sd0000E410bc03sc00i00",
+			Fans: []*fans.HwMonFan{
+				{Index: 1, Label: "amdgpu", PwmOutput: "/sys/class/hwmon/hwmon5/pwm1"},
+			},
+			Sensors: []*sensors.HwmonSensor{
+				{Index: 1, Label: "edge", Input: "/sys/class/hwmon/hwmon5/temp1_input"},
+			},
+		},
+	}
+}
+
+func TestResolveFanByPlatformAndIndex(t *testing.T) {
+	// GIVEN
+	controllers := createResolverTestControllers()
+
+	// WHEN
+	fan, err := ResolveFan(DeviceSelector{Platform: "nct6775", Index: 2}, controllers)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, "/sys/class/hwmon/hwmon2/pwm3", fan.PwmOutput)
+}
+
+func TestResolveFanByChipAndChannel(t *testing.T) {
+	// GIVEN
+	controllers := createResolverTestControllers()
+
+	// WHEN
+	fan, err := ResolveFan(DeviceSelector{Chip: "nct6798", Channel: 3}, controllers)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, "Pump Fan", fan.Label)
+}
+
+func TestResolveFanByPciAddress(t *testing.T) {
+	// GIVEN
+	controllers := createResolverTestControllers()
+
+	// WHEN
+	fan, err := ResolveFan(DeviceSelector{PciAddress: "0000:09:00.0", Index: 1}, controllers)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, "/sys/class/hwmon/hwmon5/pwm1", fan.PwmOutput)
+}
+
+func TestResolveSensorByLabel(t *testing.T) {
+	// GIVEN
+	controllers := createResolverTestControllers()
+
+	// WHEN
+	sensor, err := ResolveSensor(DeviceSelector{DevicePath: "/sys/devices/platform/nct6775.656", Label: "cputin"}, controllers)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, "/sys/class/hwmon/hwmon2/temp2_input", sensor.Input)
+}
+
+func TestResolveSensorByModalias(t *testing.T) {
+	// GIVEN
+	controllers := createResolverTestControllers()
+
+	// WHEN
+	sensor, err := ResolveSensor(DeviceSelector{Modalias: "platform:nct6775", Channel: 1}, controllers)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, "SYSTIN", sensor.Label)
+}
+
+func TestResolveSensorAmbiguous(t *testing.T) {
+	// GIVEN
+	controllers := createResolverTestControllers()
+
+	// WHEN
+	_, err := ResolveSensor(DeviceSelector{Platform: ".*", Index: 1}, controllers)
+
+	// THEN
+	assert.ErrorIs(t, err, ErrDeviceAmbiguous)
+	assert.Contains(t, err.Error(), "nct6798-isa-656, amdgpu-pci-0900")
+}
+
+func TestResolveFanNotFound(t *testing.T) {
+	// GIVEN
+	controllers := createResolverTestControllers()
+
+	// WHEN
+	_, err := ResolveFan(DeviceSelector{Chip: "nct6798", Label: "Chassis Fan"}, controllers)
+
+	// THEN
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+}
+
+func TestGetChannel(t *testing.T) {
+	assert.Equal(t, 2, GetChannel("/sys/class/hwmon/hwmon2/pwm2"))
+	assert.Equal(t, 12, GetChannel("/sys/class/hwmon/hwmon2/temp12_input"))
+	assert.Equal(t, -1, GetChannel("/sys/class/hwmon/hwmon2/name"))
+}
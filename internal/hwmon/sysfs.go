@@ -0,0 +1,225 @@
+package hwmon
+
+import (
+	"fmt"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/markusressel/fan2go/internal/util"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	tempInputRegex  = regexp.MustCompile(`^temp(\d+)_input$`)
+	pwmRegex        = regexp.MustCompile(`^pwm(\d+)$`)
+	pciAddressRegex = regexp.MustCompile(`^([0-9a-f]{4}):([0-9a-f]{2}):([0-9a-f]{2})\.([0-7])$`)
+)
+
+// GetSysfsChips detects all devices by scanning the hwmon class directory below the given sysfs root
+func GetSysfsChips(sysfsRoot string) []*HwMonController {
+	classPath := GetClassPath(sysfsRoot)
+	entries, err := ioutil.ReadDir(classPath)
+	if err != nil {
+		ui.Warning("Unable to read hwmon devices in %s: %v", classPath, err)
+		return nil
+	}
+
+	var list []*HwMonController
+	for _, entry := range entries {
+		c := scanChip(filepath.Join(classPath, entry.Name()))
+		if c == nil {
+			continue
+		}
+		list = append(list, c)
+	}
+
+	return list
+}
+
+func scanChip(chipPath string) *HwMonController {
+	fanList := getSysfsFans(chipPath)
+	sensorList := getSysfsTempSensors(chipPath)
+	if len(fanList) <= 0 && len(sensorList) <= 0 {
+		return nil
+	}
+
+	name := getDeviceName(chipPath)
+	devicePath, err := filepath.EvalSymlinks(filepath.Join(chipPath, "device"))
+	if err != nil {
+		devicePath = ""
+	}
+
+	identifier := computeSysfsIdentifier(name, devicePath)
+	platform := findPlatform(chipPath)
+	if len(platform) <= 0 {
+		platform = identifier
+	}
+
+	return &HwMonController{
+		Name:       identifier,
+		DType:      getDeviceType(chipPath),
+		Modalias:   getDeviceModalias(chipPath),
+		Platform:   platform,
+		Path:       chipPath,
+		ChipName:   name,
+		DevicePath: devicePath,
+		Fans:       fanList,
+		Sensors:    sensorList,
+	}
+}
+
+func getSysfsTempSensors(chipPath string) []*sensors.HwmonSensor {
+	var sensorList []*sensors.HwmonSensor
+
+	for _, channel := range findChannels(chipPath, tempInputRegex) {
+		inputName := fmt.Sprintf("temp%d_input", channel)
+		input := filepath.Join(chipPath, inputName)
+
+		value, err := util.ReadIntFromFile(input)
+		if err != nil {
+			value = 0
+		}
+
+		sensorList = append(
+			sensorList,
+			&sensors.HwmonSensor{
+				Label:     getLabel(chipPath, inputName),
+				Index:     len(sensorList) + 1,
+				Input:     input,
+				Max:       readDegrees(filepath.Join(chipPath, fmt.Sprintf("temp%d_max", channel))),
+				Min:       readDegrees(filepath.Join(chipPath, fmt.Sprintf("temp%d_min", channel))),
+				Crit:      readDegrees(filepath.Join(chipPath, fmt.Sprintf("temp%d_crit", channel))),
+				MovingAvg: float64(value),
+			})
+	}
+
+	return sensorList
+}
+
+func getSysfsFans(chipPath string) []*fans.HwMonFan {
+	var fanList []*fans.HwMonFan
+
+	for _, channel := range findChannels(chipPath, pwmRegex) {
+		pwmOutput := filepath.Join(chipPath, fmt.Sprintf("pwm%d", channel))
+
+		// not every pwm output has a matching tachometer input
+		rpmInputName := fmt.Sprintf("fan%d_input", channel)
+		rpmInput := filepath.Join(chipPath, rpmInputName)
+		rpm, err := util.ReadIntFromFile(rpmInput)
+		if err != nil {
+			rpmInput = ""
+			rpm = 0
+		}
+
+		// same as the libsensors backend, which derives the pwm output from the index
+		fanList = append(fanList, &fans.HwMonFan{
+			Label:        getLabel(chipPath, rpmInputName),
+			Index:        channel,
+			PwmOutput:    pwmOutput,
+			RpmInput:     rpmInput,
+			RpmMovingAvg: float64(rpm),
+			PwmEnabled:   readPwmEnabled(pwmOutput),
+			MinRpm:       readIntOrDefault(filepath.Join(chipPath, fmt.Sprintf("fan%d_min", channel)), -1),
+			MaxRpm:       readIntOrDefault(filepath.Join(chipPath, fmt.Sprintf("fan%d_max", channel)), -1),
+			MinPwm:       fans.MinPwmValue,
+			MaxPwm:       fans.MaxPwmValue,
+		})
+	}
+
+	return fanList
+}
+
+// returns the sorted channel numbers of all files in the given directory matching the given regex
+func findChannels(chipPath string, regex *regexp.Regexp) []int {
+	entries, err := ioutil.ReadDir(chipPath)
+	if err != nil {
+		return nil
+	}
+
+	var channels []int
+	for _, entry := range entries {
+		match := regex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		channel, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		channels = append(channels, channel)
+	}
+	sort.Ints(channels)
+	return channels
+}
+
+// reads the pwm_enable value of the given pwm output, returns -1 if not available
+func readPwmEnabled(pwmOutput string) int {
+	return readIntOrDefault(pwmOutput+"_enable", -1)
+}
+
+// reads an integer value, returns the given default if not available
+func readIntOrDefault(path string, defaultValue int) int {
+	value, err := util.ReadIntFromFile(path)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// reads a temperature in milli-degree, returns it in degree or -1 if not available
+func readDegrees(path string) int {
+	value, err := util.ReadIntFromFile(path)
+	if err != nil {
+		return -1
+	}
+	return value / 1000
+}
+
+// computes an identifier in the same format as the libsensors backend, e.g. "nct6798-isa-0"
+func computeSysfsIdentifier(name string, devicePath string) string {
+	if len(devicePath) <= 0 {
+		return fmt.Sprintf("%s-virtual-%d", name, 0)
+	}
+
+	subsystem, err := filepath.EvalSymlinks(filepath.Join(devicePath, "subsystem"))
+	if err != nil {
+		subsystem = ""
+	}
+	deviceName := filepath.Base(devicePath)
+
+	switch filepath.Base(subsystem) {
+	case "platform":
+		return fmt.Sprintf("%s-isa-%d", name, 0)
+	case "acpi":
+		return fmt.Sprintf("%s-acpi-%d", name, 0)
+	case "hid":
+		// f.ex. "0003:1B1C:0C10.0001"
+		var nr, addr int
+		_, err := fmt.Sscanf(strings.Replace(deviceName, ".", ":", 1), "%x:%x:%x:%x", &nr, new(int), new(int), &addr)
+		if err == nil {
+			return fmt.Sprintf("%s-hid-%d-%d", name, nr, addr)
+		}
+	}
+
+	// the device itself, or one of its parents (e.g. for nvme drives) is a PCI device
+	elements := strings.Split(devicePath, "/")
+	for i := len(elements) - 1; i >= 0; i-- {
+		match := pciAddressRegex.FindStringSubmatch(elements[i])
+		if match == nil {
+			continue
+		}
+		domain, _ := strconv.ParseInt(match[1], 16, 32)
+		bus, _ := strconv.ParseInt(match[2], 16, 32)
+		device, _ := strconv.ParseInt(match[3], 16, 32)
+		function, _ := strconv.ParseInt(match[4], 16, 32)
+		addr := bus<<8 | device<<3 | function
+		return fmt.Sprintf("%s-pci-%d%x", name, domain, addr)
+	}
+
+	return name
+}
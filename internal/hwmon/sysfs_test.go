@@ -0,0 +1,157 @@
+package hwmon
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// helper function to create a file with the given content in a fake sysfs tree
+func writeSysfsFile(t *testing.T, path string, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(path, []byte(content+"\n"), 0644)
+	assert.NoError(t, err)
+}
+
+// helper function to create a fake sysfs tree with a single platform hwmon device
+func createSysfsTree(t *testing.T) (root string) {
+	root = t.TempDir()
+
+	busPath := filepath.Join(root, "bus", "platform")
+	devicePath := filepath.Join(root, "devices", "platform", "nct6775.656")
+	chipPath := filepath.Join(devicePath, "hwmon", "hwmon2")
+
+	assert.NoError(t, os.MkdirAll(busPath, 0755))
+	assert.NoError(t, os.MkdirAll(chipPath, 0755))
+	assert.NoError(t, os.Symlink(busPath, filepath.Join(devicePath, "subsystem")))
+	assert.NoError(t, os.Symlink(devicePath, filepath.Join(chipPath, "device")))
+
+	writeSysfsFile(t, filepath.Join(chipPath, "name"), "nct6798")
+	writeSysfsFile(t, filepath.Join(chipPath, "pwm1"), "128")
+	writeSysfsFile(t, filepath.Join(chipPath, "pwm1_enable"), "2")
+	writeSysfsFile(t, filepath.Join(chipPath, "fan1_input"), "1200")
+	writeSysfsFile(t, filepath.Join(chipPath, "fan1_label"), "CPU Fan")
+	writeSysfsFile(t, filepath.Join(chipPath, "pwm2"), "255")
+	writeSysfsFile(t, filepath.Join(chipPath, "temp1_input"), "45000")
+	writeSysfsFile(t, filepath.Join(chipPath, "temp1_label"), "SYSTIN")
+	writeSysfsFile(t, filepath.Join(chipPath, "temp1_max"), "80000")
+	writeSysfsFile(t, filepath.Join(chipPath, "temp1_crit"), "100000")
+
+	classPath := GetClassPath(root)
+	assert.NoError(t, os.MkdirAll(classPath, 0755))
+	assert.NoError(t, os.Symlink(chipPath, filepath.Join(classPath, "hwmon2")))
+
+	return root
+}
+
+func TestGetSysfsChips(t *testing.T) {
+	// GIVEN
+	root := createSysfsTree(t)
+	chipPath := filepath.Join(GetClassPath(root), "hwmon2")
+
+	// WHEN
+	result := GetSysfsChips(root)
+
+	// THEN
+	assert.Len(t, result, 1)
+	c := result[0]
+	assert.Equal(t, "nct6798-isa-0", c.Name)
+	assert.Equal(t, "nct6798", c.ChipName)
+	assert.Equal(t, "nct6798-isa-0", c.Platform)
+	assert.Equal(t, chipPath, c.Path)
+
+	assert.Len(t, c.Fans, 2)
+	assert.Equal(t, "CPU Fan", c.Fans[0].Label)
+	assert.Equal(t, filepath.Join(chipPath, "pwm1"), c.Fans[0].PwmOutput)
+	assert.Equal(t, filepath.Join(chipPath, "fan1_input"), c.Fans[0].RpmInput)
+	assert.Equal(t, 1200.0, c.Fans[0].RpmMovingAvg)
+	assert.Equal(t, filepath.Join(chipPath, "pwm2"), c.Fans[1].PwmOutput)
+	assert.Equal(t, "", c.Fans[1].RpmInput)
+
+	assert.Len(t, c.Sensors, 1)
+	assert.Equal(t, "SYSTIN", c.Sensors[0].Label)
+	assert.Equal(t, filepath.Join(chipPath, "temp1_input"), c.Sensors[0].Input)
+	assert.Equal(t, 80, c.Sensors[0].Max)
+	assert.Equal(t, -1, c.Sensors[0].Min)
+	assert.Equal(t, 100, c.Sensors[0].Crit)
+	assert.Equal(t, 45000.0, c.Sensors[0].MovingAvg)
+}
+
+func TestGetSysfsChipsFanChannels(t *testing.T) {
+	// GIVEN
+	root := createSysfsTree(t)
+	chipPath := filepath.Join(GetClassPath(root), "hwmon2")
+	// the device has no pwm3 output
+	writeSysfsFile(t, filepath.Join(chipPath, "pwm4"), "100")
+	writeSysfsFile(t, filepath.Join(chipPath, "pwm4_enable"), "5")
+	writeSysfsFile(t, filepath.Join(chipPath, "fan4_input"), "800")
+	writeSysfsFile(t, filepath.Join(chipPath, "fan4_min"), "300")
+	writeSysfsFile(t, filepath.Join(chipPath, "fan4_max"), "2000")
+
+	// WHEN
+	result := GetSysfsChips(root)
+
+	// THEN
+	assert.Len(t, result, 1)
+	c := result[0]
+	assert.Len(t, c.Fans, 3)
+
+	assert.Equal(t, 1, c.Fans[0].Index)
+	assert.Equal(t, 2, c.Fans[0].PwmEnabled)
+	assert.Equal(t, 0, c.Fans[0].MinPwm)
+	assert.Equal(t, 255, c.Fans[0].MaxPwm)
+	assert.Equal(t, -1, c.Fans[0].MinRpm)
+	assert.Equal(t, -1, c.Fans[0].MaxRpm)
+
+	assert.Equal(t, 2, c.Fans[1].Index)
+	assert.Equal(t, -1, c.Fans[1].PwmEnabled)
+
+	assert.Equal(t, 4, c.Fans[2].Index)
+	assert.Equal(t, filepath.Join(chipPath, "pwm4"), c.Fans[2].PwmOutput)
+	assert.Equal(t, filepath.Join(chipPath, "fan4_input"), c.Fans[2].RpmInput)
+	assert.Equal(t, 5, c.Fans[2].PwmEnabled)
+	assert.Equal(t, 300, c.Fans[2].MinRpm)
+	assert.Equal(t, 2000, c.Fans[2].MaxRpm)
+	// the RPM limits don't affect the PWM range
+	assert.Equal(t, 0, c.Fans[2].MinPwm)
+	assert.Equal(t, 255, c.Fans[2].MaxPwm)
+	enabled, err := c.Fans[2].GetPwmEnabled()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, enabled)
+}
+
+func TestGetSysfsChipsMissingRoot(t *testing.T) {
+	// GIVEN
+	root := filepath.Join(t.TempDir(), "missing")
+
+	// WHEN
+	result := GetSysfsChips(root)
+
+	// THEN
+	assert.Empty(t, result)
+}
+
+func TestComputeSysfsIdentifierPci(t *testing.T) {
+	// GIVEN
+	devicePath := "/sys/devices/pci0000:00/0000:00:03.1/0000:09:00.0"
+
+	// WHEN
+	result := computeSysfsIdentifier("amdgpu", devicePath)
+
+	// THEN
+	assert.Equal(t, "amdgpu-pci-0900", result)
+}
+
+func TestComputeSysfsIdentifierVirtual(t *testing.T) {
+	// GIVEN
+	devicePath := ""
+
+	// WHEN
+	result := computeSysfsIdentifier("acpitz", devicePath)
+
+	// THEN
+	assert.Equal(t, "acpitz-virtual-0", result)
+}
@@ -6,40 +6,94 @@ import (
 	"github.com/markusressel/fan2go/internal/sensors"
 	"github.com/markusressel/fan2go/internal/ui"
 	"github.com/markusressel/fan2go/internal/util"
+	"sync"
 	"time"
 )
 
 type SensorMonitor interface {
 	Run(ctx context.Context) error
+
+	// GetLastHeartbeat returns the time the sensor was last read successfully,
+	// or the zero time if the monitor has not been started yet
+	GetLastHeartbeat() time.Time
+	// SetPaused stops reading the sensor (e.g. while its device is missing), without missing any heartbeats.
+	// Waits for a running read to finish, so the sensor may be rebound afterwards.
+	SetPaused(paused bool)
 }
 
 type sensorMonitor struct {
 	sensor      sensors.Sensor
 	pollingRate time.Duration
+
+	lastHeartbeat time.Time
+	paused        bool
+	mu            sync.Mutex
+	// held while the sensor is read, so it can be rebound once SetPaused returns
+	readMu sync.Mutex
 }
 
 func NewSensorMonitor(sensor sensors.Sensor, pollingRate time.Duration) SensorMonitor {
-	return sensorMonitor{
+	return &sensorMonitor{
 		sensor:      sensor,
 		pollingRate: pollingRate,
 	}
 }
 
-func (s sensorMonitor) Run(ctx context.Context) error {
+func (s *sensorMonitor) GetLastHeartbeat() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastHeartbeat
+}
+
+func (s *sensorMonitor) heartbeat() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastHeartbeat = time.Now()
+}
+
+func (s *sensorMonitor) SetPaused(paused bool) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+}
+
+func (s *sensorMonitor) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+func (s *sensorMonitor) Run(ctx context.Context) error {
+	s.heartbeat()
 	tick := time.Tick(s.pollingRate)
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-tick:
-			err := updateSensor(s.sensor)
-			if err != nil {
-				ui.Warning("Error updating sensor: %v", err)
-			}
+			s.update()
 		}
 	}
 }
 
+// reads the sensor, unless the monitor is paused
+func (s *sensorMonitor) update() {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+	if s.isPaused() {
+		s.heartbeat()
+		return
+	}
+	err := updateSensor(s.sensor)
+	if err != nil {
+		ui.Warning("Error updating sensor: %v", err)
+	} else {
+		s.heartbeat()
+	}
+}
+
 // read the current value of a sensors and append it to the moving window
 func updateSensor(s sensors.Sensor) (err error) {
 	value, err := s.GetValue()
@@ -47,7 +101,7 @@ func updateSensor(s sensors.Sensor) (err error) {
 		return err
 	}
 
-	var n = configuration.CurrentConfig.TempRollingWindowSize
+	var n = configuration.GetConfig().TempRollingWindowSize
 	lastAvg := s.GetMovingAvg()
 	newAvg := util.UpdateSimpleMovingAvg(lastAvg, n, value)
 	s.SetMovingAvg(newAvg)
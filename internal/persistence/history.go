@@ -0,0 +1,207 @@
+package persistence
+
+import (
+	"encoding/binary"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	BucketHistory = "history"
+
+	// size of a single history entry: the time in unix nanoseconds followed by the value
+	historyEntrySize = 16
+)
+
+// HistoryPoint is a single (averaged) value of a history series
+type HistoryPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// HistorySensorKey returns the key of the series holding the moving average of a sensor
+func HistorySensorKey(sensorId string) string {
+	return "sensor/" + sensorId
+}
+
+// HistoryCurveKey returns the key of the series holding the value of a curve
+func HistoryCurveKey(curveId string) string {
+	return "curve/" + curveId
+}
+
+// HistoryFanPwmKey returns the key of the series holding the PWM value of a fan
+func HistoryFanPwmKey(fanId string) string {
+	return "fan/" + fanId + "/pwm"
+}
+
+// HistoryFanRpmKey returns the key of the series holding the RPM value of a fan
+func HistoryFanRpmKey(fanId string) string {
+	return "fan/" + fanId + "/rpm"
+}
+
+// History is a bounded time-series store within the database. Each series is stored
+// in a ring of retention/interval slots, so old values are overwritten by new ones.
+type History struct {
+	persistence
+	interval time.Duration
+	size     int64
+}
+
+// NewHistory creates a history storing one value per interval and series, for the given retention
+func NewHistory(dbPath string, interval time.Duration, retention time.Duration) *History {
+	return &History{
+		persistence: persistence{
+			dbPath: dbPath,
+		},
+		interval: interval,
+		size:     int64(retention / interval),
+	}
+}
+
+// NewReadOnlyHistory creates a history which can only be loaded, without ever modifying the database file
+func NewReadOnlyHistory(dbPath string) *History {
+	return &History{
+		persistence: persistence{
+			dbPath:   dbPath,
+			readOnly: true,
+		},
+	}
+}
+
+// returns the slot of the ring the value of the given time is stored in
+func (h *History) slot(t time.Time) []byte {
+	key := make([]byte, 8)
+	index := (t.UnixNano() / int64(h.interval)) % h.size
+	binary.BigEndian.PutUint64(key, uint64(index))
+	return key
+}
+
+// Save stores the given values (by series key) for the given time
+func (h *History) Save(t time.Time, values map[string]float64) error {
+	if h.readOnly {
+		return bolt.ErrDatabaseReadOnly
+	}
+
+	db := h.openPersistence()
+	defer db.Close()
+
+	slot := h.slot(t)
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(BucketHistory))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+
+		for key, value := range values {
+			series, err := b.CreateBucketIfNotExists([]byte(key))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+
+			entry := make([]byte, historyEntrySize)
+			binary.BigEndian.PutUint64(entry[0:8], uint64(t.UnixNano()))
+			binary.BigEndian.PutUint64(entry[8:16], math.Float64bits(value))
+			err = series.Put(slot, entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Prune removes all slots which are no longer part of the ring, f.ex. after the retention has been reduced
+func (h *History) Prune() error {
+	if h.readOnly {
+		return bolt.ErrDatabaseReadOnly
+	}
+
+	db := h.openPersistence()
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketHistory))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(key, _ []byte) error {
+			series := b.Bucket(key)
+			if series == nil {
+				return nil
+			}
+
+			var obsolete [][]byte
+			start := make([]byte, 8)
+			binary.BigEndian.PutUint64(start, uint64(h.size))
+			c := series.Cursor()
+			for k, _ := c.Seek(start); k != nil; k, _ = c.Next() {
+				obsolete = append(obsolete, append([]byte{}, k...))
+			}
+			for _, k := range obsolete {
+				err := series.Delete(k)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Load returns the points of all series (by series key) within [from..to], ordered by time
+func (h *History) Load(from time.Time, to time.Time) (map[string][]HistoryPoint, error) {
+	db := h.openPersistence()
+	if db == nil {
+		return nil, os.ErrNotExist
+	}
+	defer db.Close()
+
+	result := map[string][]HistoryPoint{}
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketHistory))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(key, _ []byte) error {
+			series := b.Bucket(key)
+			if series == nil {
+				return nil
+			}
+
+			var points []HistoryPoint
+			err := series.ForEach(func(_, entry []byte) error {
+				if len(entry) != historyEntrySize {
+					return nil
+				}
+				t := time.Unix(0, int64(binary.BigEndian.Uint64(entry[0:8])))
+				if t.Before(from) || t.After(to) {
+					return nil
+				}
+				points = append(points, HistoryPoint{
+					Time:  t,
+					Value: math.Float64frombits(binary.BigEndian.Uint64(entry[8:16])),
+				})
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(points) > 0 {
+				sort.Slice(points, func(i, j int) bool {
+					return points[i].Time.Before(points[j].Time)
+				})
+				result[string(key)] = points
+			}
+			return nil
+		})
+	})
+
+	return result, err
+}
@@ -0,0 +1,121 @@
+package persistence
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHistorySaveAndLoad(t *testing.T) {
+	// GIVEN
+	_ = os.Remove(dbTestingPath)
+	history := NewHistory(dbTestingPath, time.Minute, time.Hour)
+	start := time.Unix(1633089600, 0)
+
+	// WHEN
+	err := history.Save(start, map[string]float64{
+		HistorySensorKey("cpu"): 45000,
+		HistoryFanPwmKey("fan"): 100,
+	})
+	assert.NoError(t, err)
+	err = history.Save(start.Add(time.Minute), map[string]float64{
+		HistorySensorKey("cpu"): 50000,
+	})
+	assert.NoError(t, err)
+
+	result, err := history.Load(start, start.Add(time.Hour))
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, []HistoryPoint{
+		{Time: start, Value: 45000},
+		{Time: start.Add(time.Minute), Value: 50000},
+	}, result["sensor/cpu"])
+	assert.Equal(t, []HistoryPoint{
+		{Time: start, Value: 100},
+	}, result["fan/fan/pwm"])
+}
+
+func TestHistoryLoadRange(t *testing.T) {
+	// GIVEN
+	_ = os.Remove(dbTestingPath)
+	history := NewHistory(dbTestingPath, time.Minute, time.Hour)
+	start := time.Unix(1633089600, 0)
+	for i := 0; i < 10; i++ {
+		err := history.Save(start.Add(time.Duration(i)*time.Minute), map[string]float64{
+			HistoryCurveKey("curve"): float64(i),
+		})
+		assert.NoError(t, err)
+	}
+
+	// WHEN
+	result, err := history.Load(start.Add(2*time.Minute), start.Add(4*time.Minute))
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Len(t, result["curve/curve"], 3)
+	assert.Equal(t, 2.0, result["curve/curve"][0].Value)
+	assert.Equal(t, 4.0, result["curve/curve"][2].Value)
+}
+
+func TestHistoryRingOverwritesOldValues(t *testing.T) {
+	// GIVEN
+	_ = os.Remove(dbTestingPath)
+	history := NewHistory(dbTestingPath, time.Minute, 5*time.Minute)
+	start := time.Unix(1633089600, 0)
+
+	// WHEN
+	for i := 0; i < 12; i++ {
+		err := history.Save(start.Add(time.Duration(i)*time.Minute), map[string]float64{
+			HistoryFanRpmKey("fan"): float64(i),
+		})
+		assert.NoError(t, err)
+	}
+	result, err := history.Load(start, start.Add(time.Hour))
+
+	// THEN
+	assert.NoError(t, err)
+	points := result["fan/fan/rpm"]
+	assert.Len(t, points, 5)
+	assert.Equal(t, 7.0, points[0].Value)
+	assert.Equal(t, 11.0, points[4].Value)
+}
+
+func TestHistoryPruneAfterReducedRetention(t *testing.T) {
+	// GIVEN
+	_ = os.Remove(dbTestingPath)
+	start := time.Unix(1633089600, 0)
+	history := NewHistory(dbTestingPath, time.Minute, time.Hour)
+	for i := 0; i < 10; i++ {
+		err := history.Save(start.Add(time.Duration(i)*time.Minute), map[string]float64{
+			HistorySensorKey("cpu"): float64(i),
+		})
+		assert.NoError(t, err)
+	}
+
+	// WHEN
+	history = NewHistory(dbTestingPath, time.Minute, 5*time.Minute)
+	err := history.Prune()
+	assert.NoError(t, err)
+	result, err := history.Load(start, start.Add(time.Hour))
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Len(t, result["sensor/cpu"], 5)
+}
+
+func TestReadOnlyHistory(t *testing.T) {
+	// GIVEN
+	history := NewReadOnlyHistory("./does_not_exist.db")
+
+	// WHEN
+	err := history.Save(time.Now(), map[string]float64{HistorySensorKey("cpu"): 1})
+	_, loadErr := history.Load(time.Time{}, time.Now())
+
+	// THEN
+	assert.Error(t, err)
+	assert.ErrorIs(t, loadErr, os.ErrNotExist)
+	_, statErr := os.Stat("./does_not_exist.db")
+	assert.True(t, os.IsNotExist(statErr))
+}
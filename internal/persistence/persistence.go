@@ -11,16 +11,29 @@ import (
 )
 
 const (
-	BucketFans = "fans"
+	BucketFans             = "fans"
+	BucketFanPwmThresholds = "fan_pwm_thresholds"
 )
 
 type Persistence interface {
 	LoadFanPwmData(fan fans.Fan) (map[int]float64, error)
 	SaveFanPwmData(fan fans.Fan) (err error)
+
+	LoadFanPwmThresholds(fan fans.Fan) (FanPwmThresholds, error)
+	SaveFanPwmThresholds(fan fans.Fan, thresholds FanPwmThresholds) (err error)
+}
+
+// FanPwmThresholds holds the measured PWM thresholds of a fan
+type FanPwmThresholds struct {
+	// StartPwm is the lowest PWM value at which the fan starts to rotate from a stand still
+	StartPwm int `json:"startPwm"`
+	// MinPwm is the lowest PWM value at which the fan keeps rotating, when spinning previously
+	MinPwm int `json:"minPwm"`
 }
 
 type persistence struct {
-	dbPath string
+	dbPath   string
+	readOnly bool
 }
 
 func NewPersistence(dbPath string) Persistence {
@@ -30,9 +43,30 @@ func NewPersistence(dbPath string) Persistence {
 	return p
 }
 
+// NewReadOnlyPersistence creates a persistence which never modifies the database file.
+// Loading data from a missing or unreadable database file results in os.ErrNotExist.
+func NewReadOnlyPersistence(dbPath string) Persistence {
+	p := &persistence{
+		dbPath:   dbPath,
+		readOnly: true,
+	}
+	return p
+}
+
 func (p persistence) openPersistence() *bolt.DB {
-	db, err := bolt.Open(p.dbPath, 0600, &bolt.Options{Timeout: 1 * time.Minute})
+	if p.readOnly {
+		// opening a missing database would create it
+		if _, err := os.Stat(p.dbPath); err != nil {
+			ui.Warning("Could not open database file: %v", err)
+			return nil
+		}
+	}
+	db, err := bolt.Open(p.dbPath, 0600, &bolt.Options{Timeout: 1 * time.Minute, ReadOnly: p.readOnly})
 	if err != nil {
+		if p.readOnly {
+			ui.Warning("Could not open database file: %v", err)
+			return nil
+		}
 		ui.Error("Could not open database file: %v", err)
 		os.Exit(1)
 	}
@@ -41,6 +75,10 @@ func (p persistence) openPersistence() *bolt.DB {
 
 // SaveFanPwmData saves the fan curve data of the given fan to persistence
 func (p persistence) SaveFanPwmData(fan fans.Fan) (err error) {
+	if p.readOnly {
+		return bolt.ErrDatabaseReadOnly
+	}
+
 	db := p.openPersistence()
 	defer db.Close()
 
@@ -70,12 +108,21 @@ func (p persistence) SaveFanPwmData(fan fans.Fan) (err error) {
 // LoadFanPwmData loads the fan curve data from persistence
 func (p persistence) LoadFanPwmData(fan fans.Fan) (map[int]float64, error) {
 	db := p.openPersistence()
+	if db == nil {
+		return nil, os.ErrNotExist
+	}
 	defer db.Close()
 
 	key := fan.GetId()
 
+	// corrupt data is deleted, which requires a writable transaction
+	transaction := db.Update
+	if p.readOnly {
+		transaction = db.View
+	}
+
 	fanCurveDataMap := map[int]float64{}
-	err := db.Update(func(tx *bolt.Tx) error {
+	err := transaction(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(BucketFans))
 		if b == nil {
 			return os.ErrNotExist
@@ -101,3 +148,56 @@ func (p persistence) LoadFanPwmData(fan fans.Fan) (map[int]float64, error) {
 
 	return fanCurveDataMap, err
 }
+
+// SaveFanPwmThresholds saves the measured PWM thresholds of the given fan to persistence
+func (p persistence) SaveFanPwmThresholds(fan fans.Fan, thresholds FanPwmThresholds) (err error) {
+	if p.readOnly {
+		return bolt.ErrDatabaseReadOnly
+	}
+
+	db := p.openPersistence()
+	defer db.Close()
+
+	key := fan.GetId()
+
+	data, err := json.Marshal(thresholds)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(BucketFanPwmThresholds))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+		err = b.Put([]byte(key), data)
+		return err
+	})
+}
+
+// LoadFanPwmThresholds loads the measured PWM thresholds of the given fan from persistence
+func (p persistence) LoadFanPwmThresholds(fan fans.Fan) (FanPwmThresholds, error) {
+	db := p.openPersistence()
+	if db == nil {
+		return FanPwmThresholds{}, os.ErrNotExist
+	}
+	defer db.Close()
+
+	key := fan.GetId()
+
+	thresholds := FanPwmThresholds{}
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketFanPwmThresholds))
+		if b == nil {
+			return os.ErrNotExist
+		}
+		v := b.Get([]byte(key))
+		if v == nil {
+			return os.ErrNotExist
+		}
+
+		return json.Unmarshal(v, &thresholds)
+	})
+
+	return thresholds, err
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/markusressel/fan2go/internal/fans"
 	"github.com/markusressel/fan2go/internal/util"
 	"github.com/stretchr/testify/assert"
+	"os"
 	"testing"
 )
 
@@ -57,6 +58,27 @@ func TestReadFan(t *testing.T) {
 	assert.Equal(t, expected, fanData)
 }
 
+func TestReadFanPwmThresholds(t *testing.T) {
+	// GIVEN
+	persistence := NewPersistence(dbTestingPath)
+
+	fan, _ := createFan(false, NeverStoppingFan)
+	expected := FanPwmThresholds{
+		StartPwm: 40,
+		MinPwm:   25,
+	}
+
+	err := persistence.SaveFanPwmThresholds(fan, expected)
+	assert.NoError(t, err)
+
+	// WHEN
+	thresholds, err := persistence.LoadFanPwmThresholds(fan)
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Equal(t, expected, thresholds)
+}
+
 func createFan(neverStop bool, curveData map[int]float64) (fan fans.Fan, err error) {
 	configuration.CurrentConfig.RpmRollingWindowSize = 10
 
@@ -79,3 +101,18 @@ func createFan(neverStop bool, curveData map[int]float64) (fan fans.Fan, err err
 
 	return fan, err
 }
+
+func TestReadOnlyPersistence(t *testing.T) {
+	// GIVEN
+	persistence := NewReadOnlyPersistence("./does_not_exist.db")
+
+	fan, _ := createFan(false, LinearFan)
+
+	// WHEN
+	_, loadErr := persistence.LoadFanPwmData(fan)
+	saveErr := persistence.SaveFanPwmData(fan)
+
+	// THEN
+	assert.ErrorIs(t, loadErr, os.ErrNotExist)
+	assert.Error(t, saveErr)
+}
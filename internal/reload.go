@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/curves"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/hwmon"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/ui"
+	"github.com/markusressel/fan2go/internal/util"
+)
+
+// the sensors, curves and fans created for a new configuration
+type reloadedObjects struct {
+	sensors map[string]sensors.Sensor
+	curves  map[string]curves.SpeedCurve
+	fans    map[string]fans.Fan
+}
+
+// reloadConfig reads the configuration file again and applies all changes of sensors, curves and fans
+// to the running daemon. Fans which stay bound to the same device keep running, without being initialized again.
+// An invalid configuration is rejected, keeping the current one.
+func reloadConfig(devices *deviceManager) {
+	ui.Info("Reloading configuration...")
+	newConfig, err := configuration.ReloadConfigFile()
+	if err != nil {
+		ui.Error("Invalid configuration, keeping the current one: %v", err)
+		return
+	}
+	applyConfig(devices, newConfig)
+}
+
+// applyConfig applies all changes of sensors, curves and fans of the given (valid) configuration
+// to the running daemon, and makes it the current configuration
+func applyConfig(devices *deviceManager, newConfig configuration.Configuration) {
+	oldConfig := configuration.GetConfig()
+	for _, setting := range configuration.RetainRestartSettings(&newConfig, oldConfig) {
+		ui.Warning("Changing '%s' requires a restart of fan2go, keeping the current value", setting)
+	}
+
+	diff := configuration.ComputeDiff(oldConfig, newConfig)
+	retainResolvedPaths(&newConfig, diff)
+
+	objects, err := createReloadedObjects(newConfig, diff)
+	if err != nil {
+		ui.Error("Unable to apply configuration, keeping the current one: %v", err)
+		return
+	}
+
+	configuration.SetConfig(newConfig)
+	if diff.IsEmpty() {
+		ui.Info("Configuration reloaded, no sensor, curve or fan changed")
+		return
+	}
+	applyReloadedObjects(devices, newConfig, diff, objects)
+	ui.Info("Configuration reloaded")
+}
+
+// copies the paths of all running hwmon sensors and fans, which stay bound to the same device.
+// The paths are taken from the sensors and fans, since they are rebound when their device is renumbered.
+func retainResolvedPaths(new *configuration.Configuration, diff configuration.Diff) {
+	for _, newSensor := range new.Sensors {
+		if newSensor.HwMon == nil || util.ContainsString(diff.ChangedSensors, newSensor.ID) {
+			continue
+		}
+		s, _ := sensors.GetSensor(newSensor.ID)
+		if sensor, ok := s.(*sensors.HwmonSensor); ok {
+			newSensor.HwMon.TempInput = sensor.GetInput()
+		}
+	}
+
+	for _, newFan := range new.Fans {
+		if newFan.HwMon == nil || util.ContainsString(diff.ReboundFans, newFan.ID) {
+			continue
+		}
+		f, _ := fans.GetFan(newFan.ID)
+		if fan, ok := f.(*fans.HwMonFan); ok {
+			newFan.HwMon.PwmOutput, newFan.HwMon.RpmInput = fan.GetPaths()
+		}
+	}
+}
+
+// creates all sensors, curves and fans which have been added, or have to be replaced
+func createReloadedObjects(config configuration.Configuration, diff configuration.Diff) (*reloadedObjects, error) {
+	objects := &reloadedObjects{
+		sensors: map[string]sensors.Sensor{},
+		curves:  map[string]curves.SpeedCurve{},
+		fans:    map[string]fans.Fan{},
+	}
+
+	// devices are only scanned if any hwmon sensor or fan has to be resolved
+	var controllers []*hwmon.HwMonController
+	getControllers := func(hwMon bool) []*hwmon.HwMonController {
+		if hwMon && controllers == nil {
+			controllers = hwmon.GetChips()
+		}
+		return controllers
+	}
+
+	for _, sensorConfig := range config.Sensors {
+		if !util.ContainsString(diff.AddedSensors, sensorConfig.ID) && !util.ContainsString(diff.ChangedSensors, sensorConfig.ID) {
+			continue
+		}
+		sensor, err := createSensor(sensorConfig, getControllers(sensorConfig.HwMon != nil))
+		if err != nil {
+			return nil, err
+		}
+		objects.sensors[sensorConfig.ID] = sensor
+	}
+
+	for _, curveConfig := range config.Curves {
+		if !util.ContainsString(diff.AddedCurves, curveConfig.ID) && !util.ContainsString(diff.ChangedCurves, curveConfig.ID) {
+			continue
+		}
+		curve, err := curves.NewSpeedCurve(curveConfig)
+		if err != nil {
+			return nil, err
+		}
+		objects.curves[curveConfig.ID] = curve
+	}
+
+	for _, fanConfig := range config.Fans {
+		if !util.ContainsString(diff.AddedFans, fanConfig.ID) && !util.ContainsString(diff.ReboundFans, fanConfig.ID) {
+			continue
+		}
+		fan, err := createFan(fanConfig, getControllers(fanConfig.HwMon != nil))
+		if err != nil {
+			return nil, err
+		}
+		objects.fans[fanConfig.ID] = fan
+	}
+
+	return objects, nil
+}
+
+// replaces the running sensors, curves and fans with the given objects. Removed sensors and
+// curves are only dropped once no fan can use them anymore, since they are looked up by their id.
+func applyReloadedObjects(devices *deviceManager, config configuration.Configuration, diff configuration.Diff, objects *reloadedObjects) {
+	// === sensors
+	sensorMap := map[string]sensors.Sensor{}
+	for id, sensor := range sensors.GetSensors() {
+		sensorMap[id] = sensor
+	}
+	for _, id := range diff.ChangedSensors {
+		devices.stopSensor(id)
+	}
+	for id, sensor := range objects.sensors {
+		sensorMap[id] = sensor
+	}
+	sensors.SetSensors(sensorMap)
+
+	// === curves
+	curveMap := map[string]curves.SpeedCurve{}
+	for id, curve := range curves.GetSpeedCurves() {
+		curveMap[id] = curve
+	}
+	for id, curve := range objects.curves {
+		curveMap[id] = curve
+	}
+	curves.SetSpeedCurves(curveMap)
+
+	// === fans
+	for _, id := range diff.RemovedFans {
+		ui.Info("Removing fan %s", id)
+		devices.stopFan(id)
+	}
+	for _, id := range diff.ReboundFans {
+		ui.Info("Fan %s is bound to a different device, restarting its controller", id)
+		devices.stopFan(id)
+	}
+	for _, fanConfig := range config.Fans {
+		if !util.ContainsString(diff.ChangedFans, fanConfig.ID) {
+			continue
+		}
+		if c, ok := devices.getController(fanConfig.ID); ok {
+			c.UpdateConfig(fanConfig)
+		}
+	}
+
+	fanMap := map[string]fans.Fan{}
+	for id, fan := range fans.GetFans() {
+		if !util.ContainsString(diff.RemovedFans, id) {
+			fanMap[id] = fan
+		}
+	}
+	for id, fan := range objects.fans {
+		fanMap[id] = fan
+	}
+	fans.SetFans(fanMap)
+
+	// fans have to be known before sensors, so they can be put into failsafe
+	// while the device of a sensor is missing
+	for _, fanConfig := range config.Fans {
+		if fan, ok := objects.fans[fanConfig.ID]; ok {
+			ui.Info("Starting fan %s", fanConfig.ID)
+			devices.startFan(fan)
+		}
+	}
+	for _, sensorConfig := range config.Sensors {
+		if sensor, ok := objects.sensors[sensorConfig.ID]; ok {
+			devices.startSensor(sensor)
+		}
+	}
+
+	// === drop removed sensors and curves, which are no longer used by any fan
+	for _, id := range diff.RemovedSensors {
+		devices.stopSensor(id)
+	}
+	sensorMap = map[string]sensors.Sensor{}
+	for id, sensor := range sensors.GetSensors() {
+		if !util.ContainsString(diff.RemovedSensors, id) {
+			sensorMap[id] = sensor
+		}
+	}
+	sensors.SetSensors(sensorMap)
+
+	curveMap = map[string]curves.SpeedCurve{}
+	for id, curve := range curves.GetSpeedCurves() {
+		if !util.ContainsString(diff.RemovedCurves, id) {
+			curveMap[id] = curve
+		}
+	}
+	curves.SetSpeedCurves(curveMap)
+
+	devices.registerCollectors()
+}
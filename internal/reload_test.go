@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/curves"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/statistics"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// helper function to create a configuration with a file sensor, a curve and a file fan in the given directory,
+// the given variant changes the sensor, the curve and the fan
+func createReloadTestConfig(dir string, variant int) configuration.Configuration {
+	return configuration.Configuration{
+		TempSensorPollingRate:          10 * time.Millisecond,
+		TempRollingWindowSize:          10,
+		RpmPollingRate:                 10 * time.Millisecond,
+		RpmRollingWindowSize:           10,
+		ControllerAdjustmentTickRate:   10 * time.Millisecond,
+		RunFanInitializationInParallel: true,
+		Sensors: []configuration.SensorConfig{
+			{
+				ID:   "reload_sensor",
+				File: &configuration.FileSensorConfig{Path: filepath.Join(dir, "sensor"+strconv.Itoa(variant))},
+			},
+		},
+		Curves: []configuration.CurveConfig{
+			{
+				ID:     "reload_curve",
+				Linear: &configuration.LinearCurveConfig{Sensor: "reload_sensor", Min: 40, Max: 70 + variant},
+			},
+		},
+		Fans: []configuration.FanConfig{
+			{
+				ID:        "reload_fan",
+				Curve:     "reload_curve",
+				Smoothing: float64(variant) / 10,
+				File:      &configuration.FileFanConfig{Path: filepath.Join(dir, "fan")},
+			},
+		},
+	}
+}
+
+func TestReloadWhileRunning(t *testing.T) {
+	// GIVEN
+	dir := t.TempDir()
+	for _, name := range []string{"sensor0", "sensor1", "fan"} {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte("50000"), 0644)
+		assert.NoError(t, err)
+	}
+	configuration.SetConfig(createReloadTestConfig(dir, 0))
+	sensors.SetSensors(map[string]sensors.Sensor{})
+	curves.SetSpeedCurves(map[string]curves.SpeedCurve{})
+	fans.SetFans(map[string]fans.Fan{})
+	InitializeObjects()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pers := persistence.NewPersistence(filepath.Join(dir, "fan2go.db"))
+	devices := newDeviceManager(ctx, cancel, pers, newHotplugMonitor(time.Second), nil)
+	for _, fan := range fans.GetFans() {
+		devices.startFan(fan)
+	}
+	for _, sensor := range sensors.GetSensors() {
+		devices.startSensor(sensor)
+	}
+	c, ok := devices.getController("reload_fan")
+	assert.True(t, ok)
+
+	// WHEN
+	// reload until the controller has been updated a couple of times
+	var firstHeartbeat time.Time
+	variant := 0
+	timeout := time.After(10 * time.Second)
+	for firstHeartbeat.IsZero() || c.GetLastHeartbeat().Sub(firstHeartbeat) < 100*time.Millisecond {
+		select {
+		case <-timeout:
+			t.Fatal("the controller has not been updated")
+		default:
+		}
+		if firstHeartbeat.IsZero() {
+			firstHeartbeat = c.GetLastHeartbeat()
+		}
+		variant = 1 - variant
+		applyConfig(devices, createReloadTestConfig(dir, variant))
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	devices.Wait()
+	// the collectors are registered globally
+	for _, collector := range devices.collectors {
+		statistics.Unregister(collector)
+	}
+
+	// THEN
+	// the controller kept running
+	current, _ := devices.getController("reload_fan")
+	assert.Equal(t, c, current)
+	assert.Equal(t, 70+variant, configuration.GetConfig().Curves[0].Linear.Max)
+	sensor, ok := sensors.GetSensor("reload_sensor")
+	assert.True(t, ok)
+	assert.Equal(t, createReloadTestConfig(dir, variant).Sensors[0], sensor.GetConfig())
+	_, ok = curves.GetSpeedCurve("reload_curve")
+	assert.True(t, ok)
+	fan, ok := fans.GetFan("reload_fan")
+	assert.True(t, ok)
+	assert.Equal(t, float64(variant)/10, fan.GetConfig().Smoothing)
+}
@@ -0,0 +1,170 @@
+package replay
+
+import (
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/controller"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/util"
+	"os"
+)
+
+var linearFanCurve = map[int]float64{
+	fans.MinPwmValue: fans.MinPwmValue,
+	fans.MaxPwmValue: fans.MaxPwmValue,
+}
+
+// replayFan is a virtual fan, whose RPM follows its fan curve
+type replayFan struct {
+	Config       configuration.FanConfig
+	Pwm          int
+	PwmEnabled   int
+	RpmMovingAvg float64
+	StartPwm     *int
+	MinPwm       int
+	MaxPwm       int
+	FanCurveData *map[int]float64
+}
+
+// creates a virtual fan using the fan curve and PWM thresholds stored in the given persistence
+func newReplayFan(config configuration.FanConfig, pers persistence.Persistence) *replayFan {
+	fan := &replayFan{
+		Config:     config,
+		PwmEnabled: 1,
+		StartPwm:   config.StartPwm,
+		MinPwm:     fans.MinPwmValue,
+		MaxPwm:     fans.MaxPwmValue,
+	}
+
+	curveData := linearFanCurve
+	if pers != nil {
+		data, err := pers.LoadFanPwmData(fan)
+		if err == nil && len(data) > 0 {
+			curveData = data
+		}
+	}
+	_ = fan.AttachFanCurveData(&curveData)
+
+	if pers != nil {
+		thresholds, err := pers.LoadFanPwmThresholds(fan)
+		if err == nil {
+			controller.ApplyPwmThresholds(fan, thresholds)
+		}
+	}
+
+	return fan
+}
+
+func (fan replayFan) GetId() string {
+	return fan.Config.ID
+}
+
+func (fan replayFan) GetConfig() configuration.FanConfig {
+	return fan.Config
+}
+
+func (fan *replayFan) SetConfig(config configuration.FanConfig) {
+	fan.Config = config
+}
+
+func (fan replayFan) GetStartPwm() int {
+	if fan.StartPwm != nil {
+		return *fan.StartPwm
+	} else {
+		return fans.MaxPwmValue
+	}
+}
+
+func (fan *replayFan) SetStartPwm(pwm int) {
+	fan.StartPwm = &pwm
+}
+
+func (fan replayFan) GetMinPwm() int {
+	return fan.MinPwm
+}
+
+func (fan *replayFan) SetMinPwm(pwm int) {
+	fan.MinPwm = pwm
+}
+
+func (fan replayFan) GetMaxPwm() int {
+	return fan.MaxPwm
+}
+
+func (fan *replayFan) SetMaxPwm(pwm int) {
+	fan.MaxPwm = pwm
+}
+
+// GetRpm returns the RPM value of the fan curve at the current PWM value
+func (fan replayFan) GetRpm() int {
+	if fan.FanCurveData == nil || fan.Pwm < fan.MinPwm {
+		return 0
+	}
+	return int((*fan.FanCurveData)[fan.Pwm])
+}
+
+func (fan replayFan) GetRpmAvg() float64 {
+	return fan.RpmMovingAvg
+}
+
+func (fan *replayFan) SetRpmAvg(rpm float64) {
+	fan.RpmMovingAvg = rpm
+}
+
+func (fan replayFan) GetPwm() int {
+	return fan.Pwm
+}
+
+func (fan *replayFan) SetPwm(pwm int) (err error) {
+	fan.Pwm = pwm
+	return nil
+}
+
+func (fan replayFan) GetFanCurveData() *map[int]float64 {
+	return fan.FanCurveData
+}
+
+func (fan *replayFan) AttachFanCurveData(curveData *map[int]float64) (err error) {
+	if curveData == nil || len(*curveData) <= 0 {
+		return os.ErrInvalid
+	}
+
+	interpolatedCurve := util.InterpolateLinearly(curveData, 0, 255)
+	fan.FanCurveData = &interpolatedCurve
+
+	startPwm, maxPwm := fans.ComputePwmBoundaries(fan)
+	fan.SetStartPwm(startPwm)
+	fan.SetMaxPwm(maxPwm)
+	fan.SetMinPwm(startPwm)
+
+	return nil
+}
+
+func (fan replayFan) GetCurveId() string {
+	return fan.Config.Curve
+}
+
+func (fan replayFan) ShouldNeverStop() bool {
+	return fan.Config.NeverStop
+}
+
+func (fan replayFan) GetPwmEnabled() (int, error) {
+	return fan.PwmEnabled, nil
+}
+
+func (fan *replayFan) SetPwmEnabled(value int) (err error) {
+	fan.PwmEnabled = value
+	return nil
+}
+
+func (fan replayFan) IsPwmAuto() (bool, error) {
+	return fan.PwmEnabled > 1, nil
+}
+
+func (fan replayFan) Supports(feature int) bool {
+	switch feature {
+	case fans.FeatureRpmSensor:
+		return true
+	}
+	return false
+}
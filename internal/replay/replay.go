@@ -0,0 +1,186 @@
+package replay
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/controller"
+	"github.com/markusressel/fan2go/internal/curves"
+	"github.com/markusressel/fan2go/internal/fans"
+	"github.com/markusressel/fan2go/internal/persistence"
+	"github.com/markusressel/fan2go/internal/sensors"
+	"github.com/markusressel/fan2go/internal/util"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Result holds the timeline of a replay, with one point per sample of the replayed trace
+type Result struct {
+	FanIds []string
+	Points []Point
+}
+
+// Point holds the PWM value of each fan at a single point in time
+type Point struct {
+	Time time.Time
+	Pwm  map[string]int
+}
+
+// Run replays the given trace through the sensors, curves and fan controllers of the given
+// configuration, using a virtual clock so the trace is processed faster than real time.
+// Sensors are polled and controllers are updated at the rates given by the configuration,
+// a sensor keeps its value until the next sample of the trace containing it.
+// The fan curves (start, min and max PWM) of all fans are loaded from the given persistence,
+// fans without any persisted data are assumed to be linear.
+func Run(config configuration.Configuration, trace Trace, pers persistence.Persistence) (*Result, error) {
+	if len(trace) <= 0 {
+		return nil, fmt.Errorf("trace contains no samples")
+	}
+	if config.TempSensorPollingRate <= 0 || config.ControllerAdjustmentTickRate <= 0 {
+		return nil, fmt.Errorf("tempSensorPollingRate and controllerAdjustmentTickRate must be positive")
+	}
+
+	now := trace[0].Time
+	clock := func() time.Time {
+		return now
+	}
+
+	result := &Result{}
+
+	traceSensors := map[string]*traceSensor{}
+	availableSensors := map[string]bool{}
+	for _, id := range trace.SensorIds() {
+		availableSensors[id] = true
+	}
+	for _, sensorConfig := range config.Sensors {
+		if !availableSensors[sensorConfig.ID] {
+			return nil, fmt.Errorf("trace contains no values for sensor %s", sensorConfig.ID)
+		}
+		sensor := &traceSensor{Config: sensorConfig}
+		traceSensors[sensorConfig.ID] = sensor
+		sensors.SensorMap[sensorConfig.ID] = sensor
+	}
+
+	for _, curveConfig := range config.Curves {
+		curve, err := curves.NewSpeedCurveWithClock(curveConfig, clock)
+		if err != nil {
+			return nil, err
+		}
+		curves.SpeedCurveMap[curveConfig.ID] = curve
+	}
+
+	var replayFans []*replayFan
+	var controllers []controller.FanController
+	for _, fanConfig := range config.Fans {
+		fan := newReplayFan(fanConfig, pers)
+		fans.FanMap[fanConfig.ID] = fan
+		replayFans = append(replayFans, fan)
+		controllers = append(controllers, controller.NewFanControllerWithClock(pers, fan, config.ControllerAdjustmentTickRate, clock))
+		result.FanIds = append(result.FanIds, fanConfig.ID)
+	}
+
+	nextPoll := now.Add(config.TempSensorPollingRate)
+	nextTick := now.Add(config.ControllerAdjustmentTickRate)
+	for i, sample := range trace {
+		// process all sensor polls and controller ticks up until this sample
+		for {
+			next := nextPoll
+			if nextTick.Before(next) {
+				next = nextTick
+			}
+			if next.After(sample.Time) {
+				break
+			}
+			now = next
+
+			if !nextPoll.After(now) {
+				for _, sensor := range traceSensors {
+					sensor.SetMovingAvg(util.UpdateSimpleMovingAvg(sensor.GetMovingAvg(), config.TempRollingWindowSize, sensor.Value))
+				}
+				nextPoll = nextPoll.Add(config.TempSensorPollingRate)
+			}
+			if !nextTick.After(now) {
+				for idx, c := range controllers {
+					replayFans[idx].SetRpmAvg(float64(replayFans[idx].GetRpm()))
+					err := c.UpdateFanSpeed()
+					if err != nil {
+						return nil, err
+					}
+				}
+				nextTick = nextTick.Add(config.ControllerAdjustmentTickRate)
+			}
+		}
+		now = sample.Time
+
+		for id, value := range sample.Values {
+			sensor, ok := traceSensors[id]
+			if !ok {
+				continue
+			}
+			sensor.Value = value
+			if i == 0 {
+				sensor.SetMovingAvg(value)
+			}
+		}
+
+		point := Point{
+			Time: sample.Time,
+			Pwm:  map[string]int{},
+		}
+		for _, fan := range replayFans {
+			point.Pwm[fan.GetId()] = fan.GetPwm()
+		}
+		result.Points = append(result.Points, point)
+	}
+
+	return result, nil
+}
+
+// traceSensor is a sensor whose value is given by a trace
+type traceSensor struct {
+	Config    configuration.SensorConfig
+	Value     float64
+	MovingAvg float64
+}
+
+func (sensor traceSensor) GetId() string {
+	return sensor.Config.ID
+}
+
+func (sensor traceSensor) GetConfig() configuration.SensorConfig {
+	return sensor.Config
+}
+
+func (sensor traceSensor) GetValue() (float64, error) {
+	return sensor.Value, nil
+}
+
+func (sensor traceSensor) GetMovingAvg() float64 {
+	return sensor.MovingAvg
+}
+
+func (sensor *traceSensor) SetMovingAvg(avg float64) {
+	sensor.MovingAvg = avg
+}
+
+// WriteCsv writes the given result as CSV, with a header line of the form "time,<fan id>,<fan id>,..."
+func WriteCsv(writer io.Writer, result *Result) error {
+	w := csv.NewWriter(writer)
+	err := w.Write(append([]string{"time"}, result.FanIds...))
+	if err != nil {
+		return err
+	}
+	for _, point := range result.Points {
+		record := []string{point.Time.Format(time.RFC3339Nano)}
+		for _, fanId := range result.FanIds {
+			record = append(record, strconv.Itoa(point.Pwm[fanId]))
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
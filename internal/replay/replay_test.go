@@ -0,0 +1,118 @@
+package replay
+
+import (
+	"bytes"
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helper function to create a configuration with one sensor, one linear curve and the given fans
+func createReplayConfig(fans ...configuration.FanConfig) configuration.Configuration {
+	return configuration.Configuration{
+		TempSensorPollingRate:        1 * time.Second,
+		TempRollingWindowSize:        1,
+		ControllerAdjustmentTickRate: 1 * time.Second,
+		Sensors: []configuration.SensorConfig{
+			{
+				ID:   "replay_sensor",
+				File: &configuration.FileSensorConfig{Path: "/tmp/unused"},
+			},
+		},
+		Curves: []configuration.CurveConfig{
+			{
+				ID: "replay_curve",
+				Linear: &configuration.LinearCurveConfig{
+					Sensor: "replay_sensor",
+					Min:    40,
+					Max:    80,
+				},
+			},
+		},
+		Fans: fans,
+	}
+}
+
+// helper function to create a trace with the given values of the replay sensor, one sample every 10 seconds
+func createReplayTrace(values ...float64) Trace {
+	start := time.Unix(0, 0)
+	var trace Trace
+	for i, value := range values {
+		trace = append(trace, Sample{
+			Time:   start.Add(time.Duration(i) * 10 * time.Second),
+			Values: map[string]float64{"replay_sensor": value},
+		})
+	}
+	return trace
+}
+
+func TestRun(t *testing.T) {
+	// GIVEN
+	startPwm := 51
+	config := createReplayConfig(
+		configuration.FanConfig{ID: "plain", Curve: "replay_curve", StartPwm: &startPwm},
+		configuration.FanConfig{ID: "never_stop", Curve: "replay_curve", StartPwm: &startPwm, NeverStop: true},
+		configuration.FanConfig{
+			ID:       "ramped",
+			Curve:    "replay_curve",
+			StartPwm: &startPwm,
+			RampRate: &configuration.RampRateConfig{Increase: 10},
+		},
+	)
+	trace := createReplayTrace(40000, 50000, 80000, 80000)
+
+	// WHEN
+	result, err := Run(config, trace, nil)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"plain", "never_stop", "ramped"}, result.FanIds)
+	assert.Len(t, result.Points, 4)
+
+	assert.Equal(t, 0, result.Points[1].Pwm["plain"])
+	assert.Equal(t, startPwm, result.Points[1].Pwm["never_stop"])
+
+	assert.Equal(t, 64, result.Points[2].Pwm["plain"])
+	assert.Equal(t, 64, result.Points[2].Pwm["never_stop"])
+	assert.Equal(t, 64, result.Points[2].Pwm["ramped"])
+
+	assert.Equal(t, 255, result.Points[3].Pwm["plain"])
+	assert.Equal(t, 255, result.Points[3].Pwm["never_stop"])
+	// limited to 10 PWM steps per second
+	assert.InDelta(t, 64+100, result.Points[3].Pwm["ramped"], 5)
+}
+
+func TestRunMissingSensor(t *testing.T) {
+	// GIVEN
+	config := createReplayConfig(configuration.FanConfig{ID: "plain", Curve: "replay_curve"})
+	trace := Trace{
+		{Time: time.Unix(0, 0), Values: map[string]float64{"other_sensor": 40000}},
+	}
+
+	// WHEN
+	_, err := Run(config, trace, nil)
+
+	// THEN
+	assert.Error(t, err)
+}
+
+func TestWriteCsv(t *testing.T) {
+	// GIVEN
+	result := &Result{
+		FanIds: []string{"cpu", "case"},
+		Points: []Point{
+			{Time: time.Unix(0, 0).UTC(), Pwm: map[string]int{"cpu": 10, "case": 20}},
+		},
+	}
+	var buf bytes.Buffer
+
+	// WHEN
+	err := WriteCsv(&buf, result)
+
+	// THEN
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{"time,cpu,case", "1970-01-01T00:00:00Z,10,20"}, lines)
+}
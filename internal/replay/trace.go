@@ -0,0 +1,163 @@
+package replay
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sample holds the values of one or more sensors at a single point in time.
+// Values use the same unit as the sensor readings, f.ex. milli-degree.
+type Sample struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"`
+}
+
+// Trace is a list of samples, ordered by time
+type Trace []Sample
+
+// SensorIds returns the sorted IDs of all sensors with at least one value in this trace
+func (t Trace) SensorIds() []string {
+	ids := map[string]bool{}
+	for _, sample := range t {
+		for id := range sample.Values {
+			ids[id] = true
+		}
+	}
+
+	var result []string
+	for id := range ids {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ReadTrace reads a trace from the given file, a ".json" file is parsed using ParseJsonTrace,
+// any other file using ParseCsvTrace
+func ReadTrace(path string) (Trace, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseJsonTrace(file)
+	}
+	return ParseCsvTrace(file)
+}
+
+// ParseCsvTrace parses a trace with a header line of the form "time,<sensor id>,<sensor id>,...".
+// Each following line holds the time of a sample and the value of each sensor,
+// empty values are skipped.
+func ParseCsvTrace(reader io.Reader) (Trace, error) {
+	r := csv.NewReader(reader)
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("trace is empty")
+	} else if err != nil {
+		return nil, err
+	}
+	if len(header) < 2 || strings.TrimSpace(header[0]) != "time" {
+		return nil, errors.New("first column of the trace header must be 'time', followed by sensor IDs")
+	}
+
+	var trace Trace
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		t, err := parseTime(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+
+		sample := Sample{
+			Time:   t,
+			Values: map[string]float64{},
+		}
+		for i := 1; i < len(record); i++ {
+			if len(strings.TrimSpace(record[i])) <= 0 {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid value for sensor %s: %v", row, header[i], err)
+			}
+			sample.Values[strings.TrimSpace(header[i])] = value
+		}
+		trace = append(trace, sample)
+	}
+
+	return sortTrace(trace)
+}
+
+// ParseJsonTrace parses a trace in the form of a list of samples, f.ex.:
+// [{"time": "2021-10-01T12:00:00Z", "values": {"cpu_package": 45000}}]
+func ParseJsonTrace(reader io.Reader) (Trace, error) {
+	var samples []struct {
+		Time   json.RawMessage    `json:"time"`
+		Values map[string]float64 `json:"values"`
+	}
+	err := json.NewDecoder(reader).Decode(&samples)
+	if err != nil {
+		return nil, err
+	}
+
+	var trace Trace
+	for i, s := range samples {
+		var text string
+		if json.Unmarshal(s.Time, &text) != nil {
+			text = string(s.Time)
+		}
+		t, err := parseTime(text)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %v", i, err)
+		}
+		trace = append(trace, Sample{
+			Time:   t,
+			Values: s.Values,
+		})
+	}
+
+	return sortTrace(trace)
+}
+
+// parses an RFC 3339 timestamp, or a unix timestamp in (fractional) seconds
+func parseTime(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+		return t, nil
+	}
+	seconds, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%s', use RFC 3339 or unix seconds", text)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
+
+func sortTrace(trace Trace) (Trace, error) {
+	if len(trace) <= 0 {
+		return nil, errors.New("trace contains no samples")
+	}
+	sort.SliceStable(trace, func(i, j int) bool {
+		return trace[i].Time.Before(trace[j].Time)
+	})
+	return trace, nil
+}
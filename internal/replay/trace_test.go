@@ -0,0 +1,56 @@
+package replay
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCsvTrace(t *testing.T) {
+	// GIVEN
+	input := `time,cpu,gpu
+# comment
+2021-10-01T12:00:01Z,46000,
+2021-10-01T12:00:00Z,45000,50000
+`
+
+	// WHEN
+	trace, err := ParseCsvTrace(strings.NewReader(input))
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Len(t, trace, 2)
+	assert.Equal(t, time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC), trace[0].Time.UTC())
+	assert.Equal(t, map[string]float64{"cpu": 45000, "gpu": 50000}, trace[0].Values)
+	assert.Equal(t, map[string]float64{"cpu": 46000}, trace[1].Values)
+	assert.Equal(t, []string{"cpu", "gpu"}, trace.SensorIds())
+}
+
+func TestParseCsvTraceInvalidHeader(t *testing.T) {
+	// GIVEN
+	input := "cpu,gpu\n45000,50000\n"
+
+	// WHEN
+	_, err := ParseCsvTrace(strings.NewReader(input))
+
+	// THEN
+	assert.Error(t, err)
+}
+
+func TestParseJsonTrace(t *testing.T) {
+	// GIVEN
+	input := `[
+  {"time": 1633089600.5, "values": {"cpu": 45000}},
+  {"time": "2021-10-01T12:00:01Z", "values": {"cpu": 46000}}
+]`
+
+	// WHEN
+	trace, err := ParseJsonTrace(strings.NewReader(input))
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Len(t, trace, 2)
+	assert.Equal(t, time.Date(2021, 10, 1, 12, 0, 0, 500000000, time.UTC), trace[0].Time.UTC())
+	assert.Equal(t, 46000.0, trace[1].Values["cpu"])
+}
@@ -0,0 +1,61 @@
+package resume
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultPollingRate is the rate at which the clocks are compared
+	DefaultPollingRate = 1 * time.Second
+	// DefaultThreshold is the min difference between the wall clock and the monotonic clock,
+	// which is considered a suspend of the system
+	DefaultThreshold = 5 * time.Second
+)
+
+// Detector detects a resume of the system after a suspend.
+// The monotonic clock does not advance while the system is suspended, the wall clock does,
+// so a suspend shows up as a jump of the wall clock compared to the monotonic clock.
+type Detector struct {
+	pollingRate time.Duration
+	threshold   time.Duration
+}
+
+func NewDetector(pollingRate time.Duration, threshold time.Duration) *Detector {
+	return &Detector{
+		pollingRate: pollingRate,
+		threshold:   threshold,
+	}
+}
+
+// Run calls onResume whenever a resume is detected, or a signal is received on the given
+// channel (e.g. sent by a system-sleep hook), until the given context is done.
+func (d *Detector) Run(ctx context.Context, hook <-chan os.Signal, onResume func()) error {
+	tick := time.NewTicker(d.pollingRate)
+	defer tick.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hook:
+			onResume()
+			last = time.Now()
+		case <-tick.C:
+			now := time.Now()
+			// Sub uses the monotonic clock, the stripped values the wall clock
+			if d.IsResume(now.Sub(last), now.Round(0).Sub(last.Round(0))) {
+				onResume()
+			}
+			last = now
+		}
+	}
+}
+
+// IsResume returns true if the difference between the time passed on the monotonic clock
+// and the time passed on the wall clock indicates that the system was suspended in between.
+func (d *Detector) IsResume(monotonicElapsed time.Duration, wallElapsed time.Duration) bool {
+	return wallElapsed-monotonicElapsed > d.threshold
+}
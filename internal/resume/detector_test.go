@@ -0,0 +1,52 @@
+package resume
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsResume(t *testing.T) {
+	// GIVEN
+	d := NewDetector(DefaultPollingRate, DefaultThreshold)
+
+	// WHEN
+	normal := d.IsResume(1*time.Second, 1*time.Second)
+	clockAdjusted := d.IsResume(1*time.Second, 3*time.Second)
+	backwards := d.IsResume(1*time.Second, -1*time.Hour)
+	suspended := d.IsResume(1*time.Second, 1*time.Hour)
+
+	// THEN
+	assert.False(t, normal)
+	assert.False(t, clockAdjusted)
+	assert.False(t, backwards)
+	assert.True(t, suspended)
+}
+
+func TestRunHook(t *testing.T) {
+	// GIVEN
+	d := NewDetector(time.Hour, DefaultThreshold)
+	ctx, cancel := context.WithCancel(context.Background())
+	hook := make(chan os.Signal, 1)
+	resumed := make(chan bool, 1)
+
+	go func() {
+		_ = d.Run(ctx, hook, func() {
+			resumed <- true
+		})
+	}()
+
+	// WHEN
+	hook <- syscall.SIGUSR1
+
+	// THEN
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "resume hook was not called")
+	}
+	cancel()
+}
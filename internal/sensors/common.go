@@ -3,12 +3,38 @@ package sensors
 import (
 	"fmt"
 	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/simulation"
+	"sync"
 )
 
 var (
 	SensorMap = map[string]Sensor{}
+	// guards SensorMap, which is replaced when the configuration is reloaded
+	sensorMapMu sync.RWMutex
 )
 
+// GetSensor returns the sensor with the given id
+func GetSensor(id string) (Sensor, bool) {
+	sensorMapMu.RLock()
+	defer sensorMapMu.RUnlock()
+	sensor, ok := SensorMap[id]
+	return sensor, ok
+}
+
+// GetSensors returns all sensors, the returned map must not be modified
+func GetSensors() map[string]Sensor {
+	sensorMapMu.RLock()
+	defer sensorMapMu.RUnlock()
+	return SensorMap
+}
+
+// SetSensors replaces all sensors
+func SetSensors(sensorMap map[string]Sensor) {
+	sensorMapMu.Lock()
+	defer sensorMapMu.Unlock()
+	SensorMap = sensorMap
+}
+
 type Sensor interface {
 	GetId() string
 
@@ -39,5 +65,9 @@ func NewSensor(config configuration.SensorConfig) (Sensor, error) {
 		}, nil
 	}
 
+	if config.Simulated != nil {
+		return NewSimulatedSensor(config, simulation.DefaultModel), nil
+	}
+
 	return nil, fmt.Errorf("no matching sensor type for sensor: %s", config.ID)
 }
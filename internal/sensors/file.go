@@ -7,6 +7,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 type FileSensor struct {
@@ -14,17 +15,20 @@ type FileSensor struct {
 	FilePath  string                     `json:"file_path"`
 	Config    configuration.SensorConfig `json:"configuration"`
 	MovingAvg float64                    `json:"moving_avg"`
+
+	// guards the moving average, which is updated while the sensor is in use
+	mu sync.RWMutex
 }
 
-func (sensor FileSensor) GetId() string {
+func (sensor *FileSensor) GetId() string {
 	return sensor.Config.ID
 }
 
-func (sensor FileSensor) GetConfig() configuration.SensorConfig {
+func (sensor *FileSensor) GetConfig() configuration.SensorConfig {
 	return sensor.Config
 }
 
-func (sensor FileSensor) GetValue() (float64, error) {
+func (sensor *FileSensor) GetValue() (float64, error) {
 	filePath := sensor.FilePath
 	// resolve home dir path
 	if strings.HasPrefix(filePath, "~") {
@@ -46,10 +50,14 @@ func (sensor FileSensor) GetValue() (float64, error) {
 	return result, nil
 }
 
-func (sensor FileSensor) GetMovingAvg() (avg float64) {
+func (sensor *FileSensor) GetMovingAvg() (avg float64) {
+	sensor.mu.RLock()
+	defer sensor.mu.RUnlock()
 	return sensor.MovingAvg
 }
 
 func (sensor *FileSensor) SetMovingAvg(avg float64) {
+	sensor.mu.Lock()
+	defer sensor.mu.Unlock()
 	sensor.MovingAvg = avg
 }
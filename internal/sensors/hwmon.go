@@ -3,6 +3,7 @@ package sensors
 import (
 	"github.com/markusressel/fan2go/internal/configuration"
 	"github.com/markusressel/fan2go/internal/util"
+	"sync"
 )
 
 type HwmonSensor struct {
@@ -11,20 +12,41 @@ type HwmonSensor struct {
 	Input     string                     `json:"string"`
 	Max       int                        `json:"max"`
 	Min       int                        `json:"min"`
+	Crit      int                        `json:"crit"`
 	Config    configuration.SensorConfig `json:"configuration"`
 	MovingAvg float64                    `json:"moving_avg"`
+
+	// guards all fields, since the input may be rebound (see SetInput)
+	// while the sensor is in use
+	mu sync.RWMutex
 }
 
-func (sensor HwmonSensor) GetId() string {
-	return sensor.Config.ID
+func (sensor *HwmonSensor) GetId() string {
+	return sensor.GetConfig().ID
 }
 
-func (sensor HwmonSensor) GetConfig() configuration.SensorConfig {
+func (sensor *HwmonSensor) GetConfig() configuration.SensorConfig {
+	sensor.mu.RLock()
+	defer sensor.mu.RUnlock()
 	return sensor.Config
 }
 
-func (sensor HwmonSensor) GetValue() (result float64, err error) {
-	integer, err := util.ReadIntFromFile(sensor.Input)
+// GetInput returns the path of the input of this sensor
+func (sensor *HwmonSensor) GetInput() string {
+	sensor.mu.RLock()
+	defer sensor.mu.RUnlock()
+	return sensor.Input
+}
+
+// SetInput binds this sensor to the given input, f.ex. when its device has been renumbered
+func (sensor *HwmonSensor) SetInput(input string) {
+	sensor.mu.Lock()
+	defer sensor.mu.Unlock()
+	sensor.Input = input
+}
+
+func (sensor *HwmonSensor) GetValue() (result float64, err error) {
+	integer, err := util.ReadIntFromFile(sensor.GetInput())
 	if err != nil {
 		return 0, err
 	}
@@ -32,10 +54,14 @@ func (sensor HwmonSensor) GetValue() (result float64, err error) {
 	return result, err
 }
 
-func (sensor HwmonSensor) GetMovingAvg() (avg float64) {
+func (sensor *HwmonSensor) GetMovingAvg() (avg float64) {
+	sensor.mu.RLock()
+	defer sensor.mu.RUnlock()
 	return sensor.MovingAvg
 }
 
 func (sensor *HwmonSensor) SetMovingAvg(avg float64) {
+	sensor.mu.Lock()
+	defer sensor.mu.Unlock()
 	sensor.MovingAvg = avg
 }
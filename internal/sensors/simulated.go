@@ -0,0 +1,59 @@
+package sensors
+
+import (
+	"github.com/markusressel/fan2go/internal/configuration"
+	"github.com/markusressel/fan2go/internal/simulation"
+	"sync"
+)
+
+// SimulatedSensor measures the temperature of a heat source of a simulation.Model
+type SimulatedSensor struct {
+	Model     *simulation.Model          `json:"-"`
+	Config    configuration.SensorConfig `json:"configuration"`
+	MovingAvg float64                    `json:"moving_avg"`
+
+	// guards the moving average, which is updated while the sensor is in use
+	mu sync.RWMutex
+}
+
+// NewSimulatedSensor adds a heat source with the given configuration to the given model
+func NewSimulatedSensor(config configuration.SensorConfig, model *simulation.Model) *SimulatedSensor {
+	model.AddHeatSource(config.ID, simulation.HeatSourceParams{
+		Ambient: config.Simulated.Ambient,
+		Power:   config.Simulated.Power,
+		Cooling: config.Simulated.Cooling,
+		Fans:    config.Simulated.Fans,
+		Inertia: config.Simulated.Inertia,
+		Noise:   config.Simulated.Noise,
+	})
+
+	return &SimulatedSensor{
+		Model:  model,
+		Config: config,
+	}
+}
+
+func (sensor *SimulatedSensor) GetId() string {
+	return sensor.Config.ID
+}
+
+func (sensor *SimulatedSensor) GetConfig() configuration.SensorConfig {
+	return sensor.Config
+}
+
+// GetValue returns the current temperature of the heat source in milli-degree, like a hwmon sensor
+func (sensor *SimulatedSensor) GetValue() (float64, error) {
+	return sensor.Model.GetTemperature(sensor.GetId()) * 1000, nil
+}
+
+func (sensor *SimulatedSensor) GetMovingAvg() (avg float64) {
+	sensor.mu.RLock()
+	defer sensor.mu.RUnlock()
+	return sensor.MovingAvg
+}
+
+func (sensor *SimulatedSensor) SetMovingAvg(avg float64) {
+	sensor.mu.Lock()
+	defer sensor.mu.Unlock()
+	sensor.MovingAvg = avg
+}
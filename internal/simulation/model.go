@@ -0,0 +1,217 @@
+package simulation
+
+import (
+	"github.com/markusressel/fan2go/internal/util"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// StepSize is the max time span the model is advanced by in a single integration step
+const StepSize = 100 * time.Millisecond
+
+const maxPwm = 255
+
+var (
+	// DefaultModel is the model used by all simulated fans and sensors of the daemon
+	DefaultModel = NewModel(time.Now)
+)
+
+// FanParams describes the physical behaviour of a simulated fan
+type FanParams struct {
+	// MaxRpm is the speed of the fan at max PWM
+	MaxRpm float64
+	// StartPwm is the lowest PWM value at which the fan starts to rotate from a stand still
+	StartPwm int
+	// StopPwm is the PWM value below which a rotating fan stops
+	StopPwm int
+	// Inertia is the time constant of the fan speed following a PWM change
+	Inertia time.Duration
+	// Noise is the max deviation of a single RPM measurement from the actual speed
+	Noise float64
+}
+
+// HeatSourceParams describes the thermal behaviour of a simulated heat source,
+// f.ex. a CPU, which is cooled by the airflow of the given fans
+type HeatSourceParams struct {
+	// Ambient is the temperature (in degree) of the surrounding air
+	Ambient float64
+	// Power is the temperature rise (in degree) above ambient, without any airflow
+	Power float64
+	// Cooling is the factor by which the temperature rise is divided
+	// for each fan running at its max speed
+	Cooling float64
+	// Fans are the IDs of the fans cooling this heat source
+	Fans []string
+	// Inertia is the time constant of the temperature following a change of airflow
+	Inertia time.Duration
+	// Noise is the max deviation of a single measurement from the actual temperature
+	Noise float64
+}
+
+type fanState struct {
+	params   FanParams
+	pwm      int
+	rpm      float64
+	spinning bool
+}
+
+type heatSourceState struct {
+	params      HeatSourceParams
+	temperature float64
+}
+
+// Model simulates the speed of fans and the temperature of heat sources over time.
+// The model is advanced lazily to the current time of its clock whenever it is accessed.
+type Model struct {
+	mu          sync.Mutex
+	clock       util.Clock
+	lastUpdate  time.Time
+	random      *rand.Rand
+	fans        map[string]*fanState
+	heatSources map[string]*heatSourceState
+}
+
+func NewModel(clock util.Clock) *Model {
+	now := clock()
+	return &Model{
+		clock:       clock,
+		lastUpdate:  now,
+		random:      rand.New(rand.NewSource(now.UnixNano())),
+		fans:        map[string]*fanState{},
+		heatSources: map[string]*heatSourceState{},
+	}
+}
+
+// AddFan adds a fan with the given ID to the model, the fan starts at a stand still
+func (m *Model) AddFan(id string, params FanParams) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance()
+	m.fans[id] = &fanState{params: params}
+}
+
+// AddHeatSource adds a heat source with the given ID to the model,
+// the heat source starts at its ambient temperature
+func (m *Model) AddHeatSource(id string, params HeatSourceParams) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance()
+	m.heatSources[id] = &heatSourceState{
+		params:      params,
+		temperature: params.Ambient,
+	}
+}
+
+// SetPwm sets the PWM value driving the given fan
+func (m *Model) SetPwm(fanId string, pwm int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance()
+	if fan, ok := m.fans[fanId]; ok {
+		fan.pwm = pwm
+	}
+}
+
+// GetPwm returns the PWM value driving the given fan
+func (m *Model) GetPwm(fanId string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if fan, ok := m.fans[fanId]; ok {
+		return fan.pwm
+	}
+	return 0
+}
+
+// GetRpm returns a measurement of the current speed of the given fan
+func (m *Model) GetRpm(fanId string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance()
+	fan, ok := m.fans[fanId]
+	if !ok {
+		return 0
+	}
+	if fan.rpm <= 0 {
+		return 0
+	}
+	return math.Max(0, fan.rpm+m.noise(fan.params.Noise))
+}
+
+// GetTemperature returns a measurement of the current temperature (in degree) of the given heat source
+func (m *Model) GetTemperature(heatSourceId string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance()
+	heatSource, ok := m.heatSources[heatSourceId]
+	if !ok {
+		return 0
+	}
+	return heatSource.temperature + m.noise(heatSource.params.Noise)
+}
+
+// returns a random value in [-max..max]
+func (m *Model) noise(max float64) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return (m.random.Float64()*2 - 1) * max
+}
+
+// advances the model to the current time of its clock
+func (m *Model) advance() {
+	now := m.clock()
+	for m.lastUpdate.Before(now) {
+		dt := now.Sub(m.lastUpdate)
+		if dt > StepSize {
+			dt = StepSize
+		}
+		m.step(dt)
+		m.lastUpdate = m.lastUpdate.Add(dt)
+	}
+}
+
+func (m *Model) step(dt time.Duration) {
+	for _, fan := range m.fans {
+		params := fan.params
+		if fan.pwm >= params.StartPwm || (fan.spinning && fan.pwm >= params.StopPwm) {
+			fan.spinning = fan.pwm > 0
+		} else {
+			fan.spinning = false
+		}
+
+		target := 0.0
+		if fan.spinning {
+			target = params.MaxRpm * float64(fan.pwm) / maxPwm
+		}
+		fan.rpm = approach(fan.rpm, target, dt, params.Inertia)
+		if !fan.spinning && fan.rpm < 1 {
+			fan.rpm = 0
+		}
+	}
+
+	for _, heatSource := range m.heatSources {
+		params := heatSource.params
+		airflow := 0.0
+		for _, fanId := range params.Fans {
+			fan, ok := m.fans[fanId]
+			if !ok || fan.params.MaxRpm <= 0 {
+				continue
+			}
+			airflow += fan.rpm / fan.params.MaxRpm
+		}
+
+		target := params.Ambient + params.Power/(1+params.Cooling*airflow)
+		heatSource.temperature = approach(heatSource.temperature, target, dt, params.Inertia)
+	}
+}
+
+// moves the given value towards the given target with an exponential decay of the given time constant
+func approach(value float64, target float64, dt time.Duration, timeConstant time.Duration) float64 {
+	if timeConstant <= 0 {
+		return target
+	}
+	factor := 1 - math.Exp(-dt.Seconds()/timeConstant.Seconds())
+	return value + (target-value)*factor
+}
@@ -0,0 +1,127 @@
+package simulation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// helper function to create a model with a manually advanced clock
+func createTestModel() (*Model, *time.Time) {
+	now := time.Unix(0, 0)
+	model := NewModel(func() time.Time {
+		return now
+	})
+	return model, &now
+}
+
+func TestFanFollowsPwm(t *testing.T) {
+	// GIVEN
+	model, now := createTestModel()
+	model.AddFan("fan", FanParams{
+		MaxRpm:   2000,
+		StartPwm: 60,
+		StopPwm:  40,
+		Inertia:  1 * time.Second,
+	})
+
+	// WHEN
+	model.SetPwm("fan", 255)
+	*now = now.Add(500 * time.Millisecond)
+	accelerating := model.GetRpm("fan")
+	*now = now.Add(10 * time.Second)
+	full := model.GetRpm("fan")
+
+	// THEN
+	assert.Greater(t, accelerating, 0.0)
+	assert.Less(t, accelerating, 2000.0)
+	assert.InDelta(t, 2000, full, 1)
+}
+
+func TestFanStartAndStopThresholds(t *testing.T) {
+	// GIVEN
+	model, now := createTestModel()
+	model.AddFan("fan", FanParams{
+		MaxRpm:   2000,
+		StartPwm: 60,
+		StopPwm:  40,
+	})
+
+	// WHEN
+	model.SetPwm("fan", 50)
+	*now = now.Add(1 * time.Second)
+	notStarted := model.GetRpm("fan")
+
+	model.SetPwm("fan", 60)
+	*now = now.Add(1 * time.Second)
+	started := model.GetRpm("fan")
+
+	model.SetPwm("fan", 50)
+	*now = now.Add(1 * time.Second)
+	stillSpinning := model.GetRpm("fan")
+
+	model.SetPwm("fan", 39)
+	*now = now.Add(1 * time.Second)
+	stopped := model.GetRpm("fan")
+
+	// THEN
+	assert.Equal(t, 0.0, notStarted)
+	assert.Greater(t, started, 0.0)
+	assert.Greater(t, stillSpinning, 0.0)
+	assert.Equal(t, 0.0, stopped)
+}
+
+func TestHeatSourceIsCooledByAirflow(t *testing.T) {
+	// GIVEN
+	model, now := createTestModel()
+	model.AddFan("fan", FanParams{
+		MaxRpm:   2000,
+		StartPwm: 60,
+		StopPwm:  40,
+	})
+	model.AddHeatSource("cpu", HeatSourceParams{
+		Ambient: 25,
+		Power:   60,
+		Cooling: 2,
+		Fans:    []string{"fan"},
+		Inertia: 10 * time.Second,
+	})
+
+	// WHEN
+	initial := model.GetTemperature("cpu")
+	*now = now.Add(5 * time.Minute)
+	heated := model.GetTemperature("cpu")
+	model.SetPwm("fan", 255)
+	*now = now.Add(5 * time.Minute)
+	cooled := model.GetTemperature("cpu")
+
+	// THEN
+	assert.Equal(t, 25.0, initial)
+	assert.InDelta(t, 85, heated, 0.1)
+	assert.InDelta(t, 45, cooled, 0.1)
+}
+
+func TestNoise(t *testing.T) {
+	// GIVEN
+	model, now := createTestModel()
+	model.AddFan("fan", FanParams{
+		MaxRpm:   2000,
+		StartPwm: 60,
+		StopPwm:  40,
+		Noise:    50,
+	})
+	model.SetPwm("fan", 255)
+	*now = now.Add(1 * time.Second)
+
+	// WHEN
+	var values []float64
+	for i := 0; i < 10; i++ {
+		values = append(values, model.GetRpm("fan"))
+	}
+
+	// THEN
+	for _, value := range values {
+		assert.InDelta(t, 2000, value, 50)
+	}
+	assert.NotEqual(t, values[0], values[1])
+}
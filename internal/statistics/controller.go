@@ -0,0 +1,43 @@
+package statistics
+
+import (
+	"github.com/markusressel/fan2go/internal/controller"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const subsystemController = "controller"
+
+type ControllerCollector struct {
+	controllers       []controller.FanController
+	thirdPartyChanges *prometheus.Desc
+	targetPwm         *prometheus.Desc
+}
+
+func NewControllerCollector(controllers []controller.FanController) *ControllerCollector {
+	return &ControllerCollector{
+		controllers: controllers,
+		thirdPartyChanges: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystemController, "third_party_changes_total"),
+			"Number of times the PWM value of the fan was changed by a third party",
+			[]string{"id"}, nil,
+		),
+		targetPwm: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystemController, "target_pwm"),
+			"PWM value calculated for the fan (not applied in dry-run mode)",
+			[]string{"id"}, nil,
+		),
+	}
+}
+
+func (collector *ControllerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.thirdPartyChanges
+	ch <- collector.targetPwm
+}
+
+// Collect implements required collect function for all promehteus collectors
+func (collector *ControllerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range collector.controllers {
+		fanId := c.GetFanId()
+		stats := c.GetStatistics()
+		ch <- prometheus.MustNewConstMetric(collector.thirdPartyChanges, prometheus.CounterValue, float64(stats.ThirdPartyChanges), fanId)
+		ch <- prometheus.MustNewConstMetric(collector.targetPwm, prometheus.GaugeValue, float64(stats.TargetPwm), fanId)
+	}
+}
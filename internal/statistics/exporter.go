@@ -9,3 +9,8 @@ const (
 func Register(collector prometheus.Collector) {
 	prometheus.MustRegister(collector)
 }
+
+// Unregister removes a collector added using Register
+func Unregister(collector prometheus.Collector) {
+	prometheus.Unregister(collector)
+}
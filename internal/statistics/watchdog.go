@@ -0,0 +1,45 @@
+package statistics
+
+import (
+	"github.com/markusressel/fan2go/internal/watchdog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const subsystemWatchdog = "watchdog"
+
+type WatchdogCollector struct {
+	watchdog *watchdog.Watchdog
+	alarms   *prometheus.Desc
+	active   *prometheus.Desc
+}
+
+func NewWatchdogCollector(w *watchdog.Watchdog) *WatchdogCollector {
+	return &WatchdogCollector{
+		watchdog: w,
+		alarms: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystemWatchdog, "alarms_total"),
+			"Number of times a control loop or sensor monitor missed its watchdog deadline",
+			[]string{"id"}, nil,
+		),
+		active: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystemWatchdog, "alarm_active"),
+			"Whether a control loop or sensor monitor is currently missing its watchdog deadline",
+			[]string{"id"}, nil,
+		),
+	}
+}
+
+func (collector *WatchdogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.alarms
+	ch <- collector.active
+}
+
+// Collect implements required collect function for all promehteus collectors
+func (collector *WatchdogCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range collector.watchdog.GetStatistics() {
+		active := 0.0
+		if stats.Active {
+			active = 1
+		}
+		ch <- prometheus.MustNewConstMetric(collector.alarms, prometheus.CounterValue, float64(stats.Alarms), stats.ID)
+		ch <- prometheus.MustNewConstMetric(collector.active, prometheus.GaugeValue, active, stats.ID)
+	}
+}
@@ -0,0 +1,85 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// the sd_notify protocol, see sd_notify(3)
+const (
+	EnvNotifySocket = "NOTIFY_SOCKET"
+	EnvWatchdogUsec = "WATCHDOG_USEC"
+	EnvWatchdogPid  = "WATCHDOG_PID"
+
+	StateReady    = "READY=1"
+	StateStopping = "STOPPING=1"
+	StateWatchdog = "WATCHDOG=1"
+)
+
+// Notify sends the given state to the service manager.
+// Returns false (without an error) if fan2go is not running as a systemd notify service.
+func Notify(state string) (sent bool, err error) {
+	socketPath := os.Getenv(EnvNotifySocket)
+	if socketPath == "" {
+		return false, nil
+	}
+
+	// abstract namespace socket
+	if socketPath[0] == '@' {
+		socketPath = "\x00" + socketPath[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{
+		Name: socketPath,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NotifyStatus sends a free-form status message to the service manager,
+// shown by "systemctl status"
+func NotifyStatus(format string, a ...interface{}) (sent bool, err error) {
+	return Notify("STATUS=" + fmt.Sprintf(format, a...))
+}
+
+// WatchdogInterval returns the watchdog timeout configured for this process,
+// or 0 if the service manager does not expect watchdog pings.
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv(EnvWatchdogUsec)
+	if usec == "" {
+		return 0, nil
+	}
+
+	pid := os.Getenv(EnvWatchdogPid)
+	if pid != "" {
+		p, err := strconv.Atoi(pid)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %v", EnvWatchdogPid, err)
+		}
+		if p != os.Getpid() {
+			// meant for another process
+			return 0, nil
+		}
+	}
+
+	value, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", EnvWatchdogUsec, err)
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("invalid %s: %d", EnvWatchdogUsec, value)
+	}
+	return time.Duration(value) * time.Microsecond, nil
+}
@@ -0,0 +1,111 @@
+package systemd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// helper function to set an environment variable for the duration of a test
+func setEnv(t *testing.T, key string, value string) {
+	previous, ok := os.LookupEnv(key)
+	_ = os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+// helper function to listen on a unix datagram socket, used as NOTIFY_SOCKET
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	socketPath := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setEnv(t, EnvNotifySocket, socketPath)
+	return conn
+}
+
+// helper function to read the next message from the given socket
+func readMessage(t *testing.T, conn *net.UnixConn) string {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	// GIVEN
+	conn := listenNotifySocket(t)
+	defer conn.Close()
+
+	// WHEN
+	sent, err := Notify(StateReady)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.True(t, sent)
+	assert.Equal(t, "READY=1", readMessage(t, conn))
+}
+
+func TestNotifyStatus(t *testing.T) {
+	// GIVEN
+	conn := listenNotifySocket(t)
+	defer conn.Close()
+
+	// WHEN
+	_, err := NotifyStatus("Initializing fan %s...", "cpu")
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, "STATUS=Initializing fan cpu...", readMessage(t, conn))
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	// GIVEN
+	setEnv(t, EnvNotifySocket, "")
+
+	// WHEN
+	sent, err := Notify(StateReady)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.False(t, sent)
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	// GIVEN
+	setEnv(t, EnvWatchdogUsec, "30000000")
+	setEnv(t, EnvWatchdogPid, strconv.Itoa(os.Getpid()))
+
+	// WHEN
+	interval, err := WatchdogInterval()
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, interval)
+}
+
+func TestWatchdogIntervalOtherProcess(t *testing.T) {
+	// GIVEN
+	setEnv(t, EnvWatchdogUsec, "30000000")
+	setEnv(t, EnvWatchdogPid, strconv.Itoa(os.Getpid()+1))
+
+	// WHEN
+	interval, err := WatchdogInterval()
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), interval)
+}
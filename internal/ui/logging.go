@@ -2,12 +2,18 @@ package ui
 
 import (
 	"github.com/pterm/pterm"
+	"io"
 )
 
 func SetDebugEnabled(enabled bool) {
 	pterm.PrintDebugMessages = enabled
 }
 
+// SetOutput sets the writer all messages are printed to
+func SetOutput(writer io.Writer) {
+	pterm.SetDefaultOutput(writer)
+}
+
 func Printf(format string, a ...interface{}) {
 	pterm.Printf(format, a...)
 }
@@ -0,0 +1,7 @@
+package util
+
+import "time"
+
+// Clock returns the current time. It can be replaced to run
+// time dependent logic faster than real time, f.ex. in simulations.
+type Clock func() time.Time
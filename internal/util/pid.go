@@ -0,0 +1,77 @@
+package util
+
+import "time"
+
+// PidLoop is a simple PID controller, which keeps its integral and
+// derivative state between consecutive calls
+type PidLoop struct {
+	p float64
+	i float64
+	d float64
+
+	outMin float64
+	outMax float64
+
+	integral  float64
+	lastError float64
+	lastTime  time.Time
+}
+
+// NewPidLoop creates a new PID loop with the given gains,
+// which produces output values within [outMin..outMax]
+func NewPidLoop(p float64, i float64, d float64, outMin float64, outMax float64) *PidLoop {
+	return &PidLoop{
+		p:      p,
+		i:      i,
+		d:      d,
+		outMin: outMin,
+		outMax: outMax,
+	}
+}
+
+// Loop advances the loop with the given error, using the time
+// that has passed since the last call as the time delta
+func (l *PidLoop) Loop(err float64) float64 {
+	return l.LoopAt(err, time.Now())
+}
+
+// LoopAt is like Loop, but uses the given time as the current time
+func (l *PidLoop) LoopAt(err float64, now time.Time) float64 {
+	dt := 0.0
+	if !l.lastTime.IsZero() {
+		dt = now.Sub(l.lastTime).Seconds()
+	}
+	l.lastTime = now
+	return l.Advance(err, dt)
+}
+
+// Advance advances the loop with the given error by dt seconds
+// and returns the new (clamped) output value
+func (l *PidLoop) Advance(err float64, dt float64) float64 {
+	integral := l.integral
+	derivative := 0.0
+	if dt > 0 {
+		integral += err * dt
+		derivative = (err - l.lastError) / dt
+	}
+	l.lastError = err
+
+	output := l.p*err + l.i*integral + l.d*derivative
+
+	// anti-windup: don't accumulate error while the output
+	// is saturated in the direction the error is pushing it
+	if output > l.outMax {
+		if err < 0 {
+			l.integral = integral
+		}
+		return l.outMax
+	} else if output < l.outMin {
+		if err > 0 {
+			l.integral = integral
+		}
+		return l.outMin
+	}
+
+	l.integral = integral
+	return output
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPidLoopProportional(t *testing.T) {
+	// GIVEN
+	loop := NewPidLoop(2, 0, 0, 0, 255)
+
+	// WHEN
+	result := loop.Advance(10, 1)
+
+	// THEN
+	assert.Equal(t, 20.0, result)
+}
+
+func TestPidLoopIntegral(t *testing.T) {
+	// GIVEN
+	loop := NewPidLoop(0, 1, 0, 0, 255)
+
+	// WHEN
+	loop.Advance(10, 1)
+	result := loop.Advance(10, 1)
+
+	// THEN
+	assert.Equal(t, 20.0, result)
+}
+
+func TestPidLoopDerivative(t *testing.T) {
+	// GIVEN
+	loop := NewPidLoop(0, 0, 1, 0, 255)
+
+	// WHEN
+	loop.Advance(10, 1)
+	result := loop.Advance(15, 1)
+
+	// THEN
+	assert.Equal(t, 5.0, result)
+}
+
+func TestPidLoopClampsOutput(t *testing.T) {
+	// GIVEN
+	loop := NewPidLoop(1, 0, 0, 0, 255)
+
+	// WHEN
+	high := loop.Advance(1000, 1)
+	low := loop.Advance(-1000, 1)
+
+	// THEN
+	assert.Equal(t, 255.0, high)
+	assert.Equal(t, 0.0, low)
+}
+
+func TestPidLoopAntiWindup(t *testing.T) {
+	// GIVEN
+	loop := NewPidLoop(0, 1, 0, 0, 255)
+	for i := 0; i < 100; i++ {
+		loop.Advance(100, 1)
+	}
+
+	// WHEN
+	result := loop.Advance(-10, 1)
+
+	// THEN
+	// without anti-windup the integral would be at 10000
+	assert.Less(t, result, 255.0)
+}
+
+func TestPidLoopLoopAt(t *testing.T) {
+	// GIVEN
+	loop := NewPidLoop(0, 1, 0, 0, 255)
+	start := time.Unix(0, 0)
+
+	// WHEN
+	first := loop.LoopAt(10, start)
+	second := loop.LoopAt(10, start.Add(2*time.Second))
+
+	// THEN
+	assert.Equal(t, 0.0, first)
+	assert.Equal(t, 20.0, second)
+}
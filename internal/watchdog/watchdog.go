@@ -0,0 +1,126 @@
+package watchdog
+
+import (
+	"context"
+	"github.com/markusressel/fan2go/internal/ui"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Watchdog raises an alarm for every registered target which did not send
+// a heartbeat within the configured timeout.
+type Watchdog struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	targets map[string]*target
+}
+
+type target struct {
+	id string
+	// returns the time of the last heartbeat, the zero time if there was none yet
+	lastHeartbeat func() time.Time
+	onAlarm       func()
+	onRecover     func()
+
+	alarmed bool
+	alarms  int
+}
+
+// Statistics holds the number of alarms raised for a target
+type Statistics struct {
+	ID string
+	// Alarms is the number of times the target missed its deadline
+	Alarms int
+	// Active indicates whether the target is currently missing its deadline
+	Active bool
+}
+
+func NewWatchdog(timeout time.Duration) *Watchdog {
+	return &Watchdog{
+		timeout: timeout,
+		targets: map[string]*target{},
+	}
+}
+
+// Register adds a target to be monitored by the watchdog.
+// onAlarm is called when the target misses its deadline,
+// onRecover once it sends heartbeats again afterwards.
+func (w *Watchdog) Register(id string, lastHeartbeat func() time.Time, onAlarm func(), onRecover func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.targets[id] = &target{
+		id:            id,
+		lastHeartbeat: lastHeartbeat,
+		onAlarm:       onAlarm,
+		onRecover:     onRecover,
+	}
+}
+
+// Unregister stops monitoring the target with the given id, without calling its onRecover
+func (w *Watchdog) Unregister(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.targets, id)
+}
+
+func (w *Watchdog) Run(ctx context.Context) error {
+	tick := time.NewTicker(w.timeout / 4)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-tick.C:
+			w.Check(now)
+		}
+	}
+}
+
+// Check compares the last heartbeat of all targets to the given time
+// and raises or clears alarms accordingly.
+func (w *Watchdog) Check(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, t := range w.targets {
+		lastHeartbeat := t.lastHeartbeat()
+		if lastHeartbeat.IsZero() {
+			// not started yet
+			continue
+		}
+
+		missed := now.Sub(lastHeartbeat) > w.timeout
+		if missed && !t.alarmed {
+			ui.Error("Watchdog: %s did not respond for %v, activating failsafe", t.id, now.Sub(lastHeartbeat))
+			t.alarmed = true
+			t.alarms++
+			// the alarm action may block on the same resource as the stalled target
+			go t.onAlarm()
+		} else if !missed && t.alarmed {
+			ui.Info("Watchdog: %s is responding again, deactivating failsafe", t.id)
+			t.alarmed = false
+			go t.onRecover()
+		}
+	}
+}
+
+// GetStatistics returns a snapshot of the statistics of all targets, sorted by id
+func (w *Watchdog) GetStatistics() []Statistics {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var result []Statistics
+	for _, t := range w.targets {
+		result = append(result, Statistics{
+			ID:     t.id,
+			Alarms: t.alarms,
+			Active: t.alarmed,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
@@ -0,0 +1,79 @@
+package watchdog
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWatchdogAlarmAndRecover(t *testing.T) {
+	// GIVEN
+	start := time.Now()
+	lastHeartbeat := start
+	alarms := make(chan bool, 2)
+
+	w := NewWatchdog(10 * time.Second)
+	w.Register("controller/fan1", func() time.Time {
+		return lastHeartbeat
+	}, func() {
+		alarms <- true
+	}, func() {
+		alarms <- false
+	})
+
+	// WHEN
+	w.Check(start.Add(5 * time.Second))
+
+	// THEN
+	assert.Equal(t, []Statistics{{ID: "controller/fan1", Alarms: 0, Active: false}}, w.GetStatistics())
+
+	// WHEN
+	w.Check(start.Add(11 * time.Second))
+	w.Check(start.Add(12 * time.Second))
+
+	// THEN
+	assert.True(t, <-alarms)
+	assert.Equal(t, []Statistics{{ID: "controller/fan1", Alarms: 1, Active: true}}, w.GetStatistics())
+
+	// WHEN
+	lastHeartbeat = start.Add(12 * time.Second)
+	w.Check(start.Add(13 * time.Second))
+
+	// THEN
+	assert.False(t, <-alarms)
+	assert.Equal(t, []Statistics{{ID: "controller/fan1", Alarms: 1, Active: false}}, w.GetStatistics())
+}
+
+func TestWatchdogIgnoresTargetsNotStarted(t *testing.T) {
+	// GIVEN
+	w := NewWatchdog(time.Second)
+	w.Register("sensor/cpu", func() time.Time {
+		return time.Time{}
+	}, func() {
+		assert.Fail(t, "unexpected alarm")
+	}, func() {})
+
+	// WHEN
+	w.Check(time.Now())
+
+	// THEN
+	assert.Equal(t, 0, w.GetStatistics()[0].Alarms)
+}
+
+func TestWatchdogUnregister(t *testing.T) {
+	// GIVEN
+	start := time.Now()
+	w := NewWatchdog(time.Second)
+	w.Register("controller/fan1", func() time.Time {
+		return start
+	}, func() {
+		assert.Fail(t, "unregistered target raised an alarm")
+	}, func() {})
+
+	// WHEN
+	w.Unregister("controller/fan1")
+	w.Check(start.Add(5 * time.Second))
+
+	// THEN
+	assert.Empty(t, w.GetStatistics())
+}